@@ -0,0 +1,27 @@
+// Package api содержит контракты сервиса: OpenAPI-документ HTTP API
+// и protobuf-описание gRPC API (shortener/v1).
+package api
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPI — документ OpenAPI 3 HTTP API в формате JSON.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// LoadOpenAPI разбирает встроенный документ OpenAPI и проверяет его корректность.
+func LoadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPI)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
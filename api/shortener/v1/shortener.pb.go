@@ -0,0 +1,929 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.32.1
+// source: api/shortener/v1/shortener.proto
+
+// Пакет shortener.v1 описывает gRPC API сервиса сокращения ссылок.
+// Авторизация: токен auth.Manager передаётся в metadata "authorization"
+// (с префиксом "Bearer " или без него). Если токен не передан, сервер создаёт
+// нового пользователя и возвращает его токен в заголовке ответа "authorization".
+
+package shortenerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// BatchStatus — итог обработки элемента пакета.
+type BatchStatus int32
+
+const (
+	BatchStatus_BATCH_STATUS_UNSPECIFIED BatchStatus = 0
+	BatchStatus_BATCH_STATUS_CREATED     BatchStatus = 1
+	BatchStatus_BATCH_STATUS_EXISTING    BatchStatus = 2
+	BatchStatus_BATCH_STATUS_INVALID     BatchStatus = 3
+)
+
+// Enum value maps for BatchStatus.
+var (
+	BatchStatus_name = map[int32]string{
+		0: "BATCH_STATUS_UNSPECIFIED",
+		1: "BATCH_STATUS_CREATED",
+		2: "BATCH_STATUS_EXISTING",
+		3: "BATCH_STATUS_INVALID",
+	}
+	BatchStatus_value = map[string]int32{
+		"BATCH_STATUS_UNSPECIFIED": 0,
+		"BATCH_STATUS_CREATED":     1,
+		"BATCH_STATUS_EXISTING":    2,
+		"BATCH_STATUS_INVALID":     3,
+	}
+)
+
+func (x BatchStatus) Enum() *BatchStatus {
+	p := new(BatchStatus)
+	*p = x
+	return p
+}
+
+func (x BatchStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BatchStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_shortener_v1_shortener_proto_enumTypes[0].Descriptor()
+}
+
+func (BatchStatus) Type() protoreflect.EnumType {
+	return &file_api_shortener_v1_shortener_proto_enumTypes[0]
+}
+
+func (x BatchStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BatchStatus.Descriptor instead.
+func (BatchStatus) EnumDescriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{0}
+}
+
+// LinkStatus — состояние короткой ссылки.
+type LinkStatus int32
+
+const (
+	LinkStatus_LINK_STATUS_UNSPECIFIED LinkStatus = 0
+	LinkStatus_LINK_STATUS_ACTIVE      LinkStatus = 1
+	LinkStatus_LINK_STATUS_DELETED     LinkStatus = 2
+	LinkStatus_LINK_STATUS_EXPIRED     LinkStatus = 3
+)
+
+// Enum value maps for LinkStatus.
+var (
+	LinkStatus_name = map[int32]string{
+		0: "LINK_STATUS_UNSPECIFIED",
+		1: "LINK_STATUS_ACTIVE",
+		2: "LINK_STATUS_DELETED",
+		3: "LINK_STATUS_EXPIRED",
+	}
+	LinkStatus_value = map[string]int32{
+		"LINK_STATUS_UNSPECIFIED": 0,
+		"LINK_STATUS_ACTIVE":      1,
+		"LINK_STATUS_DELETED":     2,
+		"LINK_STATUS_EXPIRED":     3,
+	}
+)
+
+func (x LinkStatus) Enum() *LinkStatus {
+	p := new(LinkStatus)
+	*p = x
+	return p
+}
+
+func (x LinkStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LinkStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_shortener_v1_shortener_proto_enumTypes[1].Descriptor()
+}
+
+func (LinkStatus) Type() protoreflect.EnumType {
+	return &file_api_shortener_v1_shortener_proto_enumTypes[1]
+}
+
+func (x LinkStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LinkStatus.Descriptor instead.
+func (LinkStatus) EnumDescriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{1}
+}
+
+type ShortenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortenRequest) Reset() {
+	*x = ShortenRequest{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortenRequest) ProtoMessage() {}
+
+func (x *ShortenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortenRequest.ProtoReflect.Descriptor instead.
+func (*ShortenRequest) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShortenRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type ShortenResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ShortUrl string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// existing — URL уже был сокращён, возвращена существующая ссылка.
+	Existing      bool `protobuf:"varint,3,opt,name=existing,proto3" json:"existing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortenResponse) Reset() {
+	*x = ShortenResponse{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortenResponse) ProtoMessage() {}
+
+func (x *ShortenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortenResponse.ProtoReflect.Descriptor instead.
+func (*ShortenResponse) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ShortenResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ShortenResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *ShortenResponse) GetExisting() bool {
+	if x != nil {
+		return x.Existing
+	}
+	return false
+}
+
+type ShortenBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortenBatchRequest) Reset() {
+	*x = ShortenBatchRequest{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortenBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortenBatchRequest) ProtoMessage() {}
+
+func (x *ShortenBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortenBatchRequest.ProtoReflect.Descriptor instead.
+func (*ShortenBatchRequest) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ShortenBatchRequest) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchItem) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *BatchItem) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+type BatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	ShortUrl      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Status        BatchStatus            `protobuf:"varint,3,opt,name=status,proto3,enum=shortener.v1.BatchStatus" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResult) Reset() {
+	*x = BatchResult{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResult) ProtoMessage() {}
+
+func (x *BatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResult.ProtoReflect.Descriptor instead.
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchResult) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *BatchResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *BatchResult) GetStatus() BatchStatus {
+	if x != nil {
+		return x.Status
+	}
+	return BatchStatus_BATCH_STATUS_UNSPECIFIED
+}
+
+func (x *BatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ShortenBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchResult         `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortenBatchResponse) Reset() {
+	*x = ShortenBatchResponse{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortenBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortenBatchResponse) ProtoMessage() {}
+
+func (x *ShortenBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortenBatchResponse.ProtoReflect.Descriptor instead.
+func (*ShortenBatchResponse) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ShortenBatchResponse) GetItems() []*BatchResult {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type ExpandRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// password — пароль защищённой ссылки; владельцу не нужен.
+	Password      string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandRequest) Reset() {
+	*x = ExpandRequest{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandRequest) ProtoMessage() {}
+
+func (x *ExpandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandRequest.ProtoReflect.Descriptor instead.
+func (*ExpandRequest) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ExpandRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ExpandRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type ExpandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ShortUrl      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,3,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Status        LinkStatus             `protobuf:"varint,4,opt,name=status,proto3,enum=shortener.v1.LinkStatus" json:"status,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandResponse) Reset() {
+	*x = ExpandResponse{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandResponse) ProtoMessage() {}
+
+func (x *ExpandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandResponse.ProtoReflect.Descriptor instead.
+func (*ExpandResponse) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ExpandResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ExpandResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *ExpandResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *ExpandResponse) GetStatus() LinkStatus {
+	if x != nil {
+		return x.Status
+	}
+	return LinkStatus_LINK_STATUS_UNSPECIFIED
+}
+
+func (x *ExpandResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListUserURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserURLsRequest) Reset() {
+	*x = ListUserURLsRequest{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsRequest) ProtoMessage() {}
+
+func (x *ListUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{8}
+}
+
+type UserURL struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserURL) Reset() {
+	*x = UserURL{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserURL) ProtoMessage() {}
+
+func (x *UserURL) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserURL.ProtoReflect.Descriptor instead.
+func (*UserURL) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UserURL) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *UserURL) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+type ListUserURLsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Urls          []*UserURL             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserURLsResponse) Reset() {
+	*x = ListUserURLsResponse{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsResponse) ProtoMessage() {}
+
+func (x *ListUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsResponse.ProtoReflect.Descriptor instead.
+func (*ListUserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListUserURLsResponse) GetUrls() []*UserURL {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+type DeleteUserURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserURLsRequest) Reset() {
+	*x = DeleteUserURLsRequest{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserURLsRequest) ProtoMessage() {}
+
+func (x *DeleteUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*DeleteUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteUserURLsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type DeleteUserURLsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserURLsResponse) Reset() {
+	*x = DeleteUserURLsResponse{}
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserURLsResponse) ProtoMessage() {}
+
+func (x *DeleteUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_shortener_v1_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserURLsResponse.ProtoReflect.Descriptor instead.
+func (*DeleteUserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_api_shortener_v1_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+var File_api_shortener_v1_shortener_proto protoreflect.FileDescriptor
+
+const file_api_shortener_v1_shortener_proto_rawDesc = "" +
+	"\n" +
+	" api/shortener/v1/shortener.proto\x12\fshortener.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\"\n" +
+	"\x0eShortenRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\"Z\n" +
+	"\x0fShortenResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12\x1a\n" +
+	"\bexisting\x18\x03 \x01(\bR\bexisting\"D\n" +
+	"\x13ShortenBatchRequest\x12-\n" +
+	"\x05items\x18\x01 \x03(\v2\x17.shortener.v1.BatchItemR\x05items\"U\n" +
+	"\tBatchItem\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"\x9a\x01\n" +
+	"\vBatchResult\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x121\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x19.shortener.v1.BatchStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"G\n" +
+	"\x14ShortenBatchResponse\x12/\n" +
+	"\x05items\x18\x01 \x03(\v2\x19.shortener.v1.BatchResultR\x05items\";\n" +
+	"\rExpandRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"\xcd\x01\n" +
+	"\x0eExpandResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x03 \x01(\tR\voriginalUrl\x120\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x18.shortener.v1.LinkStatusR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\x15\n" +
+	"\x13ListUserURLsRequest\"I\n" +
+	"\aUserURL\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"A\n" +
+	"\x14ListUserURLsResponse\x12)\n" +
+	"\x04urls\x18\x01 \x03(\v2\x15.shortener.v1.UserURLR\x04urls\")\n" +
+	"\x15DeleteUserURLsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"\x18\n" +
+	"\x16DeleteUserURLsResponse*z\n" +
+	"\vBatchStatus\x12\x1c\n" +
+	"\x18BATCH_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14BATCH_STATUS_CREATED\x10\x01\x12\x19\n" +
+	"\x15BATCH_STATUS_EXISTING\x10\x02\x12\x18\n" +
+	"\x14BATCH_STATUS_INVALID\x10\x03*s\n" +
+	"\n" +
+	"LinkStatus\x12\x1b\n" +
+	"\x17LINK_STATUS_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12LINK_STATUS_ACTIVE\x10\x01\x12\x17\n" +
+	"\x13LINK_STATUS_DELETED\x10\x02\x12\x17\n" +
+	"\x13LINK_STATUS_EXPIRED\x10\x032\xa3\x03\n" +
+	"\tShortener\x12F\n" +
+	"\aShorten\x12\x1c.shortener.v1.ShortenRequest\x1a\x1d.shortener.v1.ShortenResponse\x12U\n" +
+	"\fShortenBatch\x12!.shortener.v1.ShortenBatchRequest\x1a\".shortener.v1.ShortenBatchResponse\x12C\n" +
+	"\x06Expand\x12\x1b.shortener.v1.ExpandRequest\x1a\x1c.shortener.v1.ExpandResponse\x12U\n" +
+	"\fListUserURLs\x12!.shortener.v1.ListUserURLsRequest\x1a\".shortener.v1.ListUserURLsResponse\x12[\n" +
+	"\x0eDeleteUserURLs\x12#.shortener.v1.DeleteUserURLsRequest\x1a$.shortener.v1.DeleteUserURLsResponseBKZIgithub.com/BuJIKuH/go-musthave-shortener-tpl/api/shortener/v1;shortenerv1b\x06proto3"
+
+var (
+	file_api_shortener_v1_shortener_proto_rawDescOnce sync.Once
+	file_api_shortener_v1_shortener_proto_rawDescData []byte
+)
+
+func file_api_shortener_v1_shortener_proto_rawDescGZIP() []byte {
+	file_api_shortener_v1_shortener_proto_rawDescOnce.Do(func() {
+		file_api_shortener_v1_shortener_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_shortener_v1_shortener_proto_rawDesc), len(file_api_shortener_v1_shortener_proto_rawDesc)))
+	})
+	return file_api_shortener_v1_shortener_proto_rawDescData
+}
+
+var file_api_shortener_v1_shortener_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_shortener_v1_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_api_shortener_v1_shortener_proto_goTypes = []any{
+	(BatchStatus)(0),               // 0: shortener.v1.BatchStatus
+	(LinkStatus)(0),                // 1: shortener.v1.LinkStatus
+	(*ShortenRequest)(nil),         // 2: shortener.v1.ShortenRequest
+	(*ShortenResponse)(nil),        // 3: shortener.v1.ShortenResponse
+	(*ShortenBatchRequest)(nil),    // 4: shortener.v1.ShortenBatchRequest
+	(*BatchItem)(nil),              // 5: shortener.v1.BatchItem
+	(*BatchResult)(nil),            // 6: shortener.v1.BatchResult
+	(*ShortenBatchResponse)(nil),   // 7: shortener.v1.ShortenBatchResponse
+	(*ExpandRequest)(nil),          // 8: shortener.v1.ExpandRequest
+	(*ExpandResponse)(nil),         // 9: shortener.v1.ExpandResponse
+	(*ListUserURLsRequest)(nil),    // 10: shortener.v1.ListUserURLsRequest
+	(*UserURL)(nil),                // 11: shortener.v1.UserURL
+	(*ListUserURLsResponse)(nil),   // 12: shortener.v1.ListUserURLsResponse
+	(*DeleteUserURLsRequest)(nil),  // 13: shortener.v1.DeleteUserURLsRequest
+	(*DeleteUserURLsResponse)(nil), // 14: shortener.v1.DeleteUserURLsResponse
+	(*timestamppb.Timestamp)(nil),  // 15: google.protobuf.Timestamp
+}
+var file_api_shortener_v1_shortener_proto_depIdxs = []int32{
+	5,  // 0: shortener.v1.ShortenBatchRequest.items:type_name -> shortener.v1.BatchItem
+	0,  // 1: shortener.v1.BatchResult.status:type_name -> shortener.v1.BatchStatus
+	6,  // 2: shortener.v1.ShortenBatchResponse.items:type_name -> shortener.v1.BatchResult
+	1,  // 3: shortener.v1.ExpandResponse.status:type_name -> shortener.v1.LinkStatus
+	15, // 4: shortener.v1.ExpandResponse.created_at:type_name -> google.protobuf.Timestamp
+	11, // 5: shortener.v1.ListUserURLsResponse.urls:type_name -> shortener.v1.UserURL
+	2,  // 6: shortener.v1.Shortener.Shorten:input_type -> shortener.v1.ShortenRequest
+	4,  // 7: shortener.v1.Shortener.ShortenBatch:input_type -> shortener.v1.ShortenBatchRequest
+	8,  // 8: shortener.v1.Shortener.Expand:input_type -> shortener.v1.ExpandRequest
+	10, // 9: shortener.v1.Shortener.ListUserURLs:input_type -> shortener.v1.ListUserURLsRequest
+	13, // 10: shortener.v1.Shortener.DeleteUserURLs:input_type -> shortener.v1.DeleteUserURLsRequest
+	3,  // 11: shortener.v1.Shortener.Shorten:output_type -> shortener.v1.ShortenResponse
+	7,  // 12: shortener.v1.Shortener.ShortenBatch:output_type -> shortener.v1.ShortenBatchResponse
+	9,  // 13: shortener.v1.Shortener.Expand:output_type -> shortener.v1.ExpandResponse
+	12, // 14: shortener.v1.Shortener.ListUserURLs:output_type -> shortener.v1.ListUserURLsResponse
+	14, // 15: shortener.v1.Shortener.DeleteUserURLs:output_type -> shortener.v1.DeleteUserURLsResponse
+	11, // [11:16] is the sub-list for method output_type
+	6,  // [6:11] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_api_shortener_v1_shortener_proto_init() }
+func file_api_shortener_v1_shortener_proto_init() {
+	if File_api_shortener_v1_shortener_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_shortener_v1_shortener_proto_rawDesc), len(file_api_shortener_v1_shortener_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_shortener_v1_shortener_proto_goTypes,
+		DependencyIndexes: file_api_shortener_v1_shortener_proto_depIdxs,
+		EnumInfos:         file_api_shortener_v1_shortener_proto_enumTypes,
+		MessageInfos:      file_api_shortener_v1_shortener_proto_msgTypes,
+	}.Build()
+	File_api_shortener_v1_shortener_proto = out.File
+	file_api_shortener_v1_shortener_proto_goTypes = nil
+	file_api_shortener_v1_shortener_proto_depIdxs = nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+)
+
+// authCookie — имя cookie, в которой сервер выдаёт токен пользователя.
+const authCookie = "auth_token"
+
+// apiClient выполняет запросы к HTTP API сервиса.
+type apiClient struct {
+	server string
+	http   *http.Client
+	gzip   bool
+	creds  *credentialStore
+}
+
+// apiError — ответ сервера с кодом ошибки.
+type apiError struct {
+	Status  int
+	Problem problem.Problem
+	Body    string
+}
+
+// Error возвращает описание ошибки из problem+json или текст ответа.
+func (e *apiError) Error() string {
+	switch {
+	case e.Problem.Detail != "":
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Problem.Title, e.Problem.Detail)
+	case e.Problem.Title != "":
+		return fmt.Sprintf("%d %s", e.Status, e.Problem.Title)
+	case e.Body != "":
+		return fmt.Sprintf("%d %s", e.Status, e.Body)
+	default:
+		return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+}
+
+// response — ответ сервера с уже разжатым телом.
+type response struct {
+	Status int
+	Header http.Header
+	Body   io.ReadCloser
+}
+
+// do отправляет запрос к path на сервере.
+//
+// Тело запроса сжимается gzip, если это включено; ответ запрашивается сжатым и разжимается.
+// Токен из хранилища передаётся в cookie auth_token, а новый токен из Set-Cookie сохраняется.
+// Ответы 4xx/5xx возвращаются как *apiError; тело успешного ответа закрывает вызывающий.
+func (a *apiClient) do(ctx context.Context, method, path, contentType string, body []byte) (*response, error) {
+	var reader io.Reader
+	encoding := ""
+	if body != nil {
+		reader = bytes.NewReader(body)
+		if a.gzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(body); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			reader = &buf
+			encoding = "gzip"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if a.gzip {
+		// Явный Accept-Encoding отключает прозрачное разжатие в http.Transport.
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if token := a.creds.Token(a.server); token != "" {
+		req.AddCookie(&http.Cookie{Name: authCookie, Value: token})
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.saveToken(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	res := &response{Status: resp.StatusCode, Header: resp.Header, Body: resp.Body}
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		res.Body = &gzipBody{raw: resp.Body}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+		return nil, readAPIError(res)
+	}
+	return res, nil
+}
+
+// doJSON отправляет in в JSON (если не nil) и декодирует ответ в out (если не nil и тело не пустое).
+// Возвращает HTTP-статус успешного ответа.
+func (a *apiClient) doJSON(ctx context.Context, method, path string, in, out any) (int, error) {
+	var body []byte
+	contentType := ""
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return 0, err
+		}
+		contentType = "application/json"
+	}
+
+	resp, err := a.do(ctx, method, path, contentType, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.Status == http.StatusNoContent {
+		return resp.Status, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return resp.Status, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp.Status, nil
+}
+
+// saveToken сохраняет токен из cookie auth_token ответа.
+func (a *apiClient) saveToken(resp *http.Response) error {
+	for _, c := range resp.Cookies() {
+		if c.Name != authCookie {
+			continue
+		}
+		token := c.Value
+		if c.MaxAge < 0 {
+			token = ""
+		}
+		return a.creds.SetToken(a.server, token)
+	}
+	return nil
+}
+
+// readAPIError читает описание ошибки из ответа.
+func readAPIError(resp *response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	e := &apiError{Status: resp.Status}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), problem.ContentType) &&
+		json.Unmarshal(data, &e.Problem) == nil {
+		return e
+	}
+	e.Body = strings.TrimSpace(string(data))
+	return e
+}
+
+// gzipBody разжимает тело ответа при первом чтении; пустое тело
+// (например, у 202 Accepted) читается как пустое, а не как ошибка gzip.
+type gzipBody struct {
+	raw io.ReadCloser
+	zr  *gzip.Reader
+	eof bool
+}
+
+// Read читает разжатые данные.
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.eof {
+		return 0, io.EOF
+	}
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.raw)
+		if errors.Is(err, io.EOF) {
+			g.eof = true
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		g.zr = zr
+	}
+	return g.zr.Read(p)
+}
+
+// Close закрывает исходное тело ответа.
+func (g *gzipBody) Close() error {
+	return g.raw.Close()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+)
+
+// ShortenResult — результат сокращения одной ссылки.
+type ShortenResult struct {
+	URL      string `json:"url"`
+	ShortURL string `json:"short_url"`
+	// Existing — ссылка уже была сокращена ранее (ответ 409 Conflict).
+	Existing bool `json:"existing"`
+}
+
+// runShorten сокращает URL через POST /api/shorten.
+func runShorten(ctx context.Context, e *env, args []string) error {
+	var raw string
+	switch len(args) {
+	case 0:
+		line, err := bufio.NewReader(e.stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		raw = line
+	case 1:
+		raw = args[0]
+	default:
+		return errUsage
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return errors.New("url is required")
+	}
+
+	res := ShortenResult{URL: raw}
+	var resp handler.ResponseJSON
+	_, err := e.api.doJSON(ctx, http.MethodPost, "/api/shorten", handler.RequestJSON{URL: raw}, &resp)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict && apiErr.Body != "" {
+		// На повтор сервер отвечает 409 с уже существующей короткой ссылкой.
+		if json.Unmarshal([]byte(apiErr.Body), &resp) == nil && resp.Result != "" {
+			err = nil
+			res.Existing = true
+		}
+	}
+	if err != nil {
+		return err
+	}
+	res.ShortURL = resp.Result
+
+	return e.out.print(res, []string{"SHORT_URL", "URL", "EXISTING"},
+		[][]string{{res.ShortURL, res.URL, strconv.FormatBool(res.Existing)}})
+}
+
+// runBatch сокращает ссылки из файла (по одной на строку) через POST /api/shorten/batch.
+// Пустые строки и строки, начинающиеся с #, пропускаются; correlation_id — номер строки.
+func runBatch(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	src := e.stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	var items []handler.BatchRequestItem
+	originals := map[string]string{}
+	sc := bufio.NewScanner(src)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id := strconv.Itoa(n)
+		items = append(items, handler.BatchRequestItem{CorrelationID: id, OriginalURL: line})
+		originals[id] = line
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("no urls in input")
+	}
+
+	var resp []handler.BatchResponseItem
+	if _, err := e.api.doJSON(ctx, http.MethodPost, "/api/shorten/batch", items, &resp); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(resp))
+	for _, item := range resp {
+		result := item.ShortURL
+		if item.Error != "" {
+			result = item.Error
+		}
+		rows = append(rows, []string{item.CorrelationID, item.Status, result, originals[item.CorrelationID]})
+	}
+	return e.out.print(resp, []string{"LINE", "STATUS", "SHORT_URL", "URL"}, rows)
+}
+
+// runExpand показывает исходный адрес короткой ссылки через GET /api/expand/{id}.
+// Вместо идентификатора можно передать короткую ссылку целиком.
+func runExpand(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	id := shortID(args[0])
+	if id == "" {
+		return errors.New("short id is required")
+	}
+
+	var resp handler.ExpandResponse
+	if _, err := e.api.doJSON(ctx, http.MethodGet, "/api/expand/"+url.PathEscape(id), nil, &resp); err != nil {
+		return err
+	}
+	return e.out.print(resp, []string{"ID", "STATUS", "SHORT_URL", "URL"},
+		[][]string{{resp.ID, resp.Status, resp.ShortURL, resp.OriginalURL}})
+}
+
+// runList выводит ссылки пользователя через GET /api/user/urls.
+func runList(ctx context.Context, e *env, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	urls := []handler.UserURL{}
+	if _, err := e.api.doJSON(ctx, http.MethodGet, "/api/user/urls", nil, &urls); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(urls))
+	for _, u := range urls {
+		rows = append(rows, []string{u.ShortURL, u.OriginalURL})
+	}
+	return e.out.print(urls, []string{"SHORT_URL", "URL"}, rows)
+}
+
+// DeleteResult — результат запроса на удаление.
+type DeleteResult struct {
+	IDs    []string `json:"ids"`
+	Status string   `json:"status"`
+}
+
+// runDelete ставит ссылки пользователя в очередь на удаление через DELETE /api/user/urls.
+// Удаление асинхронное: сервер отвечает 202 Accepted.
+func runDelete(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	ids := make([]string, 0, len(args))
+	for _, arg := range args {
+		if id := shortID(arg); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	if _, err := e.api.doJSON(ctx, http.MethodDelete, "/api/user/urls", ids, nil); err != nil {
+		return err
+	}
+
+	res := DeleteResult{IDs: ids, Status: "accepted"}
+	rows := make([][]string, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, []string{id, res.Status})
+	}
+	return e.out.print(res, []string{"ID", "STATUS"}, rows)
+}
+
+// runExport выгружает ссылки пользователя через GET /api/user/urls/export.
+// Ответ копируется как есть в stdout или в файл -o, без учёта -output.
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "json", "json, ndjson or csv")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	resp, err := e.api.do(ctx, http.MethodGet, "/api/user/urls/export?format="+url.QueryEscape(*format), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dst := e.out.w
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if f, ok := dst.(*os.File); ok && *out != "" {
+		return f.Close()
+	}
+	return nil
+}
+
+// shortID извлекает идентификатор из короткой ссылки (последний сегмент пути)
+// или возвращает аргумент как есть.
+func shortID(s string) string {
+	s = strings.TrimSpace(s)
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		s = u.Path
+	}
+	s = strings.Trim(s, "/")
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// credentialsFile — имя файла с токенами в каталоге конфигурации пользователя.
+const credentialsFile = "credentials.json"
+
+// credentialStore хранит токены авторизации (cookie auth_token) между запусками клиента.
+// Токены привязаны к адресу сервера: у каждого сервера свой пользователь.
+type credentialStore struct {
+	path    string
+	Servers map[string]string `json:"servers"`
+}
+
+// defaultCredentialsPath возвращает путь к файлу токенов в каталоге конфигурации пользователя,
+// например ~/.config/shortener/credentials.json.
+func defaultCredentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate config dir: %w", err)
+	}
+	return filepath.Join(dir, "shortener", credentialsFile), nil
+}
+
+// loadCredentials читает файл токенов; отсутствующий файл означает пустое хранилище.
+func loadCredentials(path string) (*credentialStore, error) {
+	store := &credentialStore{path: path, Servers: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	if store.Servers == nil {
+		store.Servers = map[string]string{}
+	}
+	return store, nil
+}
+
+// Token возвращает сохранённый токен для сервера.
+func (s *credentialStore) Token(server string) string {
+	return s.Servers[server]
+}
+
+// SetToken запоминает токен сервера и сохраняет файл, если значение изменилось.
+// Пустой токен удаляет запись.
+func (s *credentialStore) SetToken(server, token string) error {
+	if s.Servers[server] == token {
+		return nil
+	}
+	if token == "" {
+		delete(s.Servers, server)
+	} else {
+		s.Servers[server] = token
+	}
+	return s.save()
+}
+
+// save атомарно записывает файл токенов с правами только для владельца.
+func (s *credentialStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("cannot create config dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), credentialsFile+".*")
+	if err != nil {
+		return fmt.Errorf("cannot save credentials: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot save credentials: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot save credentials: %w", err)
+	}
+	// CreateTemp создаёт файл с правами 0600.
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("cannot save credentials: %w", err)
+	}
+	return nil
+}
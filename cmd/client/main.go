@@ -1,56 +1,151 @@
+// Command client — консольный клиент сервиса сокращения ссылок.
+//
+// Использование:
+//
+//	client [флаги] <команда> [аргументы]
+//
+// Команды:
+//
+//	shorten [URL]         сократить ссылку (URL из аргумента или первой строки stdin)
+//	batch FILE            сократить ссылки из файла, по одной на строку ("-" — stdin)
+//	expand ID|SHORT_URL   показать исходный адрес и статус короткой ссылки
+//	list                  вывести ссылки текущего пользователя
+//	delete ID...          удалить ссылки текущего пользователя
+//	export [-format F] [-o FILE]
+//	                      выгрузить ссылки пользователя в json, ndjson или csv
+//
+// Токен пользователя (cookie auth_token) сохраняется в каталоге конфигурации
+// пользователя и отправляется при следующих запусках, поэтому list и delete
+// видят ссылки, созданные ранее.
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
+// Значения по умолчанию и переменные окружения глобальных флагов.
+const (
+	defaultServer  = "http://localhost:8080"
+	envServer      = "SHORTENER_SERVER"
+	envCredentials = "SHORTENER_CREDENTIALS"
+)
+
+// errUsage — ошибка в аргументах командной строки; справка уже выведена.
+var errUsage = errors.New("usage")
+
+// env — окружение запуска команды.
+type env struct {
+	api   *apiClient
+	out   *printer
+	stdin io.Reader
+}
+
+// command — подкоманда клиента.
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+// commands — подкоманды клиента по имени.
+var commands = map[string]command{
+	"shorten": {"shorten [URL]", runShorten},
+	"batch":   {"batch FILE", runBatch},
+	"expand":  {"expand ID|SHORT_URL", runExpand},
+	"list":    {"list", runList},
+	"delete":  {"delete ID...", runDelete},
+	"export":  {"export [-format json|ndjson|csv] [-o FILE]", runExport},
+}
+
 func main() {
-	endpoint := "http://localhost:8080/"
-	// контейнер данных для запроса
-	data := url.Values{}
-	// приглашение в консоли
-	fmt.Println("Введите длинный URL")
-	// открываем потоковое чтение из консоли
-	reader := bufio.NewReader(os.Stdin)
-	// читаем строку из консоли
-	long, err := reader.ReadString('\n')
-	if err != nil {
-		log.Println(err)
-	}
-	long = strings.TrimSuffix(long, "\n")
-	// заполняем контейнер данными
-	data.Set("url", long)
-	// добавляем HTTP-клиент
-	client := &http.Client{}
-	// пишем запрос
-	// запрос методом POST должен, помимо заголовков, содержать тело
-	// тело должно быть источником потокового чтения io.Reader
-	request, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(data.Encode()))
-	if err != nil {
-		log.Println(err)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает аргументы, выполняет подкоманду и возвращает код выхода:
+// 0 — успех, 1 — ошибка выполнения, 2 — неверные аргументы.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", envOr(envServer, defaultServer), "server base URL (env "+envServer+")")
+	output := fs.String("output", outputTable, "output format: table or json")
+	credentials := fs.String("credentials", os.Getenv(envCredentials), "token store path (env "+envCredentials+", default in the user config dir)")
+	useGzip := fs.Bool("gzip", true, "compress requests and responses with gzip")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: client [flags] <command> [args]")
+		fmt.Fprintln(stderr, "\nCommands:")
+		for _, name := range []string{"shorten", "batch", "expand", "list", "delete", "export"} {
+			fmt.Fprintln(stderr, "  "+commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
 	}
-	// в заголовках запроса указываем кодировку
-	request.Header.Add("Content-Type", "text/plain")
-	// отправляем запрос и получаем ответ
-	response, err := client.Do(request)
-	if err != nil {
-		log.Println(err)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(stderr, "invalid -output %q: must be table or json\n", *output)
+		return 2
 	}
-	// выводим код ответа
-	fmt.Println("Статус-код ", response.Status)
-	defer response.Body.Close()
-	// читаем поток из тела ответа
-	body, err := io.ReadAll(response.Body)
+
+	path := *credentials
+	if path == "" {
+		var err error
+		if path, err = defaultCredentialsPath(); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return 1
+		}
+	}
+	creds, err := loadCredentials(path)
 	if err != nil {
-		log.Println(err)
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+
+	e := &env{
+		api: &apiClient{
+			server: strings.TrimRight(*server, "/"),
+			http:   &http.Client{Timeout: *timeout},
+			gzip:   *useGzip,
+			creds:  creds,
+		},
+		out:   &printer{w: stdout, format: *output},
+		stdin: stdin,
+	}
+
+	if err := cmd.run(context.Background(), e, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(stderr, "Usage: client [flags] "+cmd.usage)
+			return 2
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// envOr возвращает значение переменной окружения или def, если она пуста.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
-	// и печатаем его
-	fmt.Println(string(body))
+	return def
 }
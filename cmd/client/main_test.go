@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer эмулирует API сервиса: выдаёт токен в cookie, разжимает gzip-запросы
+// и хранит ссылки отдельно для каждого токена.
+type fakeServer struct {
+	mu       sync.Mutex
+	users    int
+	urls     map[string][]handler.UserURL
+	requests []*http.Request
+	bodies   []string
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	fake := &fakeServer{urls: map[string][]handler.UserURL{}}
+	srv := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func (f *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := io.ReadAll(body)
+	f.requests = append(f.requests, r)
+	f.bodies = append(f.bodies, string(data))
+
+	token := ""
+	if c, err := r.Cookie(authCookie); err == nil {
+		token = c.Value
+	} else {
+		f.users++
+		token = "token-" + string(rune('0'+f.users))
+		http.SetCookie(w, &http.Cookie{Name: authCookie, Value: token})
+	}
+
+	writeJSON := func(status int, v any) {
+		out, _ := json.Marshal(v)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(status)
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write(out)
+			_ = zw.Close()
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(out)
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/shorten":
+		var req handler.RequestJSON
+		if err := json.Unmarshal(data, &req); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		short := "http://short/" + strings.TrimPrefix(req.URL, "https://example.com/")
+		for _, u := range f.urls[token] {
+			if u.OriginalURL == req.URL {
+				writeJSON(http.StatusConflict, handler.ResponseJSON{Result: u.ShortURL})
+				return
+			}
+		}
+		f.urls[token] = append(f.urls[token], handler.UserURL{ShortURL: short, OriginalURL: req.URL})
+		writeJSON(http.StatusCreated, handler.ResponseJSON{Result: short})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/shorten/batch":
+		var items []handler.BatchRequestItem
+		_ = json.Unmarshal(data, &items)
+		resp := make([]handler.BatchResponseItem, 0, len(items))
+		for _, item := range items {
+			resp = append(resp, handler.BatchResponseItem{
+				CorrelationID: item.CorrelationID,
+				ShortURL:      "http://short/" + item.CorrelationID,
+				Status:        "created",
+			})
+		}
+		writeJSON(http.StatusCreated, resp)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/user/urls":
+		if len(f.urls[token]) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(http.StatusOK, f.urls[token])
+	case r.Method == http.MethodGet && r.URL.Path == "/api/expand/missing":
+		w.Header().Set("Content-Type", problem.ContentType)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"short url not found"}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/expand/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/expand/")
+		writeJSON(http.StatusOK, handler.ExpandResponse{ID: id, ShortURL: "http://short/" + id, OriginalURL: "https://example.com/" + id, Status: handler.LinkStatusActive})
+	case r.Method == http.MethodDelete && r.URL.Path == "/api/user/urls":
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/user/urls/export":
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte("id,short_url\n" + r.URL.Query().Get("format") + ",x\n"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// runClient запускает клиент и возвращает код выхода, stdout и stderr.
+func runClient(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Shorten(t *testing.T) {
+	fake, srv := newFakeServer(t)
+	creds := filepath.Join(t.TempDir(), "shortener", "credentials.json")
+	base := []string{"-server", srv.URL, "-credentials", creds}
+
+	code, out, errOut := runClient("", append(base, "-output", "json", "shorten", "https://example.com/a")...)
+	assert.Equal(t, 0, code, errOut)
+	var res ShortenResult
+	assert.NoError(t, json.Unmarshal([]byte(out), &res))
+	assert.Equal(t, ShortenResult{URL: "https://example.com/a", ShortURL: "http://short/a"}, res)
+
+	// Тело — JSON, а не form-encoded текст; запрос сжат gzip.
+	assert.JSONEq(t, `{"url":"https://example.com/a"}`, fake.bodies[0])
+	assert.Equal(t, "gzip", fake.requests[0].Header.Get("Content-Encoding"))
+
+	info, err := os.Stat(creds)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	t.Run("url from stdin, repeat is reported as existing", func(t *testing.T) {
+		code, out, errOut := runClient("https://example.com/a\n", append(base, "shorten")...)
+		assert.Equal(t, 0, code, errOut)
+		assert.Contains(t, out, "SHORT_URL")
+		assert.Contains(t, out, "http://short/a")
+		assert.Contains(t, out, "true")
+	})
+
+	t.Run("token persists between runs", func(t *testing.T) {
+		code, out, errOut := runClient("", append(base, "list")...)
+		assert.Equal(t, 0, code, errOut)
+		assert.Contains(t, out, "https://example.com/a")
+		assert.Equal(t, 1, fake.users)
+	})
+
+	t.Run("without gzip", func(t *testing.T) {
+		code, _, errOut := runClient("", append(base, "-gzip=false", "shorten", "https://example.com/b")...)
+		assert.Equal(t, 0, code, errOut)
+		last := fake.requests[len(fake.requests)-1]
+		assert.Empty(t, last.Header.Get("Content-Encoding"))
+		assert.JSONEq(t, `{"url":"https://example.com/b"}`, fake.bodies[len(fake.bodies)-1])
+	})
+}
+
+func TestRun_Batch(t *testing.T) {
+	fake, srv := newFakeServer(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "urls.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("# comment\nhttps://example.com/1\n\nhttps://example.com/2\n"), 0o600))
+
+	code, out, errOut := runClient("", "-server", srv.URL, "-credentials", filepath.Join(dir, "creds.json"), "-output", "json", "batch", file)
+	assert.Equal(t, 0, code, errOut)
+
+	var items []handler.BatchRequestItem
+	assert.NoError(t, json.Unmarshal([]byte(fake.bodies[0]), &items))
+	assert.Equal(t, []handler.BatchRequestItem{
+		{CorrelationID: "2", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "4", OriginalURL: "https://example.com/2"},
+	}, items)
+
+	var resp []handler.BatchResponseItem
+	assert.NoError(t, json.Unmarshal([]byte(out), &resp))
+	assert.Len(t, resp, 2)
+}
+
+func TestRun_ExpandDeleteExport(t *testing.T) {
+	fake, srv := newFakeServer(t)
+	dir := t.TempDir()
+	base := []string{"-server", srv.URL, "-credentials", filepath.Join(dir, "creds.json")}
+
+	code, out, errOut := runClient("", append(base, "expand", "http://short/abc")...)
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, out, "https://example.com/abc")
+	assert.Contains(t, out, handler.LinkStatusActive)
+
+	code, _, errOut = runClient("", append(base, "expand", "missing")...)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "404")
+	assert.Contains(t, errOut, "short url not found")
+
+	code, out, errOut = runClient("", append(base, "delete", "abc", "http://short/def")...)
+	assert.Equal(t, 0, code, errOut)
+	assert.JSONEq(t, `["abc","def"]`, fake.bodies[len(fake.bodies)-1])
+	assert.Contains(t, out, "accepted")
+
+	target := filepath.Join(dir, "out.csv")
+	code, _, errOut = runClient("", append(base, "export", "-format", "csv", "-o", target)...)
+	assert.Equal(t, 0, code, errOut)
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,short_url\ncsv,x\n", string(data))
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, errOut := runClient("")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "Commands:")
+
+	code, _, errOut = runClient("", "unknown")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "unknown command")
+
+	code, _, _ = runClient("", "-output", "yaml", "list")
+	assert.Equal(t, 2, code)
+
+	code, _, errOut = runClient("", "-credentials", filepath.Join(t.TempDir(), "c.json"), "expand")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "expand ID|SHORT_URL")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Форматы вывода результата команд.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer печатает результаты команд в выбранном формате.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print выводит v как JSON или таблицу с заголовком header и строками rows.
+func (p *printer) print(v any, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
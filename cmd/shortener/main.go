@@ -4,20 +4,27 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/config"
-	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
-	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/grpcapi"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/router"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/rules"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/api"
+	pb "github.com/BuJIKuH/go-musthave-shortener-tpl/api/shortener/v1"
 
 	_ "net/http/pprof"
 )
@@ -27,22 +34,94 @@ func main() {
 		fx.Provide(
 			config.InitConfig,
 			newStorage,
-			newRouter,
+			router.New,
 			NewLogger,
 			NewAuthManager,
 			NewDeleter,
 			NewAuditService,
+			NewEventBroker,
+			service.NewPasswordLimiter,
+			service.NewLoginLimiter,
+			NewCountryResolver,
+			NewGRPCServer,
+			api.LoadOpenAPI,
 		),
-		fx.Invoke(startServer),
+		fx.Invoke(startServer, startGRPCServer),
 	).Run()
 }
 
+// NewGRPCServer создаёт gRPC сервер API shortener.v1.
+// Хранилище, Deleter, сервис аудита и ограничитель попыток ввода пароля — те же экземпляры,
+// что использует HTTP API.
+// Вызовы авторизуются токеном auth.Manager из metadata "authorization";
+// cfg.AuthStrict включает строгий режим, как в HTTP API.
+func NewGRPCServer(
+	cfg *config.Config,
+	store storage.Storage,
+	am *auth.Manager,
+	deleter *service.Deleter,
+	auditSvc *audit.Service,
+	limiter *service.AttemptLimiter,
+	logger *zap.Logger) *grpc.Server {
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcapi.AuthInterceptor(am, logger, cfg.AuthStrict)))
+	pb.RegisterShortenerServer(srv, grpcapi.NewServer(store, deleter, auditSvc, limiter, cfg.ShortenAddress))
+	return srv
+}
+
+// startGRPCServer запускает gRPC сервер, если задан cfg.GRPCAddress.
+// lc — fx.Lifecycle для graceful shutdown.
+// srv — gRPC сервер.
+// logger — Zap логгер.
+func startGRPCServer(lc fx.Lifecycle, cfg *config.Config, srv *grpc.Server, logger *zap.Logger) {
+	if cfg.GRPCAddress == "" {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", cfg.GRPCAddress)
+			if err != nil {
+				return err
+			}
+			logger.Info("Starting gRPC server", zap.String("address", cfg.GRPCAddress))
+
+			go func() {
+				if err := srv.Serve(lis); err != nil {
+					logger.Fatal("gRPC server error", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping gRPC server...")
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				srv.Stop()
+			}
+			return nil
+		},
+	})
+}
+
+// NewEventBroker создаёт брокер живых событий для SSE-подписок пользователей.
+func NewEventBroker() *audit.Broker {
+	return audit.NewBroker(audit.DefaultSubscriberBuffer)
+}
+
 // NewAuditService создает сервис аудита с указанными наблюдателями.
 // cfg — конфигурация приложения.
 // logger — Zap логгер для записи ошибок и событий.
+// broker — брокер живых событий, всегда подключается наблюдателем.
 // Возвращает новый экземпляр *audit.Service.
-func NewAuditService(cfg *config.Config, logger *zap.Logger) *audit.Service {
-	var observers []audit.Observer
+func NewAuditService(cfg *config.Config, logger *zap.Logger, broker *audit.Broker) *audit.Service {
+	observers := []audit.Observer{broker}
 
 	if cfg.AuditFile != "" {
 		fo, err := audit.NewFileObserver(cfg.AuditFile, logger)
@@ -59,13 +138,38 @@ func NewAuditService(cfg *config.Config, logger *zap.Logger) *audit.Service {
 	return audit.NewService(logger, observers...)
 }
 
+// NewCountryResolver открывает базу GeoIP для правил условного редиректа по стране.
+// lc — fx.Lifecycle для закрытия базы при остановке.
+// cfg — конфигурация с путём к файлу базы.
+// Возвращает nil, если путь не задан: правила с условием country при этом не срабатывают.
+func NewCountryResolver(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger) (rules.CountryResolver, error) {
+	if cfg.GeoIPFile == "" {
+		return nil, nil
+	}
+
+	geo, err := rules.NewGeoIP(cfg.GeoIPFile)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("GeoIP database loaded", zap.String("path", cfg.GeoIPFile))
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return geo.Close()
+		},
+	})
+
+	return geo, nil
+}
+
 // NewDeleter создает сервис Deleter для пометки URL как удаленных.
 // lc — fx.Lifecycle для регистрации graceful shutdown.
 // store — интерфейс хранилища.
+// auditSvc — сервис аудита для событий "delete" после пометки ссылок.
 // logger — Zap логгер для логирования действий.
 // Возвращает новый *service.Deleter.
-func NewDeleter(lc fx.Lifecycle, store storage.Storage, logger *zap.Logger) *service.Deleter {
-	d := service.NewDeleter(store.MarkDeleted)
+func NewDeleter(lc fx.Lifecycle, store storage.Storage, auditSvc *audit.Service, logger *zap.Logger) *service.Deleter {
+	d := service.NewDeleter(service.AuditedMarkDeleted(store, auditSvc))
 
 	lc.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
@@ -79,10 +183,40 @@ func NewDeleter(lc fx.Lifecycle, store storage.Storage, logger *zap.Logger) *ser
 }
 
 // NewAuthManager создает менеджер авторизации.
-// cfg — конфигурация с секретным ключом авторизации.
-// Возвращает *auth.Manager.
-func NewAuthManager(cfg *config.Config) *auth.Manager {
-	return auth.NewManager(cfg.AuthSecret)
+// cfg — конфигурация с ключами подписи и проверки и сроком жизни токена.
+// Токены подписываются закрытым ключом из AUTH_SIGNING_KEY_FILE (RS256/EdDSA),
+// а если он не задан — секретом AUTH_SECRET (HS256).
+// Возвращает *auth.Manager или ошибку, если ключ подписи не задан: сервис не запускается.
+func NewAuthManager(cfg *config.Config) (*auth.Manager, error) {
+	opts := []auth.Option{
+		auth.WithPreviousSecrets(cfg.AuthPreviousSecrets...),
+		auth.WithTTL(cfg.AuthTokenTTL),
+	}
+	for _, path := range cfg.AuthVerificationKeyFiles {
+		key, err := auth.LoadPublicKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		opts = append(opts, auth.WithVerificationKeys(key))
+	}
+
+	if cfg.AuthSigningKeyFile == "" {
+		am, err := auth.NewManager(cfg.AuthSecret, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_SECRET or AUTH_SIGNING_KEY_FILE must be set: %w", err)
+		}
+		return am, nil
+	}
+
+	signing, err := auth.LoadPrivateKeyFile(cfg.AuthSigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.AuthSigningKeyFile, err)
+	}
+	if cfg.AuthSecret != "" {
+		// Токены, подписанные секретом до перехода на асимметричный ключ, остаются валидными.
+		opts = append(opts, auth.WithPreviousSecrets(cfg.AuthSecret))
+	}
+	return auth.New(signing, opts...)
 }
 
 // NewLogger инициализирует Zap Logger в production-режиме.
@@ -122,39 +256,6 @@ func newStorage(cfg *config.Config, logger *zap.Logger) (storage.Storage, error)
 	return storage.NewInMemoryStorage(), nil
 }
 
-// newRouter создает и настраивает маршрутизатор Gin.
-// cfg — конфигурация приложения.
-// store — интерфейс хранилища.
-// am — менеджер авторизации.
-// deleter — сервис Deleter для удаления URL.
-// auditSvc — сервис аудита.
-// logger — Zap логгер.
-// Возвращает *gin.Engine.
-func newRouter(
-	cfg *config.Config,
-	store storage.Storage,
-	am *auth.Manager,
-	deleter *service.Deleter,
-	auditSvc *audit.Service,
-	logger *zap.Logger) *gin.Engine {
-
-	r := gin.New()
-	r.Use(
-		middleware.Logger(logger),
-		middleware.GzipMiddleware(logger),
-		middleware.AuthMiddleware(am, logger),
-	)
-
-	r.POST("/", handler.PostRawURL(store, cfg.ShortenAddress, auditSvc))
-	r.GET("/:id", handler.GetIDURL(store, auditSvc))
-	r.POST("/api/shorten", handler.PostJSONURL(store, cfg.ShortenAddress, auditSvc))
-	r.GET("/ping", handler.PingHandler(store))
-	r.POST("/api/shorten/batch", handler.PostBatchURL(store, cfg.ShortenAddress))
-	r.GET("/api/user/urls", handler.GetUserURLs(store, cfg.ShortenAddress))
-	r.DELETE("/api/user/urls", handler.DeleteUserURLs(store, deleter))
-	return r
-}
-
 // startServer запускает HTTP сервер и pprof сервер.
 // lc — fx.Lifecycle для graceful shutdown.
 // cfg — конфигурация приложения.
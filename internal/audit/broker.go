@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultSubscriberBuffer — размер буфера подписчика по умолчанию.
+const DefaultSubscriberBuffer = 64
+
+// Broker — внутрипроцессный pub/sub событий аудита для живых подписок (например, SSE).
+// Broker реализует Observer и подключается к Service как обычный наблюдатель,
+// поэтому публикация не блокирует путь редиректа.
+//
+// Каждый подписчик получает только события своих ссылок (Event.OwnerID).
+// Если буфер подписчика переполнен, подписчик отключается: его канал закрывается,
+// а Dropped возвращает true. Медленный клиент не задерживает остальных.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	buffer int
+}
+
+// Subscription — подписка на события ссылок одного пользователя.
+type Subscription struct {
+	ownerID string
+	ch      chan Event
+	dropped bool
+}
+
+// Events возвращает канал событий. Канал закрывается при отписке или отключении медленного подписчика.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// NewBroker создаёт брокер с буфером buffer событий на подписчика.
+// Если buffer <= 0, используется DefaultSubscriberBuffer.
+func NewBroker(buffer int) *Broker {
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+	return &Broker{
+		subs:   make(map[*Subscription]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe подписывает на события ссылок пользователя ownerID.
+func (b *Broker) Subscribe(ownerID string) *Subscription {
+	sub := &Subscription{
+		ownerID: ownerID,
+		ch:      make(chan Event, b.buffer),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe отменяет подписку и закрывает её канал. Повторный вызов безопасен.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Dropped сообщает, была ли подписка отключена из-за переполнения буфера.
+func (b *Broker) Dropped(sub *Subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sub.dropped
+}
+
+// Notify рассылает событие подписчикам владельца ссылки, не блокируясь.
+// События без OwnerID не рассылаются.
+func (b *Broker) Notify(_ context.Context, e Event) error {
+	if e.OwnerID == "" {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.ownerID != e.OwnerID {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped = true
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+	return nil
+}
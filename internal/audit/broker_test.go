@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_Notify(t *testing.T) {
+	b := NewBroker(2)
+	ctx := context.Background()
+
+	mine := b.Subscribe("u1")
+	other := b.Subscribe("u2")
+
+	assert.NoError(t, b.Notify(ctx, Event{Action: "follow", OwnerID: "u1", ShortID: "abc"}))
+	assert.NoError(t, b.Notify(ctx, Event{Action: "follow"}))
+
+	e := <-mine.Events()
+	assert.Equal(t, "abc", e.ShortID)
+	assert.Empty(t, other.Events())
+
+	b.Unsubscribe(mine)
+	b.Unsubscribe(mine)
+	_, ok := <-mine.Events()
+	assert.False(t, ok)
+	assert.False(t, b.Dropped(mine))
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(1)
+	ctx := context.Background()
+
+	slow := b.Subscribe("u1")
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.Notify(ctx, Event{Action: "follow", OwnerID: "u1"}))
+	}
+
+	assert.True(t, b.Dropped(slow))
+	_, ok := <-slow.Events()
+	assert.True(t, ok, "buffered event is still delivered")
+	_, ok = <-slow.Events()
+	assert.False(t, ok)
+
+	b.Unsubscribe(slow)
+}
@@ -13,7 +13,22 @@ type Event struct {
 	// Может быть пустым для системных событий.
 	UserID string `json:"user_id,omitempty"`
 
+	// OwnerID — идентификатор владельца ссылки, к которой относится событие.
+	// По нему Broker доставляет события в живые подписки владельца.
+	OwnerID string `json:"owner_id,omitempty"`
+
+	// ShortID — короткий идентификатор ссылки.
+	ShortID string `json:"short_id,omitempty"`
+
 	// URL — URL, к которому относится событие.
 	// Например, сокращаемый или перенаправляемый URL.
 	URL string `json:"url"`
+
+	// Variant — метка варианта A/B-сплита, выбранного при переходе.
+	// Заполняется только для событий "follow" по ссылкам со сплитом.
+	Variant string `json:"variant,omitempty"`
+
+	// APIKeyID — идентификатор API-ключа, которым выполнено действие
+	// или к которому оно относится (создание, отзыв, использование ключа).
+	APIKeyID string `json:"api_key_id,omitempty"`
 }
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix — префикс API-ключей: shk_<id>_<secret>.
+const APIKeyPrefix = "shk_"
+
+// GenerateAPIKey создаёт новый API-ключ.
+// Возвращает публичный идентификатор id, сам ключ (показывается пользователю один раз)
+// и его хеш для хранения.
+func GenerateAPIKey() (id, key, hash string, err error) {
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	id = hex.EncodeToString(idBytes)
+	key = APIKeyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return id, key, HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает SHA-256 хеш ключа в hex. Ключ содержит 256 бит случайных данных,
+// поэтому медленное хеширование, как для паролей, не требуется.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey сообщает, что строка имеет формат API-ключа.
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, APIKeyPrefix)
+}
@@ -1,50 +1,216 @@
+// Package auth выпускает и проверяет JWT-токены пользователей.
 package auth
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 )
 
+// DefaultTokenTTL — срок жизни токена по умолчанию.
+const DefaultTokenTTL = 24 * time.Hour
+
+// ErrEmptySecret — ключ подписи не задан.
+var ErrEmptySecret = errors.New("auth: signing secret is empty")
+
+// Manager выпускает токены текущим ключом и принимает токены, подписанные
+// любым из настроенных ключей: предыдущими HMAC-секретами (это позволяет сменить
+// AUTH_SECRET, не разлогинивая пользователей) и открытыми ключами RS256/EdDSA.
 type Manager struct {
-	Secret []byte
+	// keys[0] — ключ подписи.
+	keys []Key
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// Option настраивает Manager.
+type Option func(*Manager)
+
+// WithPreviousSecrets добавляет HMAC-ключи, которыми токены только проверяются.
+// Такие токены при следующем запросе переподписываются текущим ключом.
+func WithPreviousSecrets(secrets ...string) Option {
+	return func(m *Manager) {
+		for _, s := range secrets {
+			if key, err := HMACKey(s); err == nil {
+				m.addKey(key)
+			}
+		}
+	}
+}
+
+// WithVerificationKeys добавляет ключи, которыми токены только проверяются,
+// например открытые ключи, которыми сервис подписывал токены раньше.
+func WithVerificationKeys(keys ...Key) Option {
+	return func(m *Manager) {
+		for _, key := range keys {
+			key.sign = nil
+			m.addKey(key)
+		}
+	}
+}
+
+// WithTTL задаёт срок жизни токена (по умолчанию DefaultTokenTTL).
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		if ttl > 0 {
+			m.ttl = ttl
+		}
+	}
+}
+
+// WithClock задаёт источник текущего времени (для тестов).
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
 }
 
-func NewManager(secret string) *Manager {
-	return &Manager{[]byte(secret)}
+// NewManager создаёт Manager, подписывающий токены HS256 секретом secret.
+// Пустой secret — ошибка: HMAC с пустым ключом позволил бы подделать любой токен.
+func NewManager(secret string, opts ...Option) (*Manager, error) {
+	key, err := HMACKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return New(key, opts...)
 }
 
+// New создаёт Manager, подписывающий токены ключом signing (HS256, RS256 или EdDSA).
+func New(signing Key, opts ...Option) (*Manager, error) {
+	if signing.method == nil {
+		return nil, ErrEmptySecret
+	}
+	if !signing.CanSign() {
+		return nil, errors.New("auth: signing key has no private part")
+	}
+	m := &Manager{
+		keys: []Key{signing},
+		ttl:  DefaultTokenTTL,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// addKey добавляет ключ проверки, если ключа с таким kid ещё нет.
+func (m *Manager) addKey(key Key) {
+	for _, k := range m.keys {
+		if k.id == key.id {
+			return
+		}
+	}
+	m.keys = append(m.keys, key)
+}
+
+// JWKS возвращает открытые ключи RS256/EdDSA для проверки токенов другими сервисами.
+// HMAC-ключи не публикуются.
+func (m *Manager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range m.keys {
+		if jwk := k.jwk(); jwk.Kty != "" {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// TTL возвращает срок жизни выпускаемых токенов.
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+// GenerateToken выпускает токен пользователя userID, подписанный текущим ключом.
 func (m *Manager) GenerateToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+	now := m.now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.Secret)
+	token := jwt.NewWithClaims(m.keys[0].method, claims)
+	token.Header["kid"] = m.keys[0].id
+	return token.SignedString(m.keys[0].sign)
 }
 
+// ParseToken проверяет токен и возвращает userID из claim sub.
 func (m *Manager) ParseToken(tokenStr string, logger *zap.Logger) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			logger.Error("unexpected signing method", zap.String("Method", "HS256"))
-			return nil, errors.New("unexpected signing method")
-		}
-		return m.Secret, nil
-	})
-	if err != nil || !token.Valid {
+	userID, _, err := m.Authenticate(tokenStr)
+	if err != nil {
 		logger.Error("invalid token", zap.Error(err))
-		return "", errors.New("invalid token")
+		return "", err
 	}
+	return userID, nil
+}
 
-	claims := token.Claims.(jwt.MapClaims)
-	sub, _ := claims["sub"].(string)
+// Authenticate проверяет токен и возвращает userID.
+//
+// Если токен подписан предыдущим ключом или прошла половина срока его жизни,
+// renewed содержит новый токен с тем же sub — его нужно отдать клиенту
+// (скользящее продление). Иначе renewed пуст.
+func (m *Manager) Authenticate(tokenStr string) (userID, renewed string, err error) {
+	var claims jwt.RegisteredClaims
+	var usedKey string
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			// Токены, выпущенные до появления kid, подписаны HS256 и проверяются всеми HMAC-ключами.
+			if t.Method != jwt.SigningMethodHS256 {
+				return nil, errors.New("token without key id")
+			}
+			set := jwt.VerificationKeySet{}
+			for _, k := range m.keys {
+				if k.method == jwt.SigningMethodHS256 {
+					set.Keys = append(set.Keys, k.verify)
+				}
+			}
+			return set, nil
+		}
+		for _, k := range m.keys {
+			if k.id == kid {
+				// Алгоритм задаётся ключом, а не заголовком токена.
+				if t.Method.Alg() != k.method.Alg() {
+					return nil, fmt.Errorf("algorithm %s does not match key %q", t.Method.Alg(), kid)
+				}
+				usedKey = k.id
+				return k.verify, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	},
+		jwt.WithValidMethods(m.algorithms()),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil || !token.Valid {
+		return "", "", fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Subject == "" {
+		return "", "", errors.New("invalid sub claim")
+	}
 
-	if sub == "" {
-		logger.Error("failed to extract sub from token, no userID", zap.String("sub", sub))
-		return "", errors.New("invalid sub claim")
+	remaining := claims.ExpiresAt.Sub(m.now())
+	if usedKey != m.keys[0].id || remaining < m.ttl/2 {
+		if renewed, err = m.GenerateToken(claims.Subject); err != nil {
+			return "", "", err
+		}
 	}
+	return claims.Subject, renewed, nil
+}
 
-	return sub, nil
+// algorithms возвращает алгоритмы настроенных ключей.
+func (m *Manager) algorithms() []string {
+	var algs []string
+	seen := map[string]bool{}
+	for _, k := range m.keys {
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
 }
@@ -0,0 +1,252 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// clock — управляемое время для проверки срока жизни токенов.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newManager(t *testing.T, secret string, opts ...auth.Option) *auth.Manager {
+	t.Helper()
+	m, err := auth.NewManager(secret, opts...)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	return m
+}
+
+func TestNewManager_EmptySecret(t *testing.T) {
+	_, err := auth.NewManager("")
+	assert.ErrorIs(t, err, auth.ErrEmptySecret)
+}
+
+func TestManager_Authenticate(t *testing.T) {
+	clk := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := newManager(t, "secret", auth.WithTTL(time.Hour), auth.WithClock(clk.Now))
+
+	token, err := m.GenerateToken("user-1")
+	assert.NoError(t, err)
+
+	t.Run("fresh token is not renewed", func(t *testing.T) {
+		userID, renewed, err := m.Authenticate(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+		assert.Empty(t, renewed)
+
+		userID, err = m.ParseToken(token, zap.NewNop())
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	})
+
+	t.Run("token past half its lifetime is renewed with the same sub", func(t *testing.T) {
+		clk.now = clk.now.Add(40 * time.Minute)
+		userID, renewed, err := m.Authenticate(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+		assert.NotEmpty(t, renewed)
+
+		userID, again, err := m.Authenticate(renewed)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+		assert.Empty(t, again)
+
+		clk.now = clk.now.Add(40 * time.Minute)
+		_, _, err = m.Authenticate(token)
+		assert.Error(t, err, "original token has expired")
+		userID, _, err = m.Authenticate(renewed)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		_, _, err := m.Authenticate("garbage")
+		assert.Error(t, err)
+
+		other, err := newManager(t, "other", auth.WithClock(clk.Now)).GenerateToken("user-1")
+		assert.NoError(t, err)
+		_, _, err = m.Authenticate(other)
+		assert.Error(t, err)
+
+		none := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "user-1", "exp": clk.now.Add(time.Hour).Unix()})
+		unsigned, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		assert.NoError(t, err)
+		_, _, err = m.Authenticate(unsigned)
+		assert.Error(t, err)
+
+		noExp := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-1"})
+		signed, err := noExp.SignedString([]byte("secret"))
+		assert.NoError(t, err)
+		_, _, err = m.Authenticate(signed)
+		assert.Error(t, err)
+	})
+}
+
+func TestManager_KeyRotation(t *testing.T) {
+	old := newManager(t, "old-secret")
+	token, err := old.GenerateToken("user-1")
+	assert.NoError(t, err)
+
+	rotated := newManager(t, "new-secret", auth.WithPreviousSecrets("old-secret"))
+	userID, renewed, err := rotated.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.NotEmpty(t, renewed, "token signed with a previous key is re-signed")
+
+	oldToken, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	assert.NoError(t, err)
+	newToken, _, err := jwt.NewParser().ParseUnverified(renewed, jwt.MapClaims{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, newToken.Header["kid"])
+	assert.NotEqual(t, oldToken.Header["kid"], newToken.Header["kid"])
+
+	// После удаления старого ключа переподписанный токен остаётся валидным.
+	userID, _, err = newManager(t, "new-secret").Authenticate(renewed)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	_, _, err = newManager(t, "new-secret").Authenticate(token)
+	assert.Error(t, err)
+}
+
+func TestManager_LegacyTokenWithoutKid(t *testing.T) {
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token, err := legacy.SignedString([]byte("old-secret"))
+	assert.NoError(t, err)
+
+	m := newManager(t, "new-secret", auth.WithPreviousSecrets("old-secret"))
+	userID, renewed, err := m.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.NotEmpty(t, renewed)
+}
+
+// pemKey кодирует закрытый ключ в PEM PKCS#8.
+func pemKey(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// pemPublicKey кодирует открытый ключ в PEM PKIX.
+func pemPublicKey(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestManager_AsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		private []byte
+		public  []byte
+		alg     string
+		kty     string
+	}{
+		{"RS256", pemKey(t, rsaKey), pemPublicKey(t, &rsaKey.PublicKey), "RS256", "RSA"},
+		{"RS256 PKCS#1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), pemPublicKey(t, &rsaKey.PublicKey), "RS256", "RSA"},
+		{"EdDSA", pemKey(t, edKey), pemPublicKey(t, edKey.Public()), "EdDSA", "OKP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signing, err := auth.ParsePrivateKeyPEM(tt.private)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.alg, signing.Algorithm())
+			assert.True(t, signing.CanSign())
+
+			m, err := auth.New(signing, auth.WithPreviousSecrets("old-secret"))
+			assert.NoError(t, err)
+			token, err := m.GenerateToken("user-1")
+			assert.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.alg, parsed.Method.Alg())
+			assert.Equal(t, signing.ID(), parsed.Header["kid"])
+
+			userID, renewed, err := m.Authenticate(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", userID)
+			assert.Empty(t, renewed)
+
+			// HS256-токены предыдущего секрета принимаются и переподписываются новым ключом.
+			hmacToken, err := newManager(t, "old-secret").GenerateToken("user-2")
+			assert.NoError(t, err)
+			userID, renewed, err = m.Authenticate(hmacToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-2", userID)
+			assert.NotEmpty(t, renewed)
+
+			jwks := m.JWKS()
+			if assert.Len(t, jwks.Keys, 1, "HMAC keys are not published") {
+				assert.Equal(t, signing.ID(), jwks.Keys[0].Kid)
+				assert.Equal(t, tt.kty, jwks.Keys[0].Kty)
+				assert.Equal(t, tt.alg, jwks.Keys[0].Alg)
+				assert.Equal(t, "sig", jwks.Keys[0].Use)
+			}
+
+			// Сервис с одним открытым ключом проверяет токен, но не может подписывать.
+			public, err := auth.ParsePublicKeyPEM(tt.public)
+			assert.NoError(t, err)
+			assert.Equal(t, signing.ID(), public.ID())
+			assert.False(t, public.CanSign())
+			_, err = auth.New(public)
+			assert.Error(t, err)
+
+			verifier, err := auth.NewManager("unrelated", auth.WithVerificationKeys(public))
+			assert.NoError(t, err)
+			userID, _, err = verifier.Authenticate(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", userID)
+		})
+	}
+
+	t.Run("algorithm must match the key", func(t *testing.T) {
+		signing, err := auth.ParsePrivateKeyPEM(pemKey(t, rsaKey))
+		assert.NoError(t, err)
+		m, err := auth.New(signing, auth.WithPreviousSecrets("secret"))
+		assert.NoError(t, err)
+
+		// HS256-токен с kid RSA-ключа, подписанный его открытым ключом как секретом.
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+		forged.Header["kid"] = signing.ID()
+		token, err := forged.SignedString(pemPublicKey(t, &rsaKey.PublicKey))
+		assert.NoError(t, err)
+		_, _, err = m.Authenticate(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid PEM", func(t *testing.T) {
+		_, err := auth.ParsePrivateKeyPEM([]byte("not a key"))
+		assert.Error(t, err)
+		_, err = auth.ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}))
+		assert.Error(t, err)
+	})
+}
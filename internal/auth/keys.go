@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key — ключ подписи или проверки токенов с идентификатором kid.
+//
+// HMAC-ключ (HS256) подписывает и проверяет токены одним секретом.
+// RSA (RS256) и Ed25519 (EdDSA) ключи с закрытой частью подписывают токены,
+// а их открытые части публикуются в JWKS, чтобы другие сервисы могли
+// проверять токены без общего секрета.
+type Key struct {
+	id     string
+	method jwt.SigningMethod
+	// sign — ключ подписи: []byte, *rsa.PrivateKey или ed25519.PrivateKey; nil — только проверка.
+	sign any
+	// verify — ключ проверки: []byte, *rsa.PublicKey или ed25519.PublicKey.
+	verify any
+}
+
+// ID возвращает kid ключа.
+func (k Key) ID() string {
+	return k.id
+}
+
+// Algorithm возвращает алгоритм подписи: HS256, RS256 или EdDSA.
+func (k Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// CanSign сообщает, можно ли подписывать ключом токены.
+func (k Key) CanSign() bool {
+	return k.sign != nil
+}
+
+// HMACKey создаёт HS256-ключ из секрета; kid — префикс SHA-256 секрета,
+// поэтому он стабилен между перезапусками и не требует отдельной настройки.
+func HMACKey(secret string) (Key, error) {
+	if secret == "" {
+		return Key{}, ErrEmptySecret
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return Key{
+		id:     hex.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}, nil
+}
+
+// ParsePrivateKeyPEM разбирает закрытый ключ RSA (PKCS#1 или PKCS#8) или Ed25519 (PKCS#8).
+// Алгоритм определяется типом ключа: RSA — RS256, Ed25519 — EdDSA.
+func ParsePrivateKeyPEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("auth: no PEM block found")
+	}
+
+	var priv any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("auth: unsupported private key PEM type %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("auth: parse private key: %w", err)
+	}
+
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		key, err := publicKey(&priv.PublicKey)
+		key.sign = priv
+		return key, err
+	case ed25519.PrivateKey:
+		key, err := publicKey(priv.Public())
+		key.sign = priv
+		return key, err
+	default:
+		return Key{}, fmt.Errorf("auth: unsupported private key type %T", priv)
+	}
+}
+
+// ParsePublicKeyPEM разбирает открытый ключ RSA или Ed25519 для проверки токенов.
+// Закрытый ключ тоже принимается: используется только его открытая часть.
+func ParsePublicKeyPEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("auth: no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("auth: parse public key: %w", err)
+		}
+		return publicKey(pub)
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("auth: parse public key: %w", err)
+		}
+		return publicKey(pub)
+	default:
+		key, err := ParsePrivateKeyPEM(data)
+		key.sign = nil
+		return key, err
+	}
+}
+
+// LoadPrivateKeyFile читает закрытый ключ подписи из PEM-файла.
+func LoadPrivateKeyFile(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("auth: read signing key: %w", err)
+	}
+	return ParsePrivateKeyPEM(data)
+}
+
+// LoadPublicKeyFile читает ключ проверки из PEM-файла.
+func LoadPublicKeyFile(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("auth: read verification key: %w", err)
+	}
+	return ParsePublicKeyPEM(data)
+}
+
+// publicKey создаёт ключ проверки; kid — JWK thumbprint (RFC 7638).
+func publicKey(pub crypto.PublicKey) (Key, error) {
+	var key Key
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		key = Key{method: jwt.SigningMethodRS256, verify: pub}
+	case ed25519.PublicKey:
+		key = Key{method: jwt.SigningMethodEdDSA, verify: pub}
+	default:
+		return Key{}, fmt.Errorf("auth: unsupported public key type %T", pub)
+	}
+
+	jwk := key.jwk()
+	// Thumbprint считается по обязательным членам JWK в лексикографическом порядке.
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return Key{}, err
+	}
+	sum := sha256.Sum256(data)
+	key.id = base64.RawURLEncoding.EncodeToString(sum[:])
+	return key, nil
+}
+
+// JWK — открытый ключ в формате JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// N и E — модуль и экспонента RSA-ключа.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv и X — кривая и открытый ключ OKP (Ed25519).
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS — набор открытых ключей, отдаваемый по /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk возвращает открытую часть ключа в формате JWK; для HMAC-ключа — пустой JWK.
+func (k Key) jwk() JWK {
+	switch pub := k.verify.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	default:
+		return JWK{}
+	}
+}
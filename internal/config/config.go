@@ -4,32 +4,61 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds the configuration settings for the URL shortener service.
 // Fields include server address, base URL, storage paths, and auth secret.
+// AuthPreviousSecrets are former AUTH_SECRET values still accepted for token
+// verification, so the secret can be rotated without logging users out.
+// AuthSigningKeyFile is a PEM private key (RSA for RS256, Ed25519 for EdDSA);
+// when set, tokens are signed with it instead of AuthSecret and its public key
+// is published as JWKS. AuthVerificationKeyFiles are PEM public keys that are
+// only accepted for verification. With AuthStrict, identities are only issued
+// on routes and RPCs that create links; user-scoped routes answer 401 (gRPC:
+// Unauthenticated) without a valid token.
 type Config struct {
-	Address         string `env:"SERVER_ADDRESS"`
-	ShortenAddress  string `env:"BASE_URL"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`
-	DatabaseDSN     string `env:"DATABASE_DSN"`
-	AuthSecret      string `env:"AUTH_SECRET"`
-	AuditFile       string `env:"AUDIT_FILE"`
-	AuditURL        string `env:"AUDIT_URL"`
+	Address                  string        `env:"SERVER_ADDRESS"`
+	ShortenAddress           string        `env:"BASE_URL"`
+	FileStoragePath          string        `env:"FILE_STORAGE_PATH"`
+	DatabaseDSN              string        `env:"DATABASE_DSN"`
+	AuthSecret               string        `env:"AUTH_SECRET"`
+	AuthPreviousSecrets      []string      `env:"AUTH_PREVIOUS_SECRETS"`
+	AuthTokenTTL             time.Duration `env:"AUTH_TOKEN_TTL"`
+	AuthSigningKeyFile       string        `env:"AUTH_SIGNING_KEY_FILE"`
+	AuthVerificationKeyFiles []string      `env:"AUTH_VERIFICATION_KEY_FILES"`
+	AuthStrict               bool          `env:"AUTH_STRICT"`
+	AuditFile                string        `env:"AUDIT_FILE"`
+	AuditURL                 string        `env:"AUDIT_URL"`
+	GeoIPFile                string        `env:"GEOIP_FILE"`
+	RedirectCode             int           `env:"REDIRECT_CODE"`
+	GRPCAddress              string        `env:"GRPC_ADDRESS"`
+	IdempotencyTTL           time.Duration `env:"IDEMPOTENCY_TTL"`
 }
 
 // String returns a string representation of the config for logging or debugging.
 func (f *Config) String() string {
 	return fmt.Sprintf(
-		"--a %s --b %s --f %s --d %s --af %s --au %s",
+		"--a %s --b %s --f %s --d %s --af %s --au %s --geoip-file %s --redirect-code %d --grpc-address %s --idempotency-ttl %s --auth-token-ttl %s --auth-previous-secrets %d --auth-signing-key %s --auth-verification-keys %s --auth-strict %t",
 		f.Address,
 		f.ShortenAddress,
 		f.FileStoragePath,
 		f.DatabaseDSN,
 		f.AuditFile,
 		f.AuditURL,
+		f.GeoIPFile,
+		f.RedirectCode,
+		f.GRPCAddress,
+		f.IdempotencyTTL,
+		f.AuthTokenTTL,
+		len(f.AuthPreviousSecrets),
+		f.AuthSigningKeyFile,
+		strings.Join(f.AuthVerificationKeyFiles, ","),
+		f.AuthStrict,
 	)
 }
 
@@ -44,6 +73,10 @@ func InitConfig() *Config {
 	defaultAddr := "localhost:8080"
 	defaultBase := "http://localhost:8080"
 	defaultStoragePath := "./storageJson.json"
+	defaultRedirectCode := 307
+	defaultIdempotencyTTL := 24 * time.Hour
+	defaultAuthTokenTTL := 24 * time.Hour
+	var previousSecrets, verificationKeys string
 
 	flag.StringVar(&cfg.Address, "a", "", "Address to listen on")
 	flag.StringVar(&cfg.ShortenAddress, "b", "", "Base URL for shortened links")
@@ -51,6 +84,15 @@ func InitConfig() *Config {
 	flag.StringVar(&cfg.DatabaseDSN, "d", "", "Database DNS")
 	flag.StringVar(&cfg.AuditFile, "audit-file", "", "audit log file path")
 	flag.StringVar(&cfg.AuditURL, "audit-url", "", "audit http endpoint")
+	flag.StringVar(&cfg.GeoIPFile, "geoip-file", "", "GeoIP country database (mmdb) for redirect rules")
+	flag.IntVar(&cfg.RedirectCode, "redirect-code", 0, "Default redirect status code (301, 302, 307 or 308)")
+	flag.StringVar(&cfg.GRPCAddress, "grpc-address", "", "Address for the gRPC API (disabled if empty)")
+	flag.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", 0, "How long responses stored under an Idempotency-Key are replayed")
+	flag.StringVar(&previousSecrets, "auth-previous-secrets", "", "Comma-separated former auth secrets accepted for token verification")
+	flag.DurationVar(&cfg.AuthTokenTTL, "auth-token-ttl", 0, "Lifetime of issued auth tokens")
+	flag.StringVar(&cfg.AuthSigningKeyFile, "auth-signing-key", "", "PEM private key (RSA or Ed25519) used to sign auth tokens instead of the secret")
+	flag.StringVar(&verificationKeys, "auth-verification-keys", "", "Comma-separated PEM public keys accepted for token verification")
+	flag.BoolVar(&cfg.AuthStrict, "auth-strict", false, "Issue identities only on create routes; user-scoped routes require a valid token")
 	flag.Parse()
 
 	envAddress := os.Getenv("SERVER_ADDRESS")
@@ -60,6 +102,15 @@ func InitConfig() *Config {
 	envAuthSecret := os.Getenv("AUTH_SECRET")
 	envAuditFile := os.Getenv("AUDIT_FILE")
 	envAuditURL := os.Getenv("AUDIT_URL")
+	envGeoIPFile := os.Getenv("GEOIP_FILE")
+	envRedirectCode := os.Getenv("REDIRECT_CODE")
+	envGRPCAddress := os.Getenv("GRPC_ADDRESS")
+	envIdempotencyTTL := os.Getenv("IDEMPOTENCY_TTL")
+	envAuthPreviousSecrets := os.Getenv("AUTH_PREVIOUS_SECRETS")
+	envAuthTokenTTL := os.Getenv("AUTH_TOKEN_TTL")
+	envAuthSigningKeyFile := os.Getenv("AUTH_SIGNING_KEY_FILE")
+	envAuthVerificationKeyFiles := os.Getenv("AUTH_VERIFICATION_KEY_FILES")
+	envAuthStrict := os.Getenv("AUTH_STRICT")
 
 	if envAuditFile != "" {
 		cfg.AuditFile = envAuditFile
@@ -69,10 +120,73 @@ func InitConfig() *Config {
 		cfg.AuditURL = envAuditURL
 	}
 
+	if envGeoIPFile != "" {
+		cfg.GeoIPFile = envGeoIPFile
+	}
+
+	if envGRPCAddress != "" {
+		cfg.GRPCAddress = envGRPCAddress
+	}
+
+	if envRedirectCode != "" {
+		if code, err := strconv.Atoi(envRedirectCode); err == nil {
+			cfg.RedirectCode = code
+		} else {
+			fmt.Println("⚠️ invalid REDIRECT_CODE:", err)
+		}
+	}
+	if cfg.RedirectCode == 0 {
+		cfg.RedirectCode = defaultRedirectCode
+	}
+
+	if envIdempotencyTTL != "" {
+		if ttl, err := time.ParseDuration(envIdempotencyTTL); err == nil {
+			cfg.IdempotencyTTL = ttl
+		} else {
+			fmt.Println("⚠️ invalid IDEMPOTENCY_TTL:", err)
+		}
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		cfg.IdempotencyTTL = defaultIdempotencyTTL
+	}
+
 	if envAuthSecret != "" {
 		cfg.AuthSecret = envAuthSecret
 	}
 
+	if envAuthPreviousSecrets != "" {
+		previousSecrets = envAuthPreviousSecrets
+	}
+	cfg.AuthPreviousSecrets = splitList(previousSecrets)
+
+	if envAuthSigningKeyFile != "" {
+		cfg.AuthSigningKeyFile = envAuthSigningKeyFile
+	}
+
+	if envAuthVerificationKeyFiles != "" {
+		verificationKeys = envAuthVerificationKeyFiles
+	}
+	cfg.AuthVerificationKeyFiles = splitList(verificationKeys)
+
+	if envAuthStrict != "" {
+		if strict, err := strconv.ParseBool(envAuthStrict); err == nil {
+			cfg.AuthStrict = strict
+		} else {
+			fmt.Println("⚠️ invalid AUTH_STRICT:", err)
+		}
+	}
+
+	if envAuthTokenTTL != "" {
+		if ttl, err := time.ParseDuration(envAuthTokenTTL); err == nil {
+			cfg.AuthTokenTTL = ttl
+		} else {
+			fmt.Println("⚠️ invalid AUTH_TOKEN_TTL:", err)
+		}
+	}
+	if cfg.AuthTokenTTL <= 0 {
+		cfg.AuthTokenTTL = defaultAuthTokenTTL
+	}
+
 	if envAddress != "" {
 		cfg.Address = envAddress
 	} else if cfg.Address == "" {
@@ -97,3 +211,14 @@ func InitConfig() *Config {
 
 	return &cfg
 }
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
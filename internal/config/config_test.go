@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -76,3 +77,111 @@ func TestInitConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestInitConfig_RedirectCode(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd"}
+	cfg := config.InitConfig()
+	assert.Equal(t, 307, cfg.RedirectCode)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-redirect-code", "302"}
+	t.Setenv("REDIRECT_CODE", "301")
+	cfg = config.InitConfig()
+	assert.Equal(t, 301, cfg.RedirectCode)
+}
+
+func TestInitConfig_GRPCAddress(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd"}
+	cfg := config.InitConfig()
+	assert.Empty(t, cfg.GRPCAddress)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-grpc-address", ":3200"}
+	cfg = config.InitConfig()
+	assert.Equal(t, ":3200", cfg.GRPCAddress)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-grpc-address", ":3200"}
+	t.Setenv("GRPC_ADDRESS", ":3300")
+	cfg = config.InitConfig()
+	assert.Equal(t, ":3300", cfg.GRPCAddress)
+}
+
+func TestInitConfig_IdempotencyTTL(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd"}
+	cfg := config.InitConfig()
+	assert.Equal(t, 24*time.Hour, cfg.IdempotencyTTL)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-idempotency-ttl", "1h"}
+	cfg = config.InitConfig()
+	assert.Equal(t, time.Hour, cfg.IdempotencyTTL)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-idempotency-ttl", "1h"}
+	t.Setenv("IDEMPOTENCY_TTL", "30m")
+	cfg = config.InitConfig()
+	assert.Equal(t, 30*time.Minute, cfg.IdempotencyTTL)
+}
+
+func TestInitConfig_Auth(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd"}
+	cfg := config.InitConfig()
+	assert.Equal(t, 24*time.Hour, cfg.AuthTokenTTL)
+	assert.Empty(t, cfg.AuthPreviousSecrets)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-token-ttl", "2h", "-auth-previous-secrets", "a, b,,"}
+	cfg = config.InitConfig()
+	assert.Equal(t, 2*time.Hour, cfg.AuthTokenTTL)
+	assert.Equal(t, []string{"a", "b"}, cfg.AuthPreviousSecrets)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-previous-secrets", "a"}
+	t.Setenv("AUTH_SECRET", "current")
+	t.Setenv("AUTH_PREVIOUS_SECRETS", "old1,old2")
+	t.Setenv("AUTH_TOKEN_TTL", "30m")
+	cfg = config.InitConfig()
+	assert.Equal(t, "current", cfg.AuthSecret)
+	assert.Equal(t, []string{"old1", "old2"}, cfg.AuthPreviousSecrets)
+	assert.Equal(t, 30*time.Minute, cfg.AuthTokenTTL)
+	assert.NotContains(t, cfg.String(), "old1")
+}
+
+func TestInitConfig_AuthKeys(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-signing-key", "flag.pem", "-auth-verification-keys", "a.pem,b.pem"}
+	cfg := config.InitConfig()
+	assert.Equal(t, "flag.pem", cfg.AuthSigningKeyFile)
+	assert.Equal(t, []string{"a.pem", "b.pem"}, cfg.AuthVerificationKeyFiles)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-signing-key", "flag.pem"}
+	t.Setenv("AUTH_SIGNING_KEY_FILE", "env.pem")
+	t.Setenv("AUTH_VERIFICATION_KEY_FILES", "old.pem")
+	cfg = config.InitConfig()
+	assert.Equal(t, "env.pem", cfg.AuthSigningKeyFile)
+	assert.Equal(t, []string{"old.pem"}, cfg.AuthVerificationKeyFiles)
+}
+
+func TestInitConfig_AuthStrict(t *testing.T) {
+	resetEnvAndFlags()
+	os.Args = []string{"cmd"}
+	cfg := config.InitConfig()
+	assert.False(t, cfg.AuthStrict, "anonymous identities are issued by default")
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-strict"}
+	cfg = config.InitConfig()
+	assert.True(t, cfg.AuthStrict)
+
+	resetEnvAndFlags()
+	os.Args = []string{"cmd", "-auth-strict"}
+	t.Setenv("AUTH_STRICT", "false")
+	cfg = config.InitConfig()
+	assert.False(t, cfg.AuthStrict, "env overrides the flag")
+}
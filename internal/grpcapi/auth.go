@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/BuJIKuH/go-musthave-shortener-tpl/api/shortener/v1"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenMetadataKey — ключ metadata с токеном пользователя в запросе и в заголовке ответа.
+const TokenMetadataKey = "authorization"
+
+type userIDKey struct{}
+
+// provisionMethods — вызовы создания ссылок, которым в строгом режиме выдаётся новый
+// пользователь, как маршрутам с ProvisionUser в HTTP API.
+var provisionMethods = map[string]bool{
+	pb.Shortener_Shorten_FullMethodName:      true,
+	pb.Shortener_ShortenBatch_FullMethodName: true,
+}
+
+// UserIDFromContext возвращает userID, сохранённый AuthInterceptor.
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey{}).(string)
+	return id
+}
+
+// AuthInterceptor возвращает unary interceptor, авторизующий вызовы токеном auth.Manager.
+//
+// Поведение:
+//  1. Токен читается из metadata "authorization", префикс "Bearer " необязателен.
+//  2. Если токен не передан, создаётся новый userID, его токен отправляется клиенту
+//     в заголовке ответа "authorization" — как cookie auth_token в HTTP API.
+//  3. Если токен передан, но невалиден, вызов отклоняется с codes.Unauthenticated:
+//     в отличие от браузера, сервис должен узнать об ошибке, а не получить нового пользователя.
+//  4. Если токен пора продлить (см. auth.Manager.Authenticate), новый токен с тем же
+//     userID отправляется в заголовке ответа "authorization".
+//  5. userID сохраняется в контекст и доступен через UserIDFromContext.
+//
+// При strict (AUTH_STRICT) пункт 2 выполняется только для вызовов создания ссылок
+// (Shorten, ShortenBatch). Остальные вызовы без токена выполняются без userID:
+// ListUserURLs и DeleteUserURLs отвечают codes.Unauthenticated, а не работают
+// с новым пустым пользователем.
+func AuthInterceptor(am *auth.Manager, logger *zap.Logger, strict bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		token := tokenFromMetadata(ctx)
+
+		if token == "" && strict && !provisionMethods[info.FullMethod] {
+			return next(ctx, req)
+		}
+		if token == "" {
+			userID := uuid.NewString()
+			newToken, err := am.GenerateToken(userID)
+			if err != nil {
+				logger.Error("failed to generate token", zap.Error(err))
+				return nil, status.Error(codes.Internal, "failed to generate token")
+			}
+			if err := grpc.SetHeader(ctx, metadata.Pairs(TokenMetadataKey, newToken)); err != nil {
+				logger.Error("failed to send token header", zap.Error(err))
+			}
+			return next(context.WithValue(ctx, userIDKey{}, userID), req)
+		}
+
+		userID, renewed, err := am.Authenticate(token)
+		if err != nil {
+			logger.Info("invalid token", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if renewed != "" {
+			if err := grpc.SetHeader(ctx, metadata.Pairs(TokenMetadataKey, renewed)); err != nil {
+				logger.Error("failed to send token header", zap.Error(err))
+			}
+		}
+		return next(context.WithValue(ctx, userIDKey{}, userID), req)
+	}
+}
+
+// tokenFromMetadata извлекает токен из входящей metadata.
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(TokenMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	token := strings.TrimSpace(values[0])
+	if len(token) > len("Bearer ") && strings.EqualFold(token[:len("Bearer ")], "Bearer ") {
+		token = strings.TrimSpace(token[len("Bearer "):])
+	}
+	return token
+}
@@ -0,0 +1,300 @@
+// Package grpcapi реализует gRPC API сервиса сокращения ссылок (api/shortener/v1).
+// Сервер использует те же storage.Storage, service.Deleter и audit.Service, что и HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "github.com/BuJIKuH/go-musthave-shortener-tpl/api/shortener/v1"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/shortener"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// saveTimeout — таймаут сохранения в хранилище, как у HTTP-хендлеров.
+	saveTimeout = 3 * time.Second
+)
+
+// Server реализует pb.ShortenerServer.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	store    storage.Storage
+	deleter  *service.Deleter
+	auditSvc *audit.Service
+	baseURL  string
+	limiter  *service.AttemptLimiter
+}
+
+// NewServer создаёт gRPC сервер поверх общих хранилища, Deleter и сервиса аудита.
+// limiter — общий с HTTP API ограничитель попыток ввода пароля ссылки.
+// baseURL — базовый адрес коротких ссылок.
+func NewServer(store storage.Storage, deleter *service.Deleter, auditSvc *audit.Service, limiter *service.AttemptLimiter, baseURL string) *Server {
+	return &Server{
+		store:    store,
+		deleter:  deleter,
+		auditSvc: auditSvc,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		limiter:  limiter,
+	}
+}
+
+// shortURL формирует короткую ссылку по идентификатору.
+func (s *Server) shortURL(id string) string {
+	return fmt.Sprintf("%s/%s", s.baseURL, id)
+}
+
+// userID возвращает пользователя вызова или ошибку codes.Unauthenticated.
+func userID(ctx context.Context) (string, error) {
+	id := UserIDFromContext(ctx)
+	if id == "" {
+		return "", status.Error(codes.Unauthenticated, "missing user id")
+	}
+	return id, nil
+}
+
+// validateURL проверяет, что URL абсолютный, и возвращает текст ошибки или пустую строку.
+func validateURL(raw string) string {
+	if strings.TrimSpace(raw) == "" {
+		return "url is empty"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "url must be an absolute URL"
+	}
+	return ""
+}
+
+// Shorten сокращает один URL.
+//
+// Ошибки:
+//   - InvalidArgument — URL пуст или не абсолютный.
+//   - Unauthenticated — отсутствует userID.
+//   - Internal — ошибка генерации ID или сохранения.
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	originalURL := strings.TrimSpace(req.GetUrl())
+	if msg := validateURL(originalURL); msg != "" {
+		return nil, status.Error(codes.InvalidArgument, msg)
+	}
+
+	id, err := shortener.GenerateID()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate id")
+	}
+
+	saveCtx, cancel := context.WithTimeout(ctx, saveTimeout)
+	defer cancel()
+
+	shortID, err := s.store.Save(saveCtx, userID, id, originalURL)
+	if err != nil && !errors.Is(err, storage.ErrURLExists) {
+		return nil, status.Error(codes.Internal, "failed to save url")
+	}
+	// Хранилище в памяти возвращает существующий ID без ErrURLExists.
+	existing := err != nil || shortID != id
+
+	if !existing {
+		s.auditSvc.Notify(ctx, audit.Event{
+			TS:      time.Now().Unix(),
+			Action:  "shorten",
+			UserID:  userID,
+			OwnerID: userID,
+			ShortID: shortID,
+			URL:     originalURL,
+		})
+	}
+
+	return &pb.ShortenResponse{
+		Id:       shortID,
+		ShortUrl: s.shortURL(shortID),
+		Existing: existing,
+	}, nil
+}
+
+// ShortenBatch сокращает несколько URL. Некорректные элементы не прерывают пакет:
+// их итог возвращается со статусом BATCH_STATUS_INVALID и текстом ошибки.
+//
+// Ошибки:
+//   - InvalidArgument — пустой пакет.
+//   - Unauthenticated — отсутствует userID.
+//   - Internal — ошибка генерации ID или сохранения.
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := req.GetItems()
+	if len(items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty batch")
+	}
+
+	batch := make([]storage.BatchItem, 0, len(items))
+	ids := make([]string, len(items))
+	results := make([]*pb.BatchResult, len(items))
+
+	for i, item := range items {
+		results[i] = &pb.BatchResult{CorrelationId: item.GetCorrelationId()}
+		if msg := validateURL(item.GetOriginalUrl()); msg != "" {
+			results[i].Status = pb.BatchStatus_BATCH_STATUS_INVALID
+			results[i].Error = msg
+			continue
+		}
+
+		id, err := shortener.GenerateID()
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to generate id")
+		}
+		ids[i] = id
+		batch = append(batch, storage.BatchItem{ShortID: id, OriginalURL: item.GetOriginalUrl()})
+	}
+
+	saveCtx, cancel := context.WithTimeout(ctx, saveTimeout)
+	defer cancel()
+
+	created, existing, err := s.store.SaveBatch(saveCtx, userID, batch)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to save batch")
+	}
+
+	for i, item := range items {
+		if results[i].Status == pb.BatchStatus_BATCH_STATUS_INVALID {
+			continue
+		}
+		original := item.GetOriginalUrl()
+		switch saved, ok := created[original]; {
+		case ok && saved == ids[i]:
+			results[i].Status = pb.BatchStatus_BATCH_STATUS_CREATED
+			results[i].ShortUrl = s.shortURL(saved)
+		case ok:
+			results[i].Status = pb.BatchStatus_BATCH_STATUS_EXISTING
+			results[i].ShortUrl = s.shortURL(saved)
+		default:
+			if saved, ok := existing[original]; ok {
+				results[i].Status = pb.BatchStatus_BATCH_STATUS_EXISTING
+				results[i].ShortUrl = s.shortURL(saved)
+				continue
+			}
+			results[i].Status = pb.BatchStatus_BATCH_STATUS_INVALID
+			results[i].Error = "url was not saved"
+		}
+	}
+
+	return &pb.ShortenBatchResponse{Items: results}, nil
+}
+
+// Expand раскрывает короткую ссылку без редиректа: счётчик max_clicks не уменьшается,
+// аудит не пишется. Для защищённой ссылки постороннему пользователю нужен пароль.
+//
+// Ошибки:
+//   - NotFound — ID не найден.
+//   - PermissionDenied — пароль не передан или неверен.
+//   - ResourceExhausted — превышен лимит неудачных попыток ввода пароля.
+func (s *Server) Expand(ctx context.Context, req *pb.ExpandRequest) (*pb.ExpandResponse, error) {
+	rec, ok := s.store.Get(req.GetId())
+	if !ok || rec == nil {
+		return nil, status.Error(codes.NotFound, "link not found")
+	}
+
+	if uid := UserIDFromContext(ctx); rec.PasswordHash != "" && (uid == "" || uid != rec.UserID) {
+		if err := s.checkPassword(rec, req.GetPassword()); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &pb.ExpandResponse{
+		Id:          rec.ShortID,
+		ShortUrl:    s.shortURL(rec.ShortID),
+		OriginalUrl: rec.OriginalURL,
+		Status:      linkStatus(rec),
+	}
+	if !rec.CreatedAt.IsZero() {
+		resp.CreatedAt = timestamppb.New(rec.CreatedAt)
+	}
+	return resp, nil
+}
+
+// checkPassword проверяет пароль защищённой ссылки с ограничением числа попыток.
+func (s *Server) checkPassword(rec *storage.URLRecord, password string) error {
+	if password == "" {
+		return status.Error(codes.PermissionDenied, "password required")
+	}
+	if ok, retryAfter := s.limiter.Allow(rec.ShortID); !ok {
+		return status.Errorf(codes.ResourceExhausted, "too many password attempts, retry after %s", retryAfter.Round(time.Second))
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(rec.PasswordHash), []byte(password)); err != nil {
+		return status.Error(codes.PermissionDenied, "invalid password")
+	}
+	s.limiter.Reset(rec.ShortID)
+	return nil
+}
+
+// linkStatus возвращает статус ссылки: удалена, истекла (срок действия или max_clicks) или активна.
+func linkStatus(rec *storage.URLRecord) pb.LinkStatus {
+	switch {
+	case rec.Deleted:
+		return pb.LinkStatus_LINK_STATUS_DELETED
+	case rec.Expired(time.Now()) || rec.MaxClicks > 0 && rec.ClicksLeft <= 0:
+		return pb.LinkStatus_LINK_STATUS_EXPIRED
+	default:
+		return pb.LinkStatus_LINK_STATUS_ACTIVE
+	}
+}
+
+// ListUserURLs возвращает ссылки текущего пользователя; пустой список не считается ошибкой.
+//
+// Ошибки:
+//   - Unauthenticated — отсутствует userID.
+//   - Internal — ошибка хранилища.
+func (s *Server) ListUserURLs(ctx context.Context, _ *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.store.GetUserURLs(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list urls")
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(urls))}
+	for _, u := range urls {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    s.shortURL(u.ShortID),
+			OriginalUrl: u.OriginalURL,
+		})
+	}
+	return resp, nil
+}
+
+// DeleteUserURLs ставит задачу удаления в service.Deleter; события аудита "delete"
+// Deleter отправляет после пометки ссылок.
+//
+// Ошибки:
+//   - InvalidArgument — список идентификаторов пуст.
+//   - Unauthenticated — отсутствует userID.
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := req.GetIds()
+	if len(ids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "ids are required")
+	}
+
+	s.deleter.Enqueue(service.DeleteTask{UserID: userID, IDs: ids})
+	return &pb.DeleteUserURLsResponse{}, nil
+}
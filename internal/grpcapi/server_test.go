@@ -0,0 +1,260 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/BuJIKuH/go-musthave-shortener-tpl/api/shortener/v1"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/grpcapi"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const baseURL = "http://localhost:8080"
+
+type testEnv struct {
+	client pb.ShortenerClient
+	store  *storage.InMemoryStorage
+	am     *auth.Manager
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnvWithAuth(t, false)
+}
+
+// newTestEnvWithAuth поднимает сервер с AuthInterceptor в обычном или строгом режиме.
+func newTestEnvWithAuth(t *testing.T, strict bool) *testEnv {
+	t.Helper()
+
+	logger := zap.NewNop()
+	store := storage.NewInMemoryStorage()
+	am, err := auth.NewManager("test-secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	deleter := service.NewDeleter(store.MarkDeleted)
+	t.Cleanup(deleter.Close)
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcapi.AuthInterceptor(am, logger, strict)))
+	pb.RegisterShortenerServer(srv, grpcapi.NewServer(store, deleter, audit.NewService(logger), service.NewPasswordLimiter(), baseURL))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testEnv{client: pb.NewShortenerClient(conn), store: store, am: am}
+}
+
+// withToken добавляет токен пользователя в исходящую metadata.
+func withToken(t *testing.T, am *auth.Manager, userID string) context.Context {
+	t.Helper()
+	token, err := am.GenerateToken(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), grpcapi.TokenMetadataKey, "Bearer "+token)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	env := newTestEnv(t)
+
+	t.Run("issues token when missing", func(t *testing.T) {
+		var header metadata.MD
+		resp, err := env.client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/new"}, grpc.Header(&header))
+		assert.NoError(t, err)
+
+		tokens := header.Get(grpcapi.TokenMetadataKey)
+		if !assert.Len(t, tokens, 1) {
+			return
+		}
+		userID, err := env.am.ParseToken(tokens[0], zap.NewNop())
+		assert.NoError(t, err)
+
+		rec, ok := env.store.Get(resp.GetId())
+		if assert.True(t, ok) {
+			assert.Equal(t, userID, rec.UserID)
+		}
+	})
+
+	t.Run("rejects invalid token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.TokenMetadataKey, "garbage")
+		_, err := env.client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("accepts token without bearer prefix", func(t *testing.T) {
+		token, err := env.am.GenerateToken("user-plain")
+		assert.NoError(t, err)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.TokenMetadataKey, token)
+		_, err = env.client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestAuthInterceptor_Strict(t *testing.T) {
+	env := newTestEnvWithAuth(t, true)
+
+	t.Run("user-scoped calls require a token", func(t *testing.T) {
+		var header metadata.MD
+		_, err := env.client.ListUserURLs(context.Background(), &pb.ListUserURLsRequest{}, grpc.Header(&header))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Empty(t, header.Get(grpcapi.TokenMetadataKey), "no user is issued")
+
+		_, err = env.client.DeleteUserURLs(context.Background(), &pb.DeleteUserURLsRequest{Ids: []string{"abc"}})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("shorten issues a token", func(t *testing.T) {
+		var header metadata.MD
+		_, err := env.client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/strict"}, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Len(t, header.Get(grpcapi.TokenMetadataKey), 1)
+	})
+
+	t.Run("valid token is accepted", func(t *testing.T) {
+		_, err := env.client.ListUserURLs(withToken(t, env.am, "user-strict"), &pb.ListUserURLsRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestServer_Shorten(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := withToken(t, env.am, "user1")
+
+	first, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.False(t, first.GetExisting())
+	assert.Equal(t, baseURL+"/"+first.GetId(), first.GetShortUrl())
+
+	second, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.True(t, second.GetExisting())
+	assert.Equal(t, first.GetId(), second.GetId())
+
+	_, err = env.client.Shorten(ctx, &pb.ShortenRequest{Url: "not a url"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_ShortenBatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := withToken(t, env.am, "user1")
+
+	_, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://old.example.com"})
+	assert.NoError(t, err)
+
+	resp, err := env.client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.BatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://new.example.com"},
+		{CorrelationId: "2", OriginalUrl: "https://old.example.com"},
+		{CorrelationId: "3", OriginalUrl: ""},
+	}})
+	assert.NoError(t, err)
+	if !assert.Len(t, resp.GetItems(), 3) {
+		return
+	}
+
+	assert.Equal(t, pb.BatchStatus_BATCH_STATUS_CREATED, resp.GetItems()[0].GetStatus())
+	assert.NotEmpty(t, resp.GetItems()[0].GetShortUrl())
+	assert.Equal(t, pb.BatchStatus_BATCH_STATUS_EXISTING, resp.GetItems()[1].GetStatus())
+	assert.Equal(t, pb.BatchStatus_BATCH_STATUS_INVALID, resp.GetItems()[2].GetStatus())
+	assert.Equal(t, "3", resp.GetItems()[2].GetCorrelationId())
+	assert.NotEmpty(t, resp.GetItems()[2].GetError())
+
+	_, err = env.client.ShortenBatch(ctx, &pb.ShortenBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Expand(t *testing.T) {
+	env := newTestEnv(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	_, err = env.store.SaveRecord(context.Background(), storage.URLRecord{
+		ShortID:      "locked",
+		OriginalURL:  "https://example.com/locked",
+		UserID:       "owner",
+		PasswordHash: string(hash),
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		userID   string
+		password string
+		wantCode codes.Code
+	}{
+		{name: "owner without password", userID: "owner", wantCode: codes.OK},
+		{name: "stranger without password", userID: "stranger", wantCode: codes.PermissionDenied},
+		{name: "stranger with wrong password", userID: "stranger", password: "nope", wantCode: codes.PermissionDenied},
+		{name: "stranger with password", userID: "stranger", password: "secret", wantCode: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.client.Expand(withToken(t, env.am, tt.userID), &pb.ExpandRequest{Id: "locked", Password: tt.password})
+			if !assert.Equal(t, tt.wantCode, status.Code(err)) || tt.wantCode != codes.OK {
+				return
+			}
+			assert.Equal(t, "https://example.com/locked", resp.GetOriginalUrl())
+			assert.Equal(t, pb.LinkStatus_LINK_STATUS_ACTIVE, resp.GetStatus())
+			assert.NotNil(t, resp.GetCreatedAt())
+		})
+	}
+
+	_, err = env.client.Expand(withToken(t, env.am, "owner"), &pb.ExpandRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ListAndDeleteUserURLs(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := withToken(t, env.am, "user1")
+
+	list, err := env.client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.GetUrls())
+
+	created, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/delete-me"})
+	assert.NoError(t, err)
+
+	list, err = env.client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	assert.NoError(t, err)
+	if !assert.Len(t, list.GetUrls(), 1) {
+		return
+	}
+	assert.Equal(t, created.GetShortUrl(), list.GetUrls()[0].GetShortUrl())
+	assert.Equal(t, "https://example.com/delete-me", list.GetUrls()[0].GetOriginalUrl())
+
+	_, err = env.client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = env.client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{Ids: []string{created.GetId()}})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		resp, err := env.client.Expand(ctx, &pb.ExpandRequest{Id: created.GetId()})
+		return err == nil && resp.GetStatus() == pb.LinkStatus_LINK_STATUS_DELETED
+	}, 3*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Пути учётных записей.
+const (
+	RegisterPath = "/api/user/register"
+	LoginPath    = "/api/user/login"
+	LogoutPath   = "/api/user/logout"
+)
+
+const (
+	// minPasswordLength — минимальная длина пароля учётной записи.
+	minPasswordLength = 8
+	// maxPasswordLength — bcrypt учитывает только первые 72 байта пароля.
+	maxPasswordLength = 72
+)
+
+// usernamePattern — допустимое имя пользователя (после приведения к нижнему регистру).
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,64}$`)
+
+// Credentials — тело запросов регистрации и входа.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Claim переносит ссылки текущего анонимного пользователя на учётную запись.
+	Claim bool `json:"claim,omitempty"`
+}
+
+// Account — ответ на регистрацию и вход.
+type Account struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// Claimed — число ссылок, перенесённых с анонимного пользователя.
+	Claimed int `json:"claimed"`
+}
+
+// decodeCredentials читает и проверяет тело запроса; при ошибке ответ уже записан.
+func decodeCredentials(c *gin.Context) (Credentials, bool) {
+	var req Credentials
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		problem.Abort(c, problem.New(problem.InvalidJSON, ""))
+		return req, false
+	}
+	req.Username = strings.ToLower(strings.TrimSpace(req.Username))
+	if req.Username == "" || req.Password == "" {
+		problem.Abort(c, problem.New(problem.InvalidRequest, "username and password are required"))
+		return req, false
+	}
+	return req, true
+}
+
+// claimAnonymousURLs переносит ссылки пользователя fromID на учётную запись account.
+// Ссылки другой учётной записи не переносятся: забрать можно только анонимные ссылки.
+func claimAnonymousURLs(ctx context.Context, s storage.Storage, fromID string, account *storage.User) (int, error) {
+	if fromID == "" || fromID == account.ID {
+		return 0, nil
+	}
+	_, err := s.GetUser(ctx, fromID)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return 0, err
+	}
+	return s.ClaimURLs(ctx, fromID, account.ID)
+}
+
+// signIn выдаёт cookie учётной записи, при необходимости переносит анонимные ссылки
+// и отвечает ресурсом Account со статусом status.
+func signIn(c *gin.Context, s storage.Storage, am *auth.Manager, auditSvc *audit.Service, action string, status int, user *storage.User, claim bool) {
+	claimed := 0
+	if claim {
+		var err error
+		claimed, err = claimAnonymousURLs(c.Request.Context(), s, getUserID(c), user)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to claim links"))
+			return
+		}
+	}
+
+	token, err := am.GenerateToken(user.ID)
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate token"))
+		return
+	}
+	middleware.SetAuthCookie(c, am, token)
+	c.JSON(status, Account{ID: user.ID, Username: user.Username, Claimed: claimed})
+
+	auditSvc.Notify(c.Request.Context(), audit.Event{
+		TS:     time.Now().Unix(),
+		Action: action,
+		UserID: user.ID,
+		URL:    c.Request.URL.Path,
+	})
+}
+
+// Register возвращает Gin handler, создающий учётную запись с именем и паролем.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - am: менеджер авторизации для выпуска токена учётной записи
+//   - auditSvc: сервис audit.Service для логирования действий
+//
+// Тело запроса: {"username": "...", "password": "...", "claim": true}. Имя — 3–64 символа
+// [a-z0-9_.-] без учёта регистра, пароль — 8–72 байта. При claim ссылки текущего анонимного
+// пользователя переносятся на учётную запись. Анонимная работа без учётной записи остаётся
+// доступной: регистрация необязательна.
+//
+// HTTP ответы:
+//   - 201 Created — Account; cookie auth_token выдана учётной записи.
+//   - 400 Bad Request — некорректный JSON, имя или пароль.
+//   - 409 Conflict — имя занято.
+//   - 500 Internal Server Error — ошибка хранилища.
+func Register(s storage.Storage, am *auth.Manager, auditSvc *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := decodeCredentials(c)
+		if !ok {
+			return
+		}
+		if !usernamePattern.MatchString(req.Username) {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "username must be 3-64 characters of a-z, 0-9, '_', '.', '-'"))
+			return
+		}
+		if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "password must be 8-72 bytes long"))
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to hash password"))
+			return
+		}
+		user := storage.User{
+			ID:           uuid.NewString(),
+			Username:     req.Username,
+			PasswordHash: string(hash),
+			CreatedAt:    time.Now(),
+		}
+		err = s.CreateUser(c.Request.Context(), user)
+		if errors.Is(err, storage.ErrUserExists) {
+			problem.Abort(c, problem.New(problem.UsernameTaken, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save user"))
+			return
+		}
+
+		signIn(c, s, am, auditSvc, "register", http.StatusCreated, &user, req.Claim)
+	}
+}
+
+// dummyPasswordHash — хеш, с которым сравнивается пароль несуществующего пользователя,
+// чтобы время ответа не выдавало, зарегистрировано ли имя.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Login возвращает Gin handler, выполняющий вход в учётную запись.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - am: менеджер авторизации для выпуска токена учётной записи
+//   - auditSvc: сервис audit.Service для логирования действий
+//   - limiter: ограничитель попыток входа по адресу клиента и имени пользователя
+//
+// Тело запроса: {"username": "...", "password": "...", "claim": true}. При claim ссылки
+// текущего анонимного пользователя переносятся на учётную запись.
+// Неудачные попытки ограничиваются для пары «адрес клиента + имя пользователя».
+//
+// HTTP ответы:
+//   - 200 OK — Account; cookie auth_token выдана учётной записи.
+//   - 400 Bad Request — некорректный JSON или пустые имя и пароль.
+//   - 401 Unauthorized — неверное имя или пароль.
+//   - 429 Too Many Requests — превышен лимит неудачных попыток.
+//   - 500 Internal Server Error — ошибка хранилища.
+func Login(s storage.Storage, am *auth.Manager, auditSvc *audit.Service, limiter *service.LoginLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := decodeCredentials(c)
+		if !ok {
+			return
+		}
+		if ok, retryAfter := limiter.Allow(c.ClientIP(), req.Username); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			problem.Abort(c, problem.New(problem.TooManyAttempts, ""))
+			return
+		}
+
+		user, err := s.GetUserByName(c.Request.Context(), req.Username)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to find user"))
+			return
+		}
+		hash := dummyPasswordHash()
+		if user != nil {
+			hash = []byte(user.PasswordHash)
+		}
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || user == nil {
+			problem.Abort(c, problem.New(problem.InvalidCredentials, ""))
+			return
+		}
+		limiter.Reset(c.ClientIP(), req.Username)
+
+		signIn(c, s, am, auditSvc, "login", http.StatusOK, user, req.Claim)
+	}
+}
+
+// Logout возвращает Gin handler, завершающий сеанс: cookie auth_token удаляется,
+// и следующий запрос выполняется от имени нового анонимного пользователя.
+//
+// Ограничение: токены не хранятся на сервере и не отзываются. Сохранённая копия токена
+// (например, переданная в Authorization: Bearer) остаётся действительной до истечения
+// срока жизни (AUTH_TOKEN_TTL); немедленно отозвать все токены можно только сменой ключа
+// подписи без сохранения предыдущего.
+//
+// HTTP ответы:
+//   - 204 No Content — cookie удалена.
+func Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ClearAuthCookie(c)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// --- TEST /api/user/register, /api/user/login, /api/user/logout ---
+func TestAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	am, err := auth.NewManager("secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	store := storage.NewInMemoryStorage()
+	auditSvc := audit.NewService(zap.NewNop())
+	ctx := context.Background()
+
+	router := gin.New()
+	// Текущий пользователь задаётся заголовком, как его выставил бы AuthMiddleware.
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.POST(handler.RegisterPath, handler.Register(store, am, auditSvc))
+	router.POST(handler.LoginPath, handler.Login(store, am, auditSvc, service.NewLoginLimiter()))
+	router.POST(handler.LogoutPath, handler.Logout())
+
+	doFrom := func(remoteAddr, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	do := func(path, user, body string) *httptest.ResponseRecorder {
+		return doFrom("192.0.2.1:1234", path, user, body)
+	}
+	account := func(w *httptest.ResponseRecorder) handler.Account {
+		var a handler.Account
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &a))
+		return a
+	}
+	tokenUser := func(w *httptest.ResponseRecorder) string {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "auth_token" {
+				userID, err := am.ParseToken(c.Value, zap.NewNop())
+				assert.NoError(t, err)
+				return userID
+			}
+		}
+		return ""
+	}
+
+	_, err = store.Save(ctx, "anon-1", "a1", "https://example.com/1")
+	assert.NoError(t, err)
+	_, err = store.Save(ctx, "anon-2", "a2", "https://example.com/2")
+	assert.NoError(t, err)
+
+	var alice handler.Account
+	t.Run("register claims anonymous links", func(t *testing.T) {
+		w := do(handler.RegisterPath, "anon-1", `{"username":" Alice ","password":"correct horse","claim":true}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		alice = account(w)
+		assert.Equal(t, "alice", alice.Username)
+		assert.Equal(t, 1, alice.Claimed)
+		assert.NotEqual(t, "anon-1", alice.ID)
+		assert.Equal(t, alice.ID, tokenUser(w), "cookie belongs to the account")
+
+		rec, _ := store.Get("a1")
+		assert.Equal(t, alice.ID, rec.UserID)
+	})
+
+	t.Run("register validation", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, do(handler.RegisterPath, "anon-3", `{"username":"ALICE","password":"another pass"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(handler.RegisterPath, "anon-3", `{"username":"bob","password":"short"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(handler.RegisterPath, "anon-3", `{"username":"b","password":"long enough"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(handler.RegisterPath, "anon-3", `{"username":"bob space","password":"long enough"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(handler.RegisterPath, "anon-3", `{`).Code)
+	})
+
+	t.Run("login without claim keeps anonymous links", func(t *testing.T) {
+		w := do(handler.LoginPath, "anon-2", `{"username":"alice","password":"correct horse"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		got := account(w)
+		assert.Equal(t, alice.ID, got.ID)
+		assert.Equal(t, 0, got.Claimed)
+		assert.Equal(t, alice.ID, tokenUser(w))
+
+		rec, _ := store.Get("a2")
+		assert.Equal(t, "anon-2", rec.UserID)
+	})
+
+	t.Run("login with claim", func(t *testing.T) {
+		w := do(handler.LoginPath, "anon-2", `{"username":"alice","password":"correct horse","claim":true}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, account(w).Claimed)
+	})
+
+	t.Run("links of another account are not claimed", func(t *testing.T) {
+		w := do(handler.RegisterPath, "", `{"username":"bob","password":"bob password"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		bob := account(w)
+
+		w = do(handler.LoginPath, bob.ID, `{"username":"alice","password":"correct horse","claim":true}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0, account(w).Claimed)
+		urls, err := store.GetUserURLs(ctx, alice.ID)
+		assert.NoError(t, err)
+		assert.Len(t, urls, 2)
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		w := do(handler.LoginPath, "anon-3", `{"username":"alice","password":"wrong password"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, tokenUser(w))
+		assert.Equal(t, http.StatusUnauthorized, do(handler.LoginPath, "anon-3", `{"username":"nobody","password":"wrong password"}`).Code)
+	})
+
+	t.Run("failed logins are limited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			do(handler.LoginPath, "anon-3", `{"username":"bob","password":"wrong password"}`)
+		}
+		w := do(handler.LoginPath, "anon-3", `{"username":"bob","password":"bob password"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("other clients do not lock out the user", func(t *testing.T) {
+		w := doFrom("198.51.100.7:1234", handler.LoginPath, "anon-4", `{"username":"bob","password":"bob password"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, tokenUser(w))
+	})
+
+	t.Run("logout clears the cookie", func(t *testing.T) {
+		w := do(handler.LogoutPath, alice.ID, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		cookies := w.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, "auth_token", cookies[0].Name)
+			assert.Less(t, cookies[0].MaxAge, 0)
+		}
+	})
+}
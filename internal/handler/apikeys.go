@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeysPath — путь коллекции API-ключей пользователя.
+const APIKeysPath = "/api/user/keys"
+
+// allScopes — области действия ключа, созданного без явного списка scopes.
+var allScopes = []string{storage.ScopeCreate, storage.ScopeRead, storage.ScopeDelete}
+
+// CreateAPIKeyRequest — тело запроса на создание API-ключа.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes — области действия ключа; пустой список — все области.
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyInfo — описание API-ключа без самого ключа.
+type APIKeyInfo struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// CreatedAPIKey — ответ на создание ключа. Key показывается только один раз.
+type CreatedAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// APIKeyList — ответ со списком API-ключей пользователя.
+type APIKeyList struct {
+	Keys []APIKeyInfo `json:"keys"`
+}
+
+// newAPIKeyInfo собирает описание ключа key.
+func newAPIKeyInfo(key *storage.APIKey) APIKeyInfo {
+	info := APIKeyInfo{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    append([]string{}, key.Scopes...),
+		CreatedAt: key.CreatedAt.UTC(),
+	}
+	if !key.ExpiresAt.IsZero() {
+		expires := key.ExpiresAt.UTC()
+		info.ExpiresAt = &expires
+	}
+	if !key.RevokedAt.IsZero() {
+		revoked := key.RevokedAt.UTC()
+		info.RevokedAt = &revoked
+	}
+	return info
+}
+
+// normalizeScopes проверяет и упорядочивает области действия, убирая повторы.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return append([]string(nil), allScopes...), nil
+	}
+	requested := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		switch s {
+		case storage.ScopeCreate, storage.ScopeRead, storage.ScopeDelete:
+			requested[s] = true
+		default:
+			return nil, errors.New("unknown scope " + s)
+		}
+	}
+	var result []string
+	for _, s := range allScopes {
+		if requested[s] {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// CreateAPIKey возвращает Gin handler, выпускающий API-ключ пользователя.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - auditSvc: сервис audit.Service для логирования действий
+//
+// Тело запроса: {"name": "...", "scopes": ["read"], "expires_at": "2030-01-01T00:00:00Z"};
+// scopes и expires_at необязательны. Ключ передаётся в заголовке X-API-Key или
+// Authorization: Bearer и хранится только в виде хеша, поэтому возвращается один раз.
+//
+// HTTP ответы:
+//   - 201 Created — CreatedAPIKey с полем key, заголовок Location.
+//   - 400 Bad Request — некорректный JSON, неизвестный scope или expires_at в прошлом.
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 500 Internal Server Error — ошибка генерации или сохранения ключа.
+func CreateAPIKey(s storage.Storage, auditSvc *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		var req CreateAPIKeyRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
+			return
+		}
+		scopes, err := normalizeScopes(req.Scopes)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+		now := time.Now()
+		var expiresAt time.Time
+		if req.ExpiresAt != nil {
+			if !req.ExpiresAt.After(now) {
+				problem.Abort(c, problem.New(problem.InvalidRequest, "expires_at must be in the future"))
+				return
+			}
+			expiresAt = *req.ExpiresAt
+		}
+
+		id, plain, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate api key"))
+			return
+		}
+		key := storage.APIKey{
+			ID:        id,
+			UserID:    userID,
+			Name:      strings.TrimSpace(req.Name),
+			Hash:      hash,
+			Scopes:    scopes,
+			CreatedAt: now,
+			ExpiresAt: expiresAt,
+		}
+		if err := s.CreateAPIKey(c.Request.Context(), key); err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save api key"))
+			return
+		}
+
+		c.Header("Location", APIKeysPath+"/"+id)
+		c.JSON(http.StatusCreated, CreatedAPIKey{APIKeyInfo: newAPIKeyInfo(&key), Key: plain})
+
+		auditSvc.Notify(c.Request.Context(), audit.Event{
+			TS:       now.Unix(),
+			Action:   "api_key_create",
+			UserID:   userID,
+			URL:      c.Request.URL.Path,
+			APIKeyID: id,
+		})
+	}
+}
+
+// ListAPIKeys возвращает Gin handler, отдающий API-ключи пользователя (включая отозванные)
+// в порядке создания.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//
+// HTTP ответы:
+//   - 200 OK — {"keys": [...]}.
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 500 Internal Server Error — ошибка хранилища.
+func ListAPIKeys(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		keys, err := s.ListAPIKeys(c.Request.Context(), userID)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to list api keys"))
+			return
+		}
+		list := APIKeyList{Keys: []APIKeyInfo{}}
+		for i := range keys {
+			list.Keys = append(list.Keys, newAPIKeyInfo(&keys[i]))
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// RevokeAPIKey возвращает Gin handler, отзывающий API-ключ пользователя по ID.
+// Отзыв необратим; повторный отзыв ничего не меняет.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - auditSvc: сервис audit.Service для логирования действий
+//
+// HTTP ответы:
+//   - 204 No Content — ключ отозван.
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 404 Not Found — ключ не найден или принадлежит другому пользователю.
+//   - 500 Internal Server Error — ошибка хранилища.
+func RevokeAPIKey(s storage.Storage, auditSvc *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		id := c.Param("id")
+		_, err := s.RevokeAPIKey(c.Request.Context(), userID, id)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			problem.Abort(c, problem.New(problem.APIKeyNotFound, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to revoke api key"))
+			return
+		}
+		c.Status(http.StatusNoContent)
+
+		auditSvc.Notify(c.Request.Context(), audit.Event{
+			TS:       time.Now().Unix(),
+			Action:   "api_key_revoke",
+			UserID:   userID,
+			URL:      c.Request.URL.Path,
+			APIKeyID: id,
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// --- TEST /api/user/keys ---
+func TestAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	obs := &recordingObserver{}
+	auditSvc := audit.NewService(zap.NewNop(), obs)
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST(handler.APIKeysPath, handler.CreateAPIKey(store, auditSvc))
+	router.GET(handler.APIKeysPath, handler.ListAPIKeys(store))
+	router.DELETE(handler.APIKeysPath+"/:id", handler.RevokeAPIKey(store, auditSvc))
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("create returns the key once and stores its hash", func(t *testing.T) {
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		w := do(http.MethodPost, handler.APIKeysPath, `{"name":"ci","scopes":["read","create","read"],"expires_at":"`+expires.Format(time.RFC3339)+`"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var created handler.CreatedAPIKey
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.True(t, auth.IsAPIKey(created.Key))
+		assert.Contains(t, created.Key, created.ID)
+		assert.Equal(t, "ci", created.Name)
+		assert.Equal(t, []string{storage.ScopeCreate, storage.ScopeRead}, created.Scopes)
+		if assert.NotNil(t, created.ExpiresAt) {
+			assert.True(t, expires.Equal(*created.ExpiresAt))
+		}
+		assert.Nil(t, created.RevokedAt)
+		assert.Equal(t, handler.APIKeysPath+"/"+created.ID, w.Header().Get("Location"))
+
+		stored, err := store.GetAPIKey(context.Background(), auth.HashAPIKey(created.Key))
+		assert.NoError(t, err)
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, "test-user", stored.UserID)
+			assert.NotEqual(t, created.Key, stored.Hash)
+		}
+
+		assert.Eventually(t, func() bool {
+			e := obs.last()
+			return e.Action == "api_key_create" && e.APIKeyID == created.ID && e.UserID == "test-user"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("default scopes", func(t *testing.T) {
+		w := do(http.MethodPost, handler.APIKeysPath, `{}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created handler.CreatedAPIKey
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Equal(t, []string{storage.ScopeCreate, storage.ScopeRead, storage.ScopeDelete}, created.Scopes)
+		assert.Nil(t, created.ExpiresAt)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, handler.APIKeysPath, `{"scopes":["admin"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, handler.APIKeysPath, `{"expires_at":"2000-01-01T00:00:00Z"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, handler.APIKeysPath, `{`).Code)
+	})
+
+	t.Run("list and revoke", func(t *testing.T) {
+		assert.NoError(t, store.CreateAPIKey(context.Background(), storage.APIKey{ID: "foreign", UserID: "someone", Hash: "h", CreatedAt: time.Now()}))
+
+		w := do(http.MethodGet, handler.APIKeysPath, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), `"key"`, "list never exposes keys")
+		var list handler.APIKeyList
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		assert.Len(t, list.Keys, 2)
+		id := list.Keys[0].ID
+
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, handler.APIKeysPath+"/"+id, "").Code)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, handler.APIKeysPath+"/"+id, "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, handler.APIKeysPath+"/foreign", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, handler.APIKeysPath+"/missing", "").Code)
+
+		assert.NoError(t, json.Unmarshal(do(http.MethodGet, handler.APIKeysPath, "").Body.Bytes(), &list))
+		assert.NotNil(t, list.Keys[0].RevokedAt)
+		assert.Nil(t, list.Keys[1].RevokedAt)
+
+		assert.Eventually(t, func() bool {
+			e := obs.last()
+			return e.Action == "api_key_revoke" && e.APIKeyID == id
+		}, time.Second, 10*time.Millisecond)
+	})
+}
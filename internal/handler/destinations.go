@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/split"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkDestinations возвращает Gin handler со списком вариантов A/B-сплита ссылки.
+//
+// HTTP ответы:
+//   - 200 OK — JSON-массив вариантов (пустой, если сплит не настроен).
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+func GetLinkDestinations(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		rec, ok := s.Get(c.Param("id"))
+		if !ok || rec == nil || rec.UserID != userID {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+
+		list := rec.Destinations
+		if list == nil {
+			list = []storage.Destination{}
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// PutLinkDestinations возвращает Gin handler, заменяющий варианты A/B-сплита ссылки.
+//
+// Тело запроса — JSON-массив из 2–10 вариантов с положительными весами:
+//
+//	[{"name":"a","url":"https://example.com/landing-a","weight":70},
+//	 {"name":"b","url":"https://example.com/landing-b","weight":30}]
+//
+// HTTP ответы:
+//   - 200 OK — сохранённый список вариантов.
+//   - 400 Bad Request — некорректный JSON или список вариантов.
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 500 Internal Server Error — ошибка хранилища.
+func PutLinkDestinations(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		var list []storage.Destination
+		if err := c.ShouldBindJSON(&list); err != nil {
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
+			return
+		}
+
+		list, err := split.Validate(list)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+		if len(list) == 0 {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "destinations are required"))
+			return
+		}
+
+		err = s.SetDestinations(c.Request.Context(), userID, c.Param("id"), list)
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save destinations"))
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// DeleteLinkDestinations возвращает Gin handler, отключающий A/B-сплит ссылки.
+//
+// HTTP ответы:
+//   - 204 No Content — сплит отключён.
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 500 Internal Server Error — ошибка хранилища.
+func DeleteLinkDestinations(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		err := s.SetDestinations(c.Request.Context(), userID, c.Param("id"), nil)
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to delete destinations"))
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// recordingObserver запоминает события аудита.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *recordingObserver) Notify(_ context.Context, e audit.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recordingObserver) last() audit.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return audit.Event{}
+	}
+	return r.events[len(r.events)-1]
+}
+
+// --- TEST weighted A/B split ---
+func TestSplitDestinations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	obs := &recordingObserver{}
+	auditSvc := audit.NewService(zap.NewNop(), obs)
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten", handler.PostJSONURL(store, baseURL, auditSvc))
+	router.PUT("/api/user/urls/:id/destinations", handler.PutLinkDestinations(store))
+	router.GET("/api/user/urls/:id/destinations", handler.GetLinkDestinations(store))
+	router.GET("/:id", handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter()))
+
+	body, _ := json.Marshal(handler.RequestJSON{
+		URL: "https://example.com/campaign",
+		Destinations: []storage.Destination{
+			{Name: "a", URL: "https://example.com/landing-a", Weight: 70},
+			{Name: "b", URL: "https://example.com/landing-b", Weight: 30},
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created handler.ResponseJSON
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := strings.TrimPrefix(created.Result, baseURL+"/")
+
+	t.Run("assignment is sticky", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		first := w.Header().Get("Location")
+		assert.Contains(t, []string{"https://example.com/landing-a", "https://example.com/landing-b"}, first)
+
+		cookies := w.Result().Cookies()
+		assert.Len(t, cookies, 1)
+
+		for i := 0; i < 10; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+			req.AddCookie(cookies[0])
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, first, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("variant goes to audit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		req.AddCookie(&http.Cookie{Name: "ab_" + id, Value: "1"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "https://example.com/landing-b", w.Header().Get("Location"))
+		assert.Eventually(t, func() bool {
+			e := obs.last()
+			return e.Action == "follow" && e.Variant == "b"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid destinations", func(t *testing.T) {
+		data, _ := json.Marshal([]storage.Destination{{URL: "https://a.com", Weight: 1}})
+		req := httptest.NewRequest(http.MethodPut, "/api/user/urls/"+id+"/destinations", bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("replace destinations", func(t *testing.T) {
+		data, _ := json.Marshal([]storage.Destination{
+			{URL: "https://example.com/x", Weight: 1},
+			{URL: "https://example.com/y", Weight: 1},
+			{URL: "https://example.com/z", Weight: 1},
+		})
+		req := httptest.NewRequest(http.MethodPut, "/api/user/urls/"+id+"/destinations", bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/user/urls/"+id+"/destinations", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var got []storage.Destination
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Len(t, got, 3)
+	})
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// eventsHeartbeat — интервал комментариев-пингов, не дающих прокси закрыть простаивающее соединение.
+const eventsHeartbeat = 15 * time.Second
+
+// GetUserEvents возвращает Gin handler, транслирующий события ссылок пользователя
+// в виде Server-Sent Events.
+//
+// Параметры:
+//   - broker: audit.Broker, в который audit.Service публикует события
+//
+// Логика хендлера:
+//  1. Подписывается на события ссылок текущего пользователя (follow, shorten, delete).
+//  2. Отправляет каждое событие как SSE с полем event, равным действию, и JSON audit.Event в data.
+//  3. Раз в eventsHeartbeat отправляет комментарий ": ping".
+//  4. Если клиент не успевает читать и брокер отключил подписку, отправляет событие
+//     "dropped" и закрывает поток; клиент может переподключиться.
+//
+// HTTP ответы:
+//   - 200 OK — поток text/event-stream.
+//   - 401 Unauthorized — отсутствует userID.
+func GetUserEvents(broker *audit.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		sub := broker.Subscribe(userID)
+		defer broker.Unsubscribe(sub)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(eventsHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+
+			case e, ok := <-sub.Events():
+				if !ok {
+					if broker.Dropped(sub) {
+						c.SSEvent("dropped", gin.H{"error": "subscriber is too slow"})
+						_ = rc.Flush()
+					}
+					return
+				}
+				c.SSEvent(e.Action, e)
+
+			case <-ticker.C:
+				if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
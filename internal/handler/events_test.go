@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// --- TEST GET /api/user/events ---
+func TestGetUserEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	broker := audit.NewBroker(8)
+	auditSvc := audit.NewService(zap.NewNop(), broker)
+
+	router := gin.New()
+	router.Use(testUser())
+	router.GET("/api/user/events", handler.GetUserEvents(broker))
+	router.POST("/api/shorten", handler.PostJSONURL(store, "http://localhost:8080", auditSvc))
+	router.GET("/:id", handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter()))
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/user/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body, _ := json.Marshal(handler.RequestJSON{URL: "https://example.com/live"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created handler.ResponseJSON
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := strings.TrimPrefix(created.Result, "http://localhost:8080/")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	reader := bufio.NewReader(resp.Body)
+	var events []string
+	var last audit.Event
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return
+		}
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			events = append(events, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			assert.NoError(t, json.Unmarshal([]byte(data), &last))
+		}
+	}
+
+	assert.Equal(t, []string{"shorten", "follow"}, events)
+	assert.Equal(t, id, last.ShortID)
+	assert.Equal(t, "test-user", last.OwnerID)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Статусы короткой ссылки в ответах expand и lookup.
+const (
+	LinkStatusActive  = "active"
+	LinkStatusDeleted = "deleted"
+	LinkStatusExpired = "expired"
+)
+
+// ExpandResponse описывает короткую ссылку без выполнения редиректа.
+type ExpandResponse struct {
+	ID          string     `json:"id"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	Status      string     `json:"status"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// linkStatus возвращает статус ссылки: удалена, истекла (срок действия или max_clicks) или активна.
+func linkStatus(rec *storage.URLRecord) string {
+	switch {
+	case rec.Deleted:
+		return LinkStatusDeleted
+	case rec.Expired(time.Now()) || rec.MaxClicks > 0 && rec.ClicksLeft <= 0:
+		return LinkStatusExpired
+	default:
+		return LinkStatusActive
+	}
+}
+
+// newExpandResponse собирает ответ для записи rec.
+func newExpandResponse(rec *storage.URLRecord, baseURL string) ExpandResponse {
+	resp := ExpandResponse{
+		ID:          rec.ShortID,
+		ShortURL:    fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), rec.ShortID),
+		OriginalURL: rec.OriginalURL,
+		Status:      linkStatus(rec),
+	}
+	if !rec.CreatedAt.IsZero() {
+		created := rec.CreatedAt.UTC()
+		resp.CreatedAt = &created
+	}
+	return resp
+}
+
+// GetExpand возвращает Gin handler, раскрывающий короткую ссылку без редиректа.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage для поиска URL по ID
+//   - baseURL: базовый адрес коротких ссылок
+//   - limiter: ограничитель попыток ввода пароля, общий с GetIDURL и gRPC Expand
+//
+// Переход по ссылке не засчитывается: счётчик max_clicks не уменьшается, аудит не пишется.
+// Для защищённой паролем ссылки посторонний пользователь должен передать пароль
+// в заголовке X-Link-Password, владельцу пароль не нужен.
+//
+// HTTP ответы:
+//   - 200 OK — JSON с полями id, short_url, original_url, status (active, deleted, expired)
+//     и created_at (если время создания известно).
+//   - 401 Unauthorized — ссылка защищена паролем, пароль не передан или неверен.
+//   - 404 Not Found — ID не найден.
+//   - 429 Too Many Requests — превышен лимит неудачных попыток ввода пароля.
+func GetExpand(s storage.Storage, baseURL string, limiter *service.AttemptLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, ok := s.Get(c.Param("id"))
+		if !ok || rec == nil {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+
+		if userID := getUserID(c); userID == "" || rec.UserID != userID {
+			if !checkLinkPassword(c, rec, limiter) {
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, newExpandResponse(rec, baseURL))
+	}
+}
+
+// GetLookup возвращает Gin handler для обратного поиска короткой ссылки по оригинальному URL.
+// Поиск ограничен ссылками текущего пользователя.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//
+// Query-параметры:
+//   - url: оригинальный URL.
+//
+// HTTP ответы:
+//   - 200 OK — JSON того же формата, что и у GetExpand.
+//   - 400 Bad Request — параметр url не передан.
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — URL не сокращался или принадлежит другому пользователю.
+func GetLookup(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		original := c.Query("url")
+		if original == "" {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "url query parameter is required"))
+			return
+		}
+
+		id, ok := s.Lookup(c.Request.Context(), original)
+		if !ok {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		rec, ok := s.Get(id)
+		if !ok || rec == nil || rec.UserID != userID {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+
+		c.JSON(http.StatusOK, newExpandResponse(rec, baseURL))
+	}
+}
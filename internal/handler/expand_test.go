@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- TEST GET /api/expand/:id and /api/lookup ---
+func TestExpandAndLookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+
+	_, err := store.SaveRecord(ctx, storage.URLRecord{ShortID: "mine", OriginalURL: "https://example.com/mine", UserID: "test-user"})
+	assert.NoError(t, err)
+	_, err = store.SaveRecord(ctx, storage.URLRecord{ShortID: "other", OriginalURL: "https://example.com/other", UserID: "someone"})
+	assert.NoError(t, err)
+	_, err = store.SaveRecord(ctx, storage.URLRecord{ShortID: "once", OriginalURL: "https://example.com/once", UserID: "test-user", MaxClicks: 1})
+	assert.NoError(t, err)
+	_, err = store.ConsumeClick(ctx, "once")
+	assert.NoError(t, err)
+	assert.NoError(t, store.MarkDeleted("test-user", []string{"mine"}))
+
+	router := gin.New()
+	router.Use(testUser())
+	router.GET("/api/expand/:id", handler.GetExpand(store, baseURL, service.NewPasswordLimiter()))
+	router.GET("/api/lookup", handler.GetLookup(store, baseURL))
+
+	get := func(target string) (*httptest.ResponseRecorder, handler.ExpandResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp handler.ExpandResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("expand statuses", func(t *testing.T) {
+		w, resp := get("/api/expand/other")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://example.com/other", resp.OriginalURL)
+		assert.Equal(t, handler.LinkStatusActive, resp.Status)
+		assert.Equal(t, baseURL+"/other", resp.ShortURL)
+		assert.NotNil(t, resp.CreatedAt)
+
+		_, resp = get("/api/expand/mine")
+		assert.Equal(t, handler.LinkStatusDeleted, resp.Status)
+
+		_, resp = get("/api/expand/once")
+		assert.Equal(t, handler.LinkStatusExpired, resp.Status)
+
+		w, _ = get("/api/expand/missing")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("lookup own link", func(t *testing.T) {
+		w, resp := get("/api/lookup?url=" + url.QueryEscape("https://example.com/once"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "once", resp.ID)
+	})
+
+	t.Run("lookup foreign link", func(t *testing.T) {
+		w, _ := get("/api/lookup?url=" + url.QueryEscape("https://example.com/other"))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("lookup without url", func(t *testing.T) {
+		w, _ := get("/api/lookup")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushEvery — через сколько записей экспорт сбрасывает буфер ответа клиенту.
+const exportFlushEvery = 100
+
+// ExportItem — одна ссылка пользователя в экспорте.
+type ExportItem struct {
+	ID          string     `json:"id"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	Deleted     bool       `json:"deleted"`
+	// Clicks — число переходов, дошедших до сервера.
+	Clicks int `json:"clicks"`
+}
+
+// newExportItem собирает элемент экспорта из записи хранилища.
+func newExportItem(rec storage.URLRecord, baseURL string) ExportItem {
+	item := ExportItem{
+		ID:          rec.ShortID,
+		ShortURL:    baseURL + "/" + rec.ShortID,
+		OriginalURL: rec.OriginalURL,
+		Deleted:     rec.Deleted,
+		Clicks:      rec.Clicks,
+	}
+	if !rec.CreatedAt.IsZero() {
+		created := rec.CreatedAt.UTC()
+		item.CreatedAt = &created
+	}
+	return item
+}
+
+// exportWriter записывает элементы экспорта в одном из форматов.
+// Flush передаёт буферизованные данные в ResponseWriter.
+type exportWriter interface {
+	Begin() error
+	Write(item ExportItem) error
+	Flush() error
+	End() error
+}
+
+// jsonExportWriter пишет JSON-массив поэлементно, не собирая его в памяти.
+type jsonExportWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (e *jsonExportWriter) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExportWriter) Write(item ExportItem) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.enc.Encode(item)
+}
+
+func (e *jsonExportWriter) Flush() error { return nil }
+
+func (e *jsonExportWriter) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ndjsonExportWriter пишет по JSON-объекту на строку.
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Begin() error { return nil }
+
+func (e *ndjsonExportWriter) Write(item ExportItem) error { return e.enc.Encode(item) }
+
+func (e *ndjsonExportWriter) Flush() error { return nil }
+
+func (e *ndjsonExportWriter) End() error { return nil }
+
+// csvExportWriter пишет CSV со строкой заголовка.
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) Begin() error {
+	return e.w.Write([]string{"id", "short_url", "original_url", "created_at", "deleted", "clicks"})
+}
+
+func (e *csvExportWriter) Write(item ExportItem) error {
+	var created string
+	if item.CreatedAt != nil {
+		created = item.CreatedAt.Format(time.RFC3339)
+	}
+	return e.w.Write([]string{
+		item.ID, item.ShortURL, item.OriginalURL, created, strconv.FormatBool(item.Deleted), strconv.Itoa(item.Clicks),
+	})
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) End() error { return e.Flush() }
+
+// GetUserURLsExport возвращает Gin handler, выгружающий все ссылки пользователя.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//
+// Query-параметры:
+//   - format: json (по умолчанию, JSON-массив), ndjson или csv.
+//
+// Ссылки выгружаются потоком через Storage.ExportUserURLs, включая удалённые.
+// Каждая запись содержит id, short_url, original_url, created_at, deleted и clicks
+// (число переходов).
+// Ошибка хранилища после начала ответа обрывает поток (клиент получает неполный файл)
+// и добавляется в c.Errors для логирования middleware.
+//
+// HTTP ответы:
+//   - 200 OK — файл экспорта (Content-Disposition: attachment).
+//   - 400 Bad Request — неизвестный формат.
+//   - 401 Unauthorized — отсутствует userID.
+func GetUserURLsExport(s storage.Storage, baseURL string) gin.HandlerFunc {
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		format := strings.ToLower(c.DefaultQuery("format", "json"))
+		var (
+			out         exportWriter
+			contentType string
+		)
+		switch format {
+		case "json":
+			out = &jsonExportWriter{w: c.Writer, enc: json.NewEncoder(c.Writer)}
+			contentType = "application/json"
+		case "ndjson":
+			out = &ndjsonExportWriter{enc: json.NewEncoder(c.Writer)}
+			contentType = "application/x-ndjson"
+		case "csv":
+			out = &csvExportWriter{w: csv.NewWriter(c.Writer)}
+			contentType = "text/csv; charset=utf-8"
+		default:
+			problem.Abort(c, problem.New(problem.InvalidRequest, "format must be one of csv, json, ndjson"))
+			return
+		}
+
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+		c.Status(http.StatusOK)
+
+		rc := http.NewResponseController(c.Writer)
+		written := 0
+		err := out.Begin()
+		if err == nil {
+			err = s.ExportUserURLs(c.Request.Context(), userID, func(rec storage.URLRecord) error {
+				if err := out.Write(newExportItem(rec, baseURL)); err != nil {
+					return err
+				}
+				written++
+				if written%exportFlushEvery == 0 {
+					if err := out.Flush(); err != nil {
+						return err
+					}
+					_ = rc.Flush()
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			err = out.End()
+		}
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		_ = rc.Flush()
+	}
+}
@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- TEST GET /api/user/urls/export ---
+func TestGetUserURLsExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+
+	_, err := store.SaveRecord(ctx, storage.URLRecord{ShortID: "a1", OriginalURL: "https://a.com", UserID: "test-user"})
+	assert.NoError(t, err)
+	_, err = store.SaveRecord(ctx, storage.URLRecord{ShortID: "b2", OriginalURL: "https://b.com", UserID: "test-user", MaxClicks: 3})
+	assert.NoError(t, err)
+	_, err = store.SaveRecord(ctx, storage.URLRecord{ShortID: "c3", OriginalURL: "https://c.com", UserID: "someone"})
+	assert.NoError(t, err)
+	_, err = store.ConsumeClick(ctx, "b2")
+	assert.NoError(t, err)
+	assert.NoError(t, store.RecordClick(ctx, "a1"))
+	assert.NoError(t, store.MarkDeleted("test-user", []string{"a1"}))
+
+	router := gin.New()
+	router.Use(testUser())
+	router.GET("/api/user/urls/export", handler.GetUserURLsExport(store, baseURL))
+
+	get := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/urls/export?format="+format, nil))
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := get("json")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var items []handler.ExportItem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		assert.Len(t, items, 2)
+		assert.Equal(t, "a1", items[0].ID)
+		assert.True(t, items[0].Deleted)
+		assert.Equal(t, 1, items[0].Clicks, "clicks are exported for links without max_clicks")
+		assert.NotNil(t, items[0].CreatedAt)
+		assert.Equal(t, baseURL+"/b2", items[1].ShortURL)
+		assert.Equal(t, 1, items[1].Clicks)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := get("ndjson")
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := get("csv")
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, []string{"b2", baseURL + "/b2", "https://b.com"}, records[2][:3])
+		assert.Equal(t, "false", records[2][4])
+		assert.Equal(t, "1", records[2][5])
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("xml").Code)
+	})
+}
@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- TEST query passthrough, UTM and path suffix ---
+func TestForwarding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	auditSvc := newTestAuditService()
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten", handler.PostJSONURL(store, baseURL, auditSvc))
+	follow := handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter())
+	router.GET("/:id", follow)
+	router.GET("/:id/*rest", follow)
+
+	create := func(req handler.RequestJSON) (int, string) {
+		body, _ := json.Marshal(req)
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var resp handler.ResponseJSON
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, strings.TrimPrefix(resp.Result, baseURL+"/")
+	}
+
+	open := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("unknown utm key is rejected", func(t *testing.T) {
+		code, _ := create(handler.RequestJSON{
+			URL: "https://example.com/a",
+			UTM: map[string]string{"utm_foo": "x"},
+		})
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	code, id := create(handler.RequestJSON{
+		URL:        "https://example.com/docs?ref=short",
+		PassQuery:  true,
+		PathSuffix: true,
+		UTM:        map[string]string{"utm_source": "short", "utm_campaign": "{id}"},
+	})
+	assert.Equal(t, http.StatusCreated, code)
+
+	t.Run("utm and visitor query are appended", func(t *testing.T) {
+		w := open("/" + id + "?lang=en&ref=visitor")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		loc := w.Header().Get("Location")
+		assert.Contains(t, loc, "https://example.com/docs?")
+		assert.Contains(t, loc, "ref=short")
+		assert.NotContains(t, loc, "ref=visitor")
+		assert.Contains(t, loc, "lang=en")
+		assert.Contains(t, loc, "utm_source=short")
+		assert.Contains(t, loc, "utm_campaign="+id)
+	})
+
+	t.Run("path suffix is forwarded", func(t *testing.T) {
+		w := open("/" + id + "/guide/intro")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Location"), "https://example.com/docs/guide/intro?"))
+	})
+
+	_, plain := create(handler.RequestJSON{URL: "https://example.com/plain"})
+
+	t.Run("plain link ignores visitor query", func(t *testing.T) {
+		w := open("/" + plain + "?lang=en")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "https://example.com/plain", w.Header().Get("Location"))
+	})
+
+	t.Run("suffix without path_suffix is not found", func(t *testing.T) {
+		w := open("/" + plain + "/extra")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
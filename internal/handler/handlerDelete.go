@@ -3,8 +3,8 @@ package handler
 import (
 	"net/http"
 
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
-	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,29 +13,30 @@ import (
 // Этот хендлер:
 //  1. Извлекает userID из контекста (устанавливается AuthMiddleware).
 //  2. Парсит JSON-массив идентификаторов URL для удаления.
-//  3. Отправляет задачу на удаление в сервис Deleter.
+//  3. Отправляет задачу на удаление в сервис Deleter. События аудита "delete" отправляет
+//     Deleter после пометки ссылок (см. service.AuditedMarkDeleted).
 //  4. Возвращает статус 202 Accepted.
 //
 // Ответы:
 //   - 202 Accepted — задача принята в обработку.
 //   - 400 Bad Request — неверный формат JSON.
 //   - 401 Unauthorized — userID отсутствует в контексте.
-func DeleteUserURLs(s storage.Storage, d *service.Deleter) gin.HandlerFunc {
+func DeleteUserURLs(d *service.Deleter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		v, ok := c.Get("userID")
 		if !ok {
-			c.Status(http.StatusUnauthorized)
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
 			return
 		}
 		userID := v.(string)
 		if userID == "" {
-			c.Status(http.StatusUnauthorized)
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
 			return
 		}
 
 		var ids []string
 		if err := c.BindJSON(&ids); err != nil {
-			c.Status(http.StatusBadRequest)
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
 			return
 		}
 
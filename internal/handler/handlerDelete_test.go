@@ -10,7 +10,6 @@ import (
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
-	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,8 +22,6 @@ func testUserMW() gin.HandlerFunc {
 }
 
 func TestDeleteUserURLs(t *testing.T) {
-	store := storage.NewInMemoryStorage()
-
 	// канал для проверки, что markFunc вызван
 	callCh := make(chan service.DeleteTask, 1)
 
@@ -36,7 +33,7 @@ func TestDeleteUserURLs(t *testing.T) {
 
 	router := gin.Default()
 	router.Use(testUserMW())
-	router.DELETE("/api/user/urls", handler.DeleteUserURLs(store, d))
+	router.DELETE("/api/user/urls", handler.DeleteUserURLs(d))
 
 	t.Run("valid request returns 202 and enqueue called", func(t *testing.T) {
 		ids := []string{"abc123", "xyz789"}
@@ -63,7 +60,7 @@ func TestDeleteUserURLs(t *testing.T) {
 
 	t.Run("empty userID returns 401", func(t *testing.T) {
 		router2 := gin.Default()
-		router2.DELETE("/api/user/urls", handler.DeleteUserURLs(store, d))
+		router2.DELETE("/api/user/urls", handler.DeleteUserURLs(d))
 
 		ids := []string{"abc123"}
 		body, _ := json.Marshal(ids)
@@ -1,15 +1,25 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/forward"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
+// UserURL — элемент ответа GET /api/user/urls.
+type UserURL struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
+
 // GetUserURLs возвращает Gin handler, который возвращает список всех URL для текущего пользователя.
 //
 // Параметры:
@@ -30,17 +40,17 @@ func GetUserURLs(s storage.Storage, baseURL string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		v, ok := c.Get("userID")
 		if !ok {
-			c.String(http.StatusUnauthorized, "no user id")
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
 			return
 		}
 		userID := v.(string)
 		if userID == "" {
-			c.String(http.StatusUnauthorized, "invalid token")
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
 			return
 		}
 		urls, err := s.GetUserURLs(c.Request.Context(), userID)
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to list urls"))
 			return
 		}
 
@@ -49,14 +59,9 @@ func GetUserURLs(s storage.Storage, baseURL string) gin.HandlerFunc {
 			return
 		}
 
-		type RespItem struct {
-			ShortURL    string `json:"short_url"`
-			OriginalURL string `json:"original_url"`
-		}
-
-		resp := make([]RespItem, 0, len(urls))
+		resp := make([]UserURL, 0, len(urls))
 		for _, v := range urls {
-			resp = append(resp, RespItem{
+			resp = append(resp, UserURL{
 				ShortURL:    fmt.Sprintf("%s/%s", baseURL, v.ShortID),
 				OriginalURL: v.OriginalURL,
 			})
@@ -70,42 +75,109 @@ func GetUserURLs(s storage.Storage, baseURL string) gin.HandlerFunc {
 // Параметры:
 //   - s: интерфейс storage.Storage для поиска URL по ID
 //   - auditSvc: сервис audit.Service для логирования действий пользователей
+//   - limiter: ограничитель попыток ввода пароля, общий для всех точек входа по короткому ID
+//   - opts: необязательные настройки, например WithCountryResolver и WithDefaultRedirectCode
 //
 // Логика хендлера:
-//  1. Получает параметр "id" из URL.
+//  1. Получает параметр "id" из URL и необязательный суффикс пути "rest"
+//     (маршрут /:id/*rest для ссылок с path_suffix).
 //  2. Ищет запись в хранилище по ID.
-//  3. Если URL найден и не удалён — выполняет редирект на originalURL.
-//  4. Отправляет событие в сервис audit для регистрации перехода.
+//  3. Если ссылка защищена паролем — проверяет пароль из заголовка X-Link-Password
+//     или поля формы "password" (для браузера отдаётся HTML-форма, отправляемая POST-запросом
+//     на тот же адрес, поэтому хендлер регистрируется и на GET, и на POST).
+//  4. Если у ссылки задан max_clicks — атомарно списывает один переход,
+//     иначе увеличивает счётчик переходов (ошибка счётчика не мешает редиректу).
+//  5. Выбирает адрес назначения: первое подходящее правило условного редиректа
+//     (платформа, язык, страна), затем вариант A/B-сплита (закрепляется за посетителем
+//     cookie), иначе originalURL.
+//  6. Применяет настройки передачи: суффикс пути, UTM-метки и query-параметры посетителя.
+//  7. Выполняет редирект с кодом ссылки (или кодом по умолчанию); после POST формы пароля —
+//     303 See Other, чтобы браузер не повторил POST с паролем на адрес назначения. Выставляет
+//     заголовки Cache-Control и X-Robots-Tag, и отправляет событие в сервис audit для регистрации перехода;
+//     выбранный вариант сплита передаётся в поле variant.
 //
 // HTTP ответы:
-//   - 307 Temporary Redirect — успешный редирект.
-//   - 404 Not Found — ID не найден.
-//   - 410 Gone — URL помечен как удалён.
-func GetIDURL(s storage.Storage, auditSvc *audit.Service) gin.HandlerFunc {
+//   - 301, 302, 307 или 308 — успешный редирект (по умолчанию 307 Temporary Redirect).
+//   - 303 See Other — успешный редирект после POST формы пароля.
+//   - 401 Unauthorized — ссылка защищена паролем, пароль не передан или неверен.
+//   - 404 Not Found — ID не найден или передан суффикс пути для ссылки без path_suffix.
+//   - 410 Gone — URL помечен как удалён, истёк срок действия или переходы по ссылке исчерпаны.
+//   - 429 Too Many Requests — превышен лимит неудачных попыток ввода пароля.
+//   - 500 Internal Server Error — ошибка списания перехода в хранилище.
+func GetIDURL(s storage.Storage, auditSvc *audit.Service, limiter *service.AttemptLimiter, opts ...RedirectOption) gin.HandlerFunc {
+	cfg := &redirectConfig{defaultCode: http.StatusTemporaryRedirect}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
 		rec, ok := s.Get(id)
 		if !ok || rec == nil {
-			c.String(http.StatusNotFound, "id not found")
+			problem.Abort(c, problem.New(problem.NotFound, ""))
 			return
 		}
 
-		if rec.Deleted {
-			c.Status(http.StatusGone)
+		suffix := c.Param("rest")
+		if suffix != "" && suffix != "/" && !rec.Forwarding.PathSuffix {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
 			return
 		}
 
-		c.Header("Location", rec.OriginalURL)
-		c.Redirect(http.StatusTemporaryRedirect, rec.OriginalURL)
+		if rec.Deleted || rec.Expired(time.Now()) || (rec.MaxClicks > 0 && rec.ClicksLeft <= 0) {
+			problem.Abort(c, problem.New(problem.Gone, ""))
+			return
+		}
+
+		if !checkLinkPassword(c, rec, limiter) {
+			return
+		}
+
+		if rec.MaxClicks > 0 {
+			_, err := s.ConsumeClick(c.Request.Context(), id)
+			if errors.Is(err, storage.ErrLinkExhausted) {
+				problem.Abort(c, problem.New(problem.Gone, ""))
+				return
+			}
+			if err != nil {
+				problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+				return
+			}
+		} else {
+			_ = s.RecordClick(c.Request.Context(), id)
+		}
+
+		dest, variant := resolveDestination(c, rec, cfg)
+		dest, err := forward.Build(dest, rec.Forwarding, forward.Request{
+			ID:      id,
+			Variant: variant,
+			Query:   c.Request.URL.Query(),
+			Suffix:  suffix,
+		})
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+			return
+		}
+
+		code := redirectCode(rec, cfg)
+		if c.Request.Method == http.MethodPost {
+			// 307/308 сохраняют метод и тело: тело формы с паролем ушло бы на адрес назначения.
+			code = http.StatusSeeOther
+		}
+		setRedirectHeaders(c, rec, code)
+		c.Redirect(code, dest)
 
 		auditSvc.Notify(
 			c.Request.Context(),
 			audit.Event{
-				TS:     time.Now().Unix(),
-				Action: "follow",
-				UserID: getUserID(c),
-				URL:    rec.OriginalURL,
+				TS:      time.Now().Unix(),
+				Action:  "follow",
+				UserID:  getUserID(c),
+				OwnerID: rec.UserID,
+				ShortID: id,
+				URL:     dest,
+				Variant: variant,
 			})
 	}
 }
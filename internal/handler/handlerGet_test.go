@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -91,7 +93,7 @@ func TestGetIDURL(t *testing.T) {
 	assert.NoError(t, err)
 
 	router := gin.New()
-	router.GET("/:id", handler.GetIDURL(store, auditSvc))
+	router.GET("/:id", handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter()))
 
 	tests := []struct {
 		name           string
@@ -147,3 +149,82 @@ func TestGetIDURL(t *testing.T) {
 		})
 	}
 }
+
+func TestGetIDURL_MaxClicks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	_, err := store.SaveRecord(context.Background(), storage.URLRecord{
+		ShortID:     "once123",
+		OriginalURL: "https://onboarding.example.com/",
+		UserID:      "user1",
+		MaxClicks:   1,
+	})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/:id", handler.GetIDURL(store, newTestAuditService(), service.NewPasswordLimiter()))
+
+	req := httptest.NewRequest(http.MethodGet, "/once123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/once123", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+// --- TEST per-link redirect code and caching headers ---
+func TestGetIDURL_RedirectCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+	for _, rec := range []storage.URLRecord{
+		{ShortID: "perm", OriginalURL: "https://example.com/seo", RedirectCode: http.StatusMovedPermanently},
+		{ShortID: "deflt", OriginalURL: "https://example.com/default"},
+		{ShortID: "track", OriginalURL: "https://example.com/track", RedirectCode: http.StatusFound},
+		{ShortID: "limited", OriginalURL: "https://example.com/limited", RedirectCode: http.StatusPermanentRedirect, MaxClicks: 5},
+	} {
+		_, err := store.SaveRecord(ctx, rec)
+		assert.NoError(t, err)
+	}
+
+	router := gin.New()
+	router.GET("/:id", handler.GetIDURL(store, newTestAuditService(), service.NewPasswordLimiter(),
+		handler.WithDefaultRedirectCode(http.StatusPermanentRedirect)))
+
+	tests := []struct {
+		id           string
+		wantCode     int
+		wantCache    string
+		wantRobotTag string
+	}{
+		{id: "perm", wantCode: http.StatusMovedPermanently, wantCache: "public, max-age=86400"},
+		{id: "deflt", wantCode: http.StatusPermanentRedirect, wantCache: "public, max-age=86400"},
+		{id: "track", wantCode: http.StatusFound, wantCache: "private, no-store", wantRobotTag: "noindex"},
+		{id: "limited", wantCode: http.StatusPermanentRedirect, wantCache: "private, no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.id, nil))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantCache, w.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.wantRobotTag, w.Header().Get("X-Robots-Tag"))
+		})
+	}
+
+	t.Run("invalid code on create", func(t *testing.T) {
+		r := gin.New()
+		r.Use(testUser())
+		r.POST("/api/shorten", handler.PostJSONURL(store, "http://localhost:8080", newTestAuditService()))
+
+		body, _ := json.Marshal(handler.RequestJSON{URL: "https://example.com/x", RedirectCode: 303})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
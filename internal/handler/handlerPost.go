@@ -10,17 +10,34 @@ import (
 	"time"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/forward"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/shortener"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/split"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
 type RequestJSON struct {
-	URL string `json:"url"`
+	URL          string                `json:"url"`
+	Password     string                `json:"password,omitempty"`
+	MaxClicks    int                   `json:"max_clicks,omitempty"`
+	Destinations []storage.Destination `json:"destinations,omitempty"`
+	PassQuery    bool                  `json:"pass_query,omitempty"`
+	PathSuffix   bool                  `json:"path_suffix,omitempty"`
+	UTM          map[string]string     `json:"utm,omitempty"`
+	RedirectCode int                   `json:"redirect_code,omitempty"`
+}
+
+// hasLinkOptions сообщает, что в запросе заданы настройки ссылки помимо самого URL.
+func (r RequestJSON) hasLinkOptions() bool {
+	return r.Password != "" || r.MaxClicks > 0 || len(r.Destinations) > 0 ||
+		r.PassQuery || r.PathSuffix || len(r.UTM) > 0 || r.RedirectCode != 0
 }
 
 type ResponseJSON struct {
 	Result string `json:"result"`
+	QR     string `json:"qr,omitempty"`
 }
 
 type BatchRequestItem struct {
@@ -30,7 +47,47 @@ type BatchRequestItem struct {
 
 type BatchResponseItem struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	QR            string `json:"qr,omitempty"`
+}
+
+// Статусы элементов пакетного сокращения.
+const (
+	// BatchStatusCreated — создана новая короткая ссылка.
+	BatchStatusCreated = "created"
+	// BatchStatusExisting — URL уже был сокращён, возвращена существующая ссылка.
+	BatchStatusExisting = "existing"
+	// BatchStatusInvalid — элемент отклонён, причина в поле error.
+	BatchStatusInvalid = "invalid"
+)
+
+// validateOriginalURL проверяет URL элемента пакета и возвращает текст ошибки
+// или пустую строку, если URL корректен. Как и остальные эндпоинты создания,
+// пакет отклоняет только пустой URL.
+func validateOriginalURL(raw string) string {
+	if strings.TrimSpace(raw) == "" {
+		return "original_url is empty"
+	}
+	return ""
+}
+
+// batchItemResult определяет итог сохранения элемента пакета по картам, возвращённым SaveBatch.
+// Элемент считается созданным, только если в хранилище записан сгенерированный для него id;
+// иначе возвращается идентификатор уже существующей ссылки (в том числе созданной
+// предыдущим элементом того же пакета).
+func batchItemResult(id, original string, created, existing map[string]string) (string, string) {
+	if saved, ok := created[original]; ok {
+		if saved == id {
+			return id, BatchStatusCreated
+		}
+		return saved, BatchStatusExisting
+	}
+	if saved, ok := existing[original]; ok {
+		return saved, BatchStatusExisting
+	}
+	return "", BatchStatusInvalid
 }
 
 // PostBatchURL возвращает Gin handler для массового сокращения URL.
@@ -42,13 +99,17 @@ type BatchResponseItem struct {
 // Логика хендлера:
 //  1. Проверяет наличие userID в контексте.
 //  2. Декодирует JSON-массив BatchRequestItem.
-//  3. Генерирует короткие ID для каждого URL.
+//  3. Проверяет URL и генерирует короткие ID для корректных элементов.
 //  4. Сохраняет batch в хранилище.
-//  5. Возвращает JSON-массив BatchResponseItem с короткими ссылками.
+//  5. Возвращает JSON-массив BatchResponseItem в порядке запроса. Каждый элемент содержит
+//     status: created (новая ссылка), existing (URL уже сокращён, short_url указывает
+//     на существующую ссылку) или invalid (short_url пуст, причина в поле error).
+//     Если передан query-параметр qr=png|svg, элементы со ссылкой содержат QR-код в виде data URI.
 //
 // HTTP ответы:
-//   - 201 Created — успешно сохранён batch.
-//   - 400 Bad Request — пустой массив или некорректный JSON.
+//   - 201 Created — все элементы созданы.
+//   - 207 Multi-Status — часть элементов существовала или отклонена; итог в поле status.
+//   - 400 Bad Request — пустой массив, некорректный JSON или параметры QR-кода.
 //   - 401 Unauthorized — отсутствует userID.
 //   - 500 Internal Server Error — ошибка генерации ID или сохранения batch.
 func PostBatchURL(s storage.Storage, baseURL string) gin.HandlerFunc {
@@ -58,55 +119,87 @@ func PostBatchURL(s storage.Storage, baseURL string) gin.HandlerFunc {
 
 		u, ok := c.Get("userID")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing userID"})
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
 			return
 		}
 		userID := u.(string)
 
 		var req []BatchRequestItem
 		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
 			return
 		}
 
 		if len(req) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "empty body"})
+			problem.Abort(c, problem.New(problem.InvalidRequest, "empty body"))
 			return
 		}
 
-		batch := make([]storage.BatchItem, 0, len(req))
-		resp := make([]BatchResponseItem, 0, len(req))
+		withQR := c.Query("qr") != ""
+		qrOpts, err := parseQROptions(c, "qr")
+		if withQR && err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
 
-		for _, item := range req {
-			if strings.TrimSpace(item.OriginalURL) == "" {
+		batch := make([]storage.BatchItem, 0, len(req))
+		ids := make([]string, len(req))
+		resp := make([]BatchResponseItem, len(req))
+
+		for i, item := range req {
+			resp[i].CorrelationID = item.CorrelationID
+			if msg := validateOriginalURL(item.OriginalURL); msg != "" {
+				resp[i].Status = BatchStatusInvalid
+				resp[i].Error = msg
 				continue
 			}
 
 			id, err := shortener.GenerateID()
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate short id"})
+				problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate short id"))
 				return
 			}
-
+			ids[i] = id
 			batch = append(batch, storage.BatchItem{
 				ShortID:     id,
 				OriginalURL: item.OriginalURL,
 			})
-
-			shortURL := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), id)
-
-			resp = append(resp, BatchResponseItem{
-				CorrelationID: item.CorrelationID,
-				ShortURL:      shortURL,
-			})
 		}
 
-		if _, _, err := s.SaveBatch(ctx, userID, batch); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save batch"})
+		created, existing, err := s.SaveBatch(ctx, userID, batch)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save batch"))
 			return
 		}
 
-		c.JSON(http.StatusCreated, resp)
+		status := http.StatusCreated
+		for i, item := range req {
+			if resp[i].Status == BatchStatusInvalid {
+				status = http.StatusMultiStatus
+				continue
+			}
+
+			id, itemStatus := batchItemResult(ids[i], item.OriginalURL, created, existing)
+			resp[i].Status = itemStatus
+			if itemStatus == BatchStatusInvalid {
+				resp[i].Error = "url was not saved"
+				status = http.StatusMultiStatus
+				continue
+			}
+			if itemStatus != BatchStatusCreated {
+				status = http.StatusMultiStatus
+			}
+
+			resp[i].ShortURL = fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), id)
+			if withQR {
+				if resp[i].QR, err = qrDataURI(resp[i].ShortURL, qrOpts); err != nil {
+					problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate qr code"))
+					return
+				}
+			}
+		}
+
+		c.JSON(status, resp)
 	}
 }
 
@@ -118,16 +211,30 @@ func PostBatchURL(s storage.Storage, baseURL string) gin.HandlerFunc {
 //   - auditSvc: сервис audit.Service для логирования действий
 //
 // Логика хендлера:
-//  1. Декодирует JSON с полем "url".
+//  1. Декодирует JSON с полем "url" и необязательными полями "password", "max_clicks",
+//     "destinations" (взвешенные варианты A/B-сплита), "pass_query" (передавать query-параметры
+//     посетителя), "path_suffix" (перенаправлять /{id}/path на destination/path)
+//     "utm" (UTM-метки с шаблонами {id} и {variant}) и "redirect_code" (301, 302, 307 или 308).
 //  2. Генерирует короткий ID.
-//  3. Сохраняет URL в хранилище.
+//  3. Сохраняет URL в хранилище; если задан пароль, сохраняется его bcrypt-хеш,
+//     и переход по ссылке будет требовать пароль. Если задан max_clicks,
+//     ссылка перестаёт работать после указанного числа переходов.
+//     Повтор уже сокращённого URL без настроек отвечает так же, как раньше: зависит от
+//     хранилища (в памяти и в файле — 201 с существующей ссылкой, в БД — 409). Если для уже
+//     сокращённого URL переданы настройки, запрос отклоняется: настройки существующей
+//     ссылки не меняются.
 //  4. Возвращает JSON с полем "result" — короткая ссылка.
+//     Если передан query-параметр qr=png|svg, поле "qr" содержит QR-код в виде data URI.
 //  5. Отправляет событие в audit сервис.
 //
 // HTTP ответы:
-//   - 201 Created — успешное создание новой короткой ссылки.
-//   - 409 Conflict — URL уже существует, возвращается существующая короткая ссылка.
-//   - 400 Bad Request — пустой или некорректный JSON.
+//   - 201 Created — успешное создание новой короткой ссылки (или повтор URL без настроек
+//     в хранилище в памяти или в файле).
+//   - 409 Conflict — URL уже существует, возвращается существующая короткая ссылка;
+//     если были переданы настройки ссылки — problem+json link-options-not-applied.
+//   - 400 Bad Request — пустой или некорректный JSON, некорректные параметры QR-кода,
+//     слишком длинный пароль, отрицательный max_clicks, некорректные варианты сплита
+//     неизвестные UTM-параметры или недопустимый redirect_code.
 //   - 500 Internal Server Error — ошибка генерации ID или сохранения URL.
 func PostJSONURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -136,45 +243,109 @@ func PostJSONURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin
 
 		var req RequestJSON
 		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
 			return
 		}
 		if strings.TrimSpace(req.URL) == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+			problem.Abort(c, problem.New(problem.InvalidRequest, "url is required"))
 			return
 		}
 		originalURL := strings.TrimSpace(req.URL)
+		if req.MaxClicks < 0 {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "max_clicks must not be negative"))
+			return
+		}
+		destinations, err := split.Validate(req.Destinations)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+		if req.RedirectCode != 0 && !ValidRedirectCode(req.RedirectCode) {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "redirect_code must be one of 301, 302, 307, 308"))
+			return
+		}
+		if err := forward.ValidateUTM(req.UTM); err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+
+		withQR := c.Query("qr") != ""
+		qrOpts, err := parseQROptions(c, "qr")
+		if withQR && err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+
+		passwordHash, err := hashLinkPassword(req.Password)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "invalid password"))
+			return
+		}
 
 		u, _ := c.Get("userID")
 		userID := u.(string)
 
 		id, err := shortener.GenerateID()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate id"})
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate id"))
 			return
 		}
 
-		shortID, err := s.Save(ctx, userID, id, req.URL)
+		var shortID string
+		if req.hasLinkOptions() {
+			shortID, err = s.SaveRecord(ctx, storage.URLRecord{
+				ShortID:      id,
+				OriginalURL:  req.URL,
+				UserID:       userID,
+				PasswordHash: passwordHash,
+				MaxClicks:    req.MaxClicks,
+				Destinations: destinations,
+				Forwarding: storage.Forwarding{
+					PassQuery:  req.PassQuery,
+					PathSuffix: req.PathSuffix,
+					UTM:        req.UTM,
+				},
+				RedirectCode: req.RedirectCode,
+			})
+			if errors.Is(err, storage.ErrURLExists) {
+				problem.Abort(c, problem.New(problem.OptionsNotApplied,
+					fmt.Sprintf("existing link %s/%s keeps its settings", strings.TrimRight(baseURL, "/"), shortID)))
+				return
+			}
+		} else {
+			// Без настроек — как раньше: повтор URL отвечает в зависимости от хранилища.
+			shortID, err = s.Save(ctx, userID, id, req.URL)
+		}
 
+		status := http.StatusCreated
 		if errors.Is(err, storage.ErrURLExists) {
-			shortURL := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), shortID)
-			c.JSON(http.StatusConflict, ResponseJSON{Result: shortURL})
-			return
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			status = http.StatusConflict
+		} else if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save url"))
 			return
 		}
 
 		shortURL := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), shortID)
-		c.JSON(http.StatusCreated, ResponseJSON{Result: shortURL})
+		resp := ResponseJSON{Result: shortURL}
+		if withQR {
+			if resp.QR, err = qrDataURI(shortURL, qrOpts); err != nil {
+				problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate qr code"))
+				return
+			}
+		}
+		c.JSON(status, resp)
+		if status == http.StatusConflict {
+			return
+		}
 
 		auditSvc.Notify(
 			c.Request.Context(),
 			audit.Event{TS: time.Now().Unix(),
-				Action: "shorten",
-				UserID: getUserID(c),
-				URL:    originalURL})
+				Action:  "shorten",
+				UserID:  getUserID(c),
+				OwnerID: getUserID(c),
+				ShortID: shortID,
+				URL:     originalURL})
 	}
 }
 
@@ -193,6 +364,8 @@ func PostJSONURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin
 //  5. Возвращает короткую ссылку как plain text.
 //  6. Отправляет событие в audit сервис.
 //
+// Ошибки тоже отдаются как plain text (problem.UseText), без подробностей хранилища.
+//
 // HTTP ответы:
 //   - 201 Created — успешно создана короткая ссылка.
 //   - 409 Conflict — URL уже существует, возвращается существующая короткая ссылка.
@@ -200,14 +373,16 @@ func PostJSONURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin
 //   - 500 Internal Server Error — ошибка генерации ID или сохранения URL.
 func PostRawURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		problem.UseText(c)
+
 		if c.GetHeader("Content-Type") != "text/plain" {
-			c.String(http.StatusBadRequest, "invalid content type")
+			problem.Abort(c, problem.New(problem.InvalidRequest, "invalid content type"))
 			return
 		}
 
 		body, err := c.GetRawData()
 		if err != nil || len(body) == 0 {
-			c.String(http.StatusBadRequest, "empty body")
+			problem.Abort(c, problem.New(problem.InvalidRequest, "empty body"))
 			return
 		}
 
@@ -221,7 +396,7 @@ func PostRawURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.
 
 		id, err := shortener.GenerateID()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate id"})
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate id"))
 			return
 		}
 
@@ -233,7 +408,7 @@ func PostRawURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.
 			return
 		}
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save url"))
 			return
 		}
 
@@ -243,9 +418,11 @@ func PostRawURL(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.
 		auditSvc.Notify(
 			c.Request.Context(),
 			audit.Event{
-				TS:     time.Now().Unix(),
-				Action: "shorten",
-				UserID: getUserID(c),
-				URL:    originalURL})
+				TS:      time.Now().Unix(),
+				Action:  "shorten",
+				UserID:  userID,
+				OwnerID: userID,
+				ShortID: shortID,
+				URL:     originalURL})
 	}
 }
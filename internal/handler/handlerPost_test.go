@@ -11,6 +11,8 @@ import (
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -89,6 +91,51 @@ func TestPostBatchURL(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, result, 2)
 		assert.True(t, strings.HasPrefix(result[0].ShortURL, baseURL))
+		assert.Equal(t, handler.BatchStatusCreated, result[0].Status)
+	})
+
+	t.Run("partial batch", func(t *testing.T) {
+		id, err := store.Save(context.Background(), "test-user", "known1", "https://known.com")
+		assert.NoError(t, err)
+
+		batch := []handler.BatchRequestItem{
+			{CorrelationID: "new", OriginalURL: "https://c.com"},
+			{CorrelationID: "known", OriginalURL: "https://known.com"},
+			{CorrelationID: "dup", OriginalURL: "https://c.com"},
+			{CorrelationID: "relative", OriginalURL: "not-a-url"},
+			{CorrelationID: "empty", OriginalURL: " "},
+		}
+
+		body, _ := json.Marshal(batch)
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		var result []handler.BatchResponseItem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Len(t, result, 5)
+
+		assert.Equal(t, "new", result[0].CorrelationID)
+		assert.Equal(t, handler.BatchStatusCreated, result[0].Status)
+
+		assert.Equal(t, handler.BatchStatusExisting, result[1].Status)
+		assert.Equal(t, baseURL+"/"+id, result[1].ShortURL)
+
+		assert.Equal(t, handler.BatchStatusExisting, result[2].Status)
+		assert.Equal(t, result[0].ShortURL, result[2].ShortURL)
+
+		assert.Equal(t, handler.BatchStatusCreated, result[3].Status, "batch accepts the same URLs as the other create endpoints")
+
+		assert.Equal(t, "empty", result[4].CorrelationID)
+		assert.Equal(t, handler.BatchStatusInvalid, result[4].Status)
+		assert.Empty(t, result[4].ShortURL)
+		assert.NotEmpty(t, result[4].Error)
+
+		rec, ok := store.Get(strings.TrimPrefix(result[0].ShortURL, baseURL+"/"))
+		assert.True(t, ok)
+		assert.Equal(t, "https://c.com", rec.OriginalURL)
 	})
 }
 
@@ -143,7 +190,7 @@ func TestPostJSONURL(t *testing.T) {
 
 	baseURL := "http://localhost:8080"
 	router := gin.New()
-	router.Use(testUser())
+	router.Use(middleware.Problems(zap.NewNop()), testUser())
 
 	store := storage.NewInMemoryStorage()
 	auditSvc := newTestAuditService()
@@ -182,4 +229,37 @@ func TestPostJSONURL(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
+	shorten := func(body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("duplicate without options", func(t *testing.T) {
+		first := shorten(handler.RequestJSON{URL: "https://example.com/dup"})
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		again := shorten(handler.RequestJSON{URL: "https://example.com/dup"})
+		assert.Equal(t, http.StatusCreated, again.Code, "memory storage keeps the v1 duplicate status")
+		assert.JSONEq(t, first.Body.String(), again.Body.String())
+	})
+
+	t.Run("duplicate with options is rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, shorten(handler.RequestJSON{URL: "https://example.com/open"}).Code)
+
+		w := shorten(handler.RequestJSON{URL: "https://example.com/open", Password: "s3cret", MaxClicks: 3})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var body problem.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, problem.OptionsNotApplied.URI, body.Type)
+
+		id, ok := store.Lookup(context.Background(), "https://example.com/open")
+		assert.True(t, ok)
+		rec, _ := store.Get(id)
+		assert.Empty(t, rec.PasswordHash, "existing link keeps its settings")
+		assert.Zero(t, rec.MaxClicks)
+	})
 }
@@ -0,0 +1,440 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/exports"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/shortener"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// importChunkSize — число строк импорта, сохраняемых одним вызовом SaveBatch.
+	importChunkSize = 500
+	// importChunkTimeout — таймаут сохранения одного чанка; на весь импорт таймаут не действует.
+	importChunkTimeout = 3 * time.Second
+	// importMaxLine — максимальная длина строки NDJSON.
+	importMaxLine = 1 << 20
+)
+
+// Статусы строк импорта в дополнение к статусам пакетного сокращения.
+const (
+	// ImportStatusFailed — элемент не сохранён из-за ошибки хранилища; импорт на этом прерывается.
+	ImportStatusFailed = "failed"
+	// ImportStatusConflict — короткий идентификатор из выгрузки уже занят другой ссылкой.
+	ImportStatusConflict = "conflict"
+)
+
+// ImportResultItem — результат обработки одной строки импорта.
+type ImportResultItem struct {
+	Line          int    `json:"line"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	OriginalURL   string `json:"original_url,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// importRow — строка входного потока импорта.
+type importRow struct {
+	line int
+	item BatchRequestItem
+	err  string
+}
+
+// importReader последовательно читает строки импорта; в конце потока возвращает io.EOF.
+type importReader interface {
+	Next() (importRow, error)
+}
+
+// ndjsonImportReader читает объекты BatchRequestItem, по одному на строку. Пустые строки пропускаются.
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONImportReader(r io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLine)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (r *ndjsonImportReader) Next() (importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := importRow{line: r.line}
+		if err := json.Unmarshal([]byte(text), &row.item); err != nil {
+			row.err = "invalid JSON"
+		}
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return importRow{}, err
+	}
+	return importRow{}, io.EOF
+}
+
+// csvImportReader читает CSV с URL в первой колонке и необязательным correlation_id во второй.
+// Если первая строка — заголовок с колонкой original_url (или url), колонки берутся по заголовку.
+type csvImportReader struct {
+	reader     *csv.Reader
+	urlCol     int
+	corrCol    int
+	headerRead bool
+}
+
+func newCSVImportReader(r io.Reader) *csvImportReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	return &csvImportReader{reader: reader, urlCol: 0, corrCol: 1}
+}
+
+func (r *csvImportReader) Next() (importRow, error) {
+	for {
+		record, err := r.reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return importRow{line: parseErr.Line, err: "invalid CSV: " + parseErr.Err.Error()}, nil
+		}
+		if err != nil {
+			return importRow{}, err
+		}
+		line, _ := r.reader.FieldPos(0)
+
+		if !r.headerRead {
+			r.headerRead = true
+			if r.parseHeader(record) {
+				continue
+			}
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row := importRow{line: line}
+		if r.urlCol < len(record) {
+			row.item.OriginalURL = strings.TrimSpace(record[r.urlCol])
+		}
+		if r.corrCol >= 0 && r.corrCol < len(record) {
+			row.item.CorrelationID = strings.TrimSpace(record[r.corrCol])
+		}
+		return row, nil
+	}
+}
+
+// parseHeader распознаёт строку заголовка и запоминает номера колонок.
+func (r *csvImportReader) parseHeader(record []string) bool {
+	urlCol, corrCol := -1, -1
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "original_url", "url":
+			urlCol = i
+		case "correlation_id":
+			corrCol = i
+		}
+	}
+	if urlCol < 0 {
+		return false
+	}
+	r.urlCol, r.corrCol = urlCol, corrCol
+	return true
+}
+
+// newImportReader выбирает формат по query-параметру format (ndjson, csv) или Content-Type.
+// По умолчанию используется NDJSON.
+func newImportReader(c *gin.Context) (importReader, error) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		switch mediaType {
+		case "text/csv", "application/csv":
+			format = "csv"
+		default:
+			format = "ndjson"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return newCSVImportReader(c.Request.Body), nil
+	case "ndjson", "jsonl":
+		return newNDJSONImportReader(c.Request.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// PostImportURL возвращает Gin handler для потокового массового импорта URL.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage для сохранения URL
+//   - baseURL: базовый адрес для формирования коротких ссылок
+//
+// Формат тела определяется query-параметром format или заголовком Content-Type:
+//   - NDJSON (application/x-ndjson, по умолчанию) — по объекту {"correlation_id","original_url"} на строку.
+//   - CSV (text/csv) — URL в первой колонке, correlation_id во второй; допускается
+//     строка заголовка с колонками original_url и correlation_id.
+//
+// С query-параметром mode=aliases тело читается как CSV-выгрузка стороннего сокращателя
+// (см. importAliases): короткие идентификаторы из выгрузки сохраняются как есть.
+//
+// Логика хендлера:
+//  1. Читает тело потоком, не загружая его в память целиком.
+//  2. Накапливает до importChunkSize строк и сохраняет их одним вызовом SaveBatch
+//     с отдельным таймаутом на каждый чанк.
+//  3. После каждого чанка отправляет клиенту результаты его строк в формате NDJSON
+//     (ImportResultItem со статусом created, existing, invalid или failed).
+//
+// Ошибка хранилища прерывает импорт: строки текущего чанка получают статус failed,
+// ранее сохранённые чанки остаются в хранилище.
+//
+// HTTP ответы:
+//   - 200 OK — поток результатов application/x-ndjson.
+//   - 400 Bad Request — неподдерживаемый формат, режим или нераспознанный заголовок выгрузки.
+//   - 401 Unauthorized — отсутствует userID.
+func PostImportURL(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		switch c.Query("mode") {
+		case "":
+		case "aliases":
+			importAliases(c, s, baseURL, userID)
+			return
+		default:
+			problem.Abort(c, problem.New(problem.InvalidRequest, "mode must be aliases or omitted"))
+			return
+		}
+
+		reader, err := newImportReader(c)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+
+		rc := http.NewResponseController(c.Writer)
+		// Результаты отправляются до того, как тело прочитано до конца.
+		_ = rc.EnableFullDuplex()
+
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		enc := json.NewEncoder(c.Writer)
+
+		imp := &importer{s: s, userID: userID, baseURL: strings.TrimRight(baseURL, "/")}
+		rows := make([]importRow, 0, importChunkSize)
+
+		flush := func() bool {
+			results, ok := imp.saveChunk(c.Request.Context(), rows)
+			for _, r := range results {
+				if err := enc.Encode(r); err != nil {
+					return false
+				}
+			}
+			_ = rc.Flush()
+			rows = rows[:0]
+			return ok
+		}
+
+		for {
+			row, err := reader.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				_ = enc.Encode(ImportResultItem{Status: ImportStatusFailed, Error: "failed to read request body"})
+				_ = rc.Flush()
+				return
+			}
+			rows = append(rows, row)
+			if len(rows) == importChunkSize && !flush() {
+				return
+			}
+		}
+		if len(rows) > 0 {
+			flush()
+		}
+	}
+}
+
+// importer сохраняет чанки импорта одного пользователя.
+type importer struct {
+	s       storage.Storage
+	userID  string
+	baseURL string
+}
+
+// saveChunk сохраняет корректные строки чанка и возвращает результаты всех строк в исходном порядке.
+// Второе значение равно false, если сохранение не удалось и импорт нужно прервать.
+func (imp *importer) saveChunk(ctx context.Context, rows []importRow) ([]ImportResultItem, bool) {
+	results := make([]ImportResultItem, len(rows))
+	ids := make([]string, len(rows))
+	batch := make([]storage.BatchItem, 0, len(rows))
+
+	for i, row := range rows {
+		results[i] = ImportResultItem{
+			Line:          row.line,
+			CorrelationID: row.item.CorrelationID,
+			OriginalURL:   row.item.OriginalURL,
+		}
+		msg := row.err
+		if msg == "" {
+			msg = validateOriginalURL(row.item.OriginalURL)
+		}
+		if msg != "" {
+			results[i].Status = BatchStatusInvalid
+			results[i].Error = msg
+			continue
+		}
+
+		id, err := shortener.GenerateID()
+		if err != nil {
+			results[i].Status = ImportStatusFailed
+			results[i].Error = "failed to generate short id"
+			continue
+		}
+		ids[i] = id
+		batch = append(batch, storage.BatchItem{ShortID: id, OriginalURL: row.item.OriginalURL})
+	}
+
+	if len(batch) == 0 {
+		return results, true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importChunkTimeout)
+	defer cancel()
+
+	created, existing, err := imp.s.SaveBatch(ctx, imp.userID, batch)
+	for i, row := range rows {
+		if ids[i] == "" {
+			continue
+		}
+		if err != nil {
+			results[i].Status = ImportStatusFailed
+			results[i].Error = "failed to save batch"
+			continue
+		}
+		id, status := batchItemResult(ids[i], row.item.OriginalURL, created, existing)
+		results[i].Status = status
+		if status == BatchStatusInvalid {
+			results[i].Error = "url was not saved"
+			continue
+		}
+		results[i].ShortURL = imp.baseURL + "/" + id
+	}
+	return results, err == nil
+}
+
+// importAliases импортирует CSV-выгрузку стороннего сокращателя, сохраняя короткие
+// идентификаторы из неё как пользовательские алиасы.
+//
+// Формат колонок определяется по заголовку или задаётся query-параметром layout
+// (bitly, rebrandly, shortio, yourls, tinyurl, generic). Каждая ссылка сохраняется
+// через Storage.SaveRecord под текущим пользователем с временем создания из выгрузки.
+// Результат по каждой строке отправляется в формате NDJSON, correlation_id содержит
+// идентификатор из выгрузки:
+//   - created — ссылка сохранена с исходным идентификатором;
+//   - existing — оригинальный URL уже сокращён, short_url указывает на существующую ссылку;
+//   - conflict — идентификатор занят другой ссылкой;
+//   - invalid — строку не удалось разобрать;
+//   - failed — ошибка хранилища, импорт прерван.
+func importAliases(c *gin.Context, s storage.Storage, baseURL, userID string) {
+	reader, err := exports.NewReader(c.Request.Body, c.Query("layout"))
+	if err != nil {
+		problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+		return
+	}
+
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.EnableFullDuplex()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	for n := 1; ; n++ {
+		link, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = enc.Encode(ImportResultItem{Status: ImportStatusFailed, Error: "failed to read request body"})
+			break
+		}
+
+		res, ok := saveAlias(c.Request.Context(), s, userID, baseURL, link)
+		if err := enc.Encode(res); err != nil || !ok {
+			break
+		}
+		if n%importChunkSize == 0 {
+			_ = rc.Flush()
+		}
+	}
+	_ = rc.Flush()
+}
+
+// saveAlias сохраняет одну ссылку из выгрузки. Второе значение равно false,
+// если произошла ошибка хранилища и импорт нужно прервать.
+func saveAlias(ctx context.Context, s storage.Storage, userID, baseURL string, link exports.Link) (ImportResultItem, bool) {
+	res := ImportResultItem{
+		Line:          link.Line,
+		CorrelationID: link.Slug,
+		OriginalURL:   link.URL,
+	}
+	if link.Err == "" {
+		link.Err = validateOriginalURL(link.URL)
+	}
+	if link.Err != "" {
+		res.Status = BatchStatusInvalid
+		res.Error = link.Err
+		return res, true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importChunkTimeout)
+	defer cancel()
+
+	id, err := s.SaveRecord(ctx, storage.URLRecord{
+		ShortID:     link.Slug,
+		OriginalURL: link.URL,
+		UserID:      userID,
+		CreatedAt:   link.CreatedAt,
+	})
+	switch {
+	case err == nil:
+		res.Status = BatchStatusCreated
+		res.ShortURL = baseURL + "/" + id
+	case errors.Is(err, storage.ErrURLExists):
+		res.Status = BatchStatusExisting
+		res.ShortURL = baseURL + "/" + id
+	case errors.Is(err, storage.ErrShortIDExists):
+		res.Status = ImportStatusConflict
+		res.Error = "short id already exists"
+	default:
+		res.Status = ImportStatusFailed
+		res.Error = "failed to save link"
+		return res, false
+	}
+	return res, true
+}
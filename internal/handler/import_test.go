@@ -0,0 +1,152 @@
+package handler_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeImportResults(t *testing.T, body string) []handler.ImportResultItem {
+	var out []handler.ImportResultItem
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var item handler.ImportResultItem
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &item))
+		out = append(out, item)
+	}
+	return out
+}
+
+// --- TEST POST /api/shorten/import ---
+func TestPostImportURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten/import", handler.PostImportURL(store, baseURL))
+
+	do := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		body := `{"correlation_id":"1","original_url":"https://import-a.com"}
+
+{"correlation_id":"2","original_url":"https://import-a.com"}
+not json
+{"correlation_id":"4","original_url":""}
+`
+		w := do("application/x-ndjson", body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		res := decodeImportResults(t, w.Body.String())
+		assert.Len(t, res, 4)
+		assert.Equal(t, 1, res[0].Line)
+		assert.Equal(t, handler.BatchStatusCreated, res[0].Status)
+		assert.Equal(t, 3, res[1].Line)
+		assert.Equal(t, handler.BatchStatusExisting, res[1].Status)
+		assert.Equal(t, res[0].ShortURL, res[1].ShortURL)
+		assert.Equal(t, handler.BatchStatusInvalid, res[2].Status)
+		assert.Equal(t, "4", res[3].CorrelationID)
+		assert.Equal(t, handler.BatchStatusInvalid, res[3].Status)
+	})
+
+	t.Run("csv with header", func(t *testing.T) {
+		body := "correlation_id,original_url\nx1,https://import-b.com\nx2,https://import-c.com\n"
+		res := decodeImportResults(t, do("text/csv", body).Body.String())
+		assert.Len(t, res, 2)
+		assert.Equal(t, "x1", res[0].CorrelationID)
+		assert.Equal(t, 2, res[0].Line)
+		assert.Equal(t, handler.BatchStatusCreated, res[1].Status)
+
+		rec, ok := store.Get(strings.TrimPrefix(res[1].ShortURL, baseURL+"/"))
+		assert.True(t, ok)
+		assert.Equal(t, "https://import-c.com", rec.OriginalURL)
+	})
+
+	t.Run("many chunks", func(t *testing.T) {
+		var sb strings.Builder
+		for i := 0; i < 1200; i++ {
+			fmt.Fprintf(&sb, "https://bulk.example.com/%d\n", i)
+		}
+		res := decodeImportResults(t, do("text/csv", sb.String()).Body.String())
+		assert.Len(t, res, 1200)
+		for _, r := range res {
+			assert.Equal(t, handler.BatchStatusCreated, r.Status)
+		}
+		urls, err := store.GetUserURLs(t.Context(), "test-user")
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, len(urls), 1200)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/import?format=xml", strings.NewReader(""))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// --- TEST POST /api/shorten/import?mode=aliases ---
+func TestPostImportURL_Aliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	_, err := store.Save(t.Context(), "someone", "taken", "https://taken.example.com")
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten/import", handler.PostImportURL(store, baseURL))
+
+	body := "link,long_url,created_at\n" +
+		"bit.ly/promo,https://example.com/promo,2024-01-02T10:00:00Z\n" +
+		"bit.ly/taken,https://example.com/other,\n" +
+		"bit.ly/again,https://taken.example.com,\n" +
+		"bit.ly/api,https://example.com/reserved,\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/import?mode=aliases", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := decodeImportResults(t, w.Body.String())
+	if assert.Len(t, res, 4) {
+		assert.Equal(t, handler.BatchStatusCreated, res[0].Status)
+		assert.Equal(t, baseURL+"/promo", res[0].ShortURL)
+		assert.Equal(t, handler.ImportStatusConflict, res[1].Status)
+		assert.Equal(t, handler.BatchStatusExisting, res[2].Status)
+		assert.Equal(t, baseURL+"/taken", res[2].ShortURL)
+		assert.Equal(t, handler.BatchStatusInvalid, res[3].Status)
+	}
+
+	rec, ok := store.Get("promo")
+	assert.True(t, ok)
+	assert.Equal(t, "test-user", rec.UserID)
+	assert.Equal(t, 2024, rec.CreatedAt.Year())
+
+	t.Run("unrecognized header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/import?mode=aliases", strings.NewReader("a,b\n1,2\n"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSPath — путь, по которому публикуются открытые ключи проверки токенов.
+const JWKSPath = "/.well-known/jwks.json"
+
+// GetJWKS возвращает Gin handler, отдающий открытые ключи RS256/EdDSA в формате JWKS (RFC 7517).
+// Другие сервисы проверяют по ним токены пользователей без общего секрета;
+// при подписи HS256 набор ключей пуст.
+//
+// Параметры:
+//   - am: менеджер авторизации
+//
+// HTTP ответы:
+//   - 200 OK — JSON {"keys": [...]}.
+func GetJWKS(am *auth.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, am.JWKS())
+	}
+}
@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- TEST GET /.well-known/jwks.json ---
+func TestGetJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	assert.NoError(t, err)
+	signing, err := auth.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	am, err := auth.New(signing)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET(handler.JWKSPath, handler.GetJWKS(am))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, handler.JWKSPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "max-age")
+
+	var jwks auth.JWKS
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+	if !assert.Len(t, jwks.Keys, 1) {
+		return
+	}
+
+	// Токен проверяется только по опубликованному ключу, без доступа к Manager.
+	token, err := am.GenerateToken("user-1")
+	assert.NoError(t, err)
+	parsed, err := jwt.Parse(token, func(tok *jwt.Token) (any, error) {
+		for _, k := range jwks.Keys {
+			if k.Kid == tok.Header["kid"] {
+				x, err := jwt.NewParser().DecodeSegment(k.X)
+				return ed25519.PublicKey(x), err
+			}
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	assert.NoError(t, err)
+	sub, _ := parsed.Claims.GetSubject()
+	assert.Equal(t, "user-1", sub)
+
+	t.Run("HS256 publishes no keys", func(t *testing.T) {
+		hmac, err := auth.NewManager("secret")
+		assert.NoError(t, err)
+		router := gin.New()
+		router.GET(handler.JWKSPath, handler.GetJWKS(hmac))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, handler.JWKSPath, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+	})
+}
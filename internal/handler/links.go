@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/shortener"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// LinksPath — путь коллекции ссылок API v2.
+const LinksPath = "/api/v2/links"
+
+// Link — ресурс короткой ссылки API v2.
+// Все поля присутствуют в ответе всегда; неизвестные или неограниченные значения — null.
+type Link struct {
+	ID          string     `json:"id"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   *time.Time `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Deleted     bool       `json:"deleted"`
+	Owner       string     `json:"owner"`
+	// Clicks — число переходов, дошедших до сервера (кешированные браузером 301/308 не учитываются).
+	Clicks int `json:"clicks"`
+}
+
+// LinkList — ответ со списком ссылок пользователя.
+type LinkList struct {
+	Links []Link `json:"links"`
+}
+
+// CreateLinkRequest — тело запроса на создание ссылки.
+type CreateLinkRequest struct {
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// newLink собирает ресурс Link для записи rec.
+func newLink(rec *storage.URLRecord, baseURL string) Link {
+	link := Link{
+		ID:          rec.ShortID,
+		ShortURL:    fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), rec.ShortID),
+		OriginalURL: rec.OriginalURL,
+		Deleted:     rec.Deleted,
+		Owner:       rec.UserID,
+		Clicks:      rec.Clicks,
+	}
+	if !rec.CreatedAt.IsZero() {
+		created := rec.CreatedAt.UTC()
+		link.CreatedAt = &created
+	}
+	if !rec.ExpiresAt.IsZero() {
+		expires := rec.ExpiresAt.UTC()
+		link.ExpiresAt = &expires
+	}
+	return link
+}
+
+// linkPath возвращает путь ресурса ссылки id.
+func linkPath(id string) string {
+	return LinksPath + "/" + id
+}
+
+// etagOf возвращает сильный ETag для тела ответа.
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches сообщает, что значение If-Match / If-None-Match содержит etag или "*".
+// Слабые валидаторы (W/"...") сравниваются по значению.
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// linkETag возвращает ETag ссылки. Счётчик переходов в валидатор не входит: иначе любой
+// переход по ссылке делал бы If-Match устаревшим.
+func linkETag(link Link) (string, error) {
+	link.Clicks = 0
+	body, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+	return etagOf(body), nil
+}
+
+// renderLink отдаёт ресурс Link с заголовком ETag из linkETag.
+func renderLink(c *gin.Context, status int, link Link) {
+	etag, err := linkETag(link)
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+		return
+	}
+	renderWithETag(c, status, link, etag)
+}
+
+// renderWithETag отдаёт v в JSON с заголовком ETag; пустой etag вычисляется по телу ответа.
+// Для GET с совпавшим If-None-Match отвечает 304 Not Modified без тела.
+func renderWithETag(c *gin.Context, status int, v any, etag string) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+		return
+	}
+	if etag == "" {
+		etag = etagOf(body)
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+
+	if c.Request.Method == http.MethodGet {
+		if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// checkIfMatch проверяет заголовок If-Match против текущего представления ссылки.
+// Возвращает false, если запрос прерван с 412 Precondition Failed.
+func checkIfMatch(c *gin.Context, link Link) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	etag, err := linkETag(link)
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+		return false
+	}
+	if !etagMatches(ifMatch, etag) {
+		problem.Abort(c, problem.New(problem.PreconditionFail, ""))
+		return false
+	}
+	return true
+}
+
+// ownLink возвращает ссылку текущего пользователя или прерывает запрос с 401/404.
+// Ссылки других пользователей неотличимы от несуществующих.
+func ownLink(c *gin.Context, s storage.Storage) (*storage.URLRecord, bool) {
+	userID := getUserID(c)
+	if userID == "" {
+		problem.Abort(c, problem.New(problem.Unauthorized, ""))
+		return nil, false
+	}
+	rec, ok := s.Get(c.Param("id"))
+	if !ok || rec == nil || rec.UserID != userID {
+		problem.Abort(c, problem.New(problem.NotFound, ""))
+		return nil, false
+	}
+	return rec, true
+}
+
+// ListLinks возвращает Gin handler, отдающий все ссылки пользователя (включая удалённые)
+// в порядке создания.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//
+// HTTP ответы:
+//   - 200 OK — {"links": [...]} с заголовком ETag.
+//   - 304 Not Modified — список не изменился (If-None-Match).
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 500 Internal Server Error — ошибка хранилища.
+func ListLinks(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		list := LinkList{Links: []Link{}}
+		err := s.ExportUserURLs(c.Request.Context(), userID, func(rec storage.URLRecord) error {
+			list.Links = append(list.Links, newLink(&rec, baseURL))
+			return nil
+		})
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to list links"))
+			return
+		}
+		renderWithETag(c, http.StatusOK, list, "")
+	}
+}
+
+// CreateLink возвращает Gin handler, создающий ссылку.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//   - auditSvc: сервис audit.Service для логирования действий
+//
+// Тело запроса: {"url": "...", "expires_at": "2030-01-01T00:00:00Z"}; expires_at необязателен
+// и должен быть в будущем. После expires_at редирект по ссылке отвечает 410 Gone.
+//
+// HTTP ответы:
+//   - 201 Created — ресурс Link, заголовки Location и ETag.
+//   - 400 Bad Request — некорректный JSON, пустой url или expires_at в прошлом.
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 409 Conflict — URL уже сокращён; Location указывает на существующую ссылку.
+//   - 500 Internal Server Error — ошибка генерации ID или сохранения.
+func CreateLink(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		var req CreateLinkRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
+			return
+		}
+		originalURL := strings.TrimSpace(req.URL)
+		if originalURL == "" {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "url is required"))
+			return
+		}
+		var expiresAt time.Time
+		if req.ExpiresAt != nil {
+			if !req.ExpiresAt.After(time.Now()) {
+				problem.Abort(c, problem.New(problem.InvalidRequest, "expires_at must be in the future"))
+				return
+			}
+			expiresAt = *req.ExpiresAt
+		}
+
+		id, err := shortener.GenerateID()
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate id"))
+			return
+		}
+
+		shortID, err := s.SaveRecord(ctx, storage.URLRecord{
+			ShortID:     id,
+			OriginalURL: originalURL,
+			UserID:      userID,
+			ExpiresAt:   expiresAt,
+		})
+		if errors.Is(err, storage.ErrURLExists) {
+			c.Header("Location", linkPath(shortID))
+			problem.Abort(c, problem.New(problem.LinkExists, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save url"))
+			return
+		}
+
+		rec, ok := s.Get(shortID)
+		if !ok {
+			problem.Abort(c, problem.Wrap(problem.Internal, storage.ErrLinkNotFound, "failed to read created link"))
+			return
+		}
+		c.Header("Location", linkPath(shortID))
+		renderLink(c, http.StatusCreated, newLink(rec, baseURL))
+
+		auditSvc.Notify(c.Request.Context(), audit.Event{
+			TS:      time.Now().Unix(),
+			Action:  "shorten",
+			UserID:  userID,
+			OwnerID: userID,
+			ShortID: shortID,
+			URL:     originalURL,
+		})
+	}
+}
+
+// GetLink возвращает Gin handler, отдающий ссылку пользователя по ID.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//
+// HTTP ответы:
+//   - 200 OK — ресурс Link с заголовком ETag.
+//   - 304 Not Modified — ссылка не изменилась (If-None-Match).
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+func GetLink(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, ok := ownLink(c, s)
+		if !ok {
+			return
+		}
+		renderLink(c, http.StatusOK, newLink(rec, baseURL))
+	}
+}
+
+// PatchLink возвращает Gin handler, частично изменяющий ссылку пользователя.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//
+// Тело запроса — JSON merge patch (RFC 7396) с полями "original_url" и "expires_at";
+// "expires_at": null снимает срок действия. Заголовок If-Match с ETag ссылки
+// защищает от потери параллельных изменений: условие повторно проверяется хранилищем
+// в момент изменения. Переходы по ссылке ETag не меняют.
+//
+// HTTP ответы:
+//   - 200 OK — изменённый ресурс Link с новым ETag.
+//   - 400 Bad Request — некорректный JSON, неизвестное поле, пустой original_url
+//     или expires_at в прошлом.
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 409 Conflict — новый original_url уже сокращён другой ссылкой.
+//   - 410 Gone — ссылка удалена.
+//   - 412 Precondition Failed — If-Match не совпал с текущим ETag.
+//   - 500 Internal Server Error — ошибка хранилища.
+func PatchLink(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, ok := ownLink(c, s)
+		if !ok {
+			return
+		}
+		if !checkIfMatch(c, newLink(rec, baseURL)) {
+			return
+		}
+		if rec.Deleted {
+			problem.Abort(c, problem.New(problem.Gone, ""))
+			return
+		}
+
+		upd, err := decodeLinkPatch(c)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+		if c.GetHeader("If-Match") != "" {
+			upd.Match = rec
+		}
+
+		updated, err := s.UpdateLink(c.Request.Context(), rec.UserID, rec.ShortID, upd)
+		switch {
+		case errors.Is(err, storage.ErrLinkChanged):
+			problem.Abort(c, problem.New(problem.PreconditionFail, ""))
+			return
+		case errors.Is(err, storage.ErrLinkNotFound):
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		case errors.Is(err, storage.ErrURLExists):
+			problem.Abort(c, problem.New(problem.LinkExists, ""))
+			return
+		case err != nil:
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to update link"))
+			return
+		}
+		renderLink(c, http.StatusOK, newLink(updated, baseURL))
+	}
+}
+
+// decodeLinkPatch разбирает тело PATCH: отсутствующее поле не меняется, null снимает значение.
+func decodeLinkPatch(c *gin.Context) (storage.LinkUpdate, error) {
+	var upd storage.LinkUpdate
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&fields); err != nil || fields == nil {
+		return upd, problem.New(problem.InvalidJSON, "")
+	}
+	for name, raw := range fields {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		switch name {
+		case "original_url":
+			var u string
+			if isNull || json.Unmarshal(raw, &u) != nil || strings.TrimSpace(u) == "" {
+				return upd, problem.New(problem.InvalidRequest, "original_url must be a non-empty string")
+			}
+			u = strings.TrimSpace(u)
+			upd.OriginalURL = &u
+		case "expires_at":
+			var t time.Time
+			if !isNull {
+				if err := json.Unmarshal(raw, &t); err != nil {
+					return upd, problem.New(problem.InvalidRequest, "expires_at must be an RFC 3339 timestamp or null")
+				}
+				if !t.After(time.Now()) {
+					return upd, problem.New(problem.InvalidRequest, "expires_at must be in the future")
+				}
+			}
+			upd.ExpiresAt = &t
+		default:
+			return upd, problem.New(problem.InvalidRequest, fmt.Sprintf("field %q cannot be changed", name))
+		}
+	}
+	return upd, nil
+}
+
+// DeleteLink возвращает Gin handler, удаляющий ссылку пользователя.
+// В отличие от DELETE /api/user/urls ссылка помечается удалённой синхронно.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//   - baseURL: базовый адрес коротких ссылок
+//   - auditSvc: сервис audit.Service для логирования действий
+//
+// HTTP ответы:
+//   - 204 No Content — ссылка удалена (повторное удаление тоже возвращает 204).
+//   - 401 Unauthorized — userID отсутствует в контексте.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 412 Precondition Failed — If-Match не совпал с текущим ETag.
+//   - 500 Internal Server Error — ошибка хранилища.
+func DeleteLink(s storage.Storage, baseURL string, auditSvc *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, ok := ownLink(c, s)
+		if !ok {
+			return
+		}
+		if !checkIfMatch(c, newLink(rec, baseURL)) {
+			return
+		}
+		if rec.Deleted {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		if err := s.MarkDeleted(rec.UserID, []string{rec.ShortID}); err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to delete link"))
+			return
+		}
+		c.Status(http.StatusNoContent)
+
+		auditSvc.Notify(c.Request.Context(), audit.Event{
+			TS:      time.Now().Unix(),
+			Action:  "delete",
+			UserID:  rec.UserID,
+			OwnerID: rec.UserID,
+			ShortID: rec.ShortID,
+			URL:     rec.OriginalURL,
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// --- TEST /api/v2/links ---
+func TestLinksV2(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+	_, err := store.SaveRecord(ctx, storage.URLRecord{ShortID: "other", OriginalURL: "https://example.com/other", UserID: "someone"})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(testUser())
+	auditSvc := audit.NewService(zap.NewNop())
+	router.GET("/api/v2/links", handler.ListLinks(store, baseURL))
+	router.POST("/api/v2/links", handler.CreateLink(store, baseURL, auditSvc))
+	router.GET("/api/v2/links/:id", handler.GetLink(store, baseURL))
+	router.PATCH("/api/v2/links/:id", handler.PatchLink(store, baseURL))
+	router.DELETE("/api/v2/links/:id", handler.DeleteLink(store, baseURL, auditSvc))
+	router.GET("/:id", handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter()))
+
+	do := func(method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) handler.Link {
+		var link handler.Link
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+		return link
+	}
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	w := do(http.MethodPost, "/api/v2/links", `{"url":"https://example.com/v2","expires_at":"`+expires.Format(time.RFC3339)+`"}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	created := decode(w)
+	id := created.ID
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "/api/v2/links/"+id, w.Header().Get("Location"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, baseURL+"/"+id, created.ShortURL)
+	assert.Equal(t, "https://example.com/v2", created.OriginalURL)
+	assert.Equal(t, "test-user", created.Owner)
+	assert.NotNil(t, created.CreatedAt)
+	if assert.NotNil(t, created.ExpiresAt) {
+		assert.True(t, expires.Equal(*created.ExpiresAt))
+	}
+	assert.False(t, created.Deleted)
+	assert.Equal(t, 0, created.Clicks)
+
+	t.Run("all fields are always present", func(t *testing.T) {
+		w := do(http.MethodPost, "/api/v2/links", `{"url":"https://example.com/plain"}`, nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var raw map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+		for _, field := range []string{"id", "short_url", "original_url", "created_at", "expires_at", "deleted", "owner", "clicks"} {
+			assert.Contains(t, raw, field)
+		}
+		assert.Nil(t, raw["expires_at"])
+	})
+
+	t.Run("create validation", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v2/links", `{`, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v2/links", `{"url":" "}`, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v2/links", `{"url":"https://example.com/past","expires_at":"2000-01-01T00:00:00Z"}`, nil).Code)
+
+		w := do(http.MethodPost, "/api/v2/links", `{"url":"https://example.com/v2"}`, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "/api/v2/links/"+id, w.Header().Get("Location"))
+	})
+
+	t.Run("get with etag", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/v2/links/"+id, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+		assert.Equal(t, created, decode(w))
+
+		w = do(http.MethodGet, "/api/v2/links/"+id, "", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v2/links/other", "", nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v2/links/missing", "", nil).Code)
+	})
+
+	t.Run("clicks are counted", func(t *testing.T) {
+		assert.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "/"+id, "", nil).Code)
+		assert.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "/"+id, "", nil).Code)
+		assert.Equal(t, 2, decode(do(http.MethodGet, "/api/v2/links/"+id, "", nil)).Clicks)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/v2/links", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list handler.LinkList
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		if assert.Len(t, list.Links, 2) {
+			assert.Equal(t, id, list.Links[0].ID)
+		}
+
+		w = do(http.MethodGet, "/api/v2/links", "", map[string]string{"If-None-Match": w.Header().Get("ETag")})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		etag := do(http.MethodGet, "/api/v2/links/"+id, "", nil).Header().Get("ETag")
+		assert.NoError(t, store.RecordClick(ctx, id), "clicks do not invalidate the ETag")
+
+		w := do(http.MethodPatch, "/api/v2/links/"+id, `{"original_url":"https://example.com/moved","expires_at":null}`, map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+		patched := decode(w)
+		assert.Equal(t, "https://example.com/moved", patched.OriginalURL)
+		assert.Nil(t, patched.ExpiresAt)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+		id, ok := store.Lookup(ctx, "https://example.com/moved")
+		assert.True(t, ok)
+		assert.Equal(t, patched.ID, id)
+		_, ok = store.Lookup(ctx, "https://example.com/v2")
+		assert.False(t, ok)
+
+		w = do(http.MethodPatch, "/api/v2/links/"+patched.ID, `{"original_url":"https://example.com/again"}`, map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		assert.Equal(t, http.StatusConflict, do(http.MethodPatch, "/api/v2/links/"+patched.ID, `{"original_url":"https://example.com/plain"}`, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/api/v2/links/"+patched.ID, `{"owner":"me"}`, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/api/v2/links/"+patched.ID, `{"expires_at":"2000-01-01T00:00:00Z"}`, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/api/v2/links/other", `{"original_url":"https://example.com/x"}`, nil).Code)
+	})
+
+	t.Run("expired link is gone", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		_, err := store.UpdateLink(ctx, "test-user", id, storage.LinkUpdate{ExpiresAt: &past})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusGone, do(http.MethodGet, "/"+id, "", nil).Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodDelete, "/api/v2/links/"+id, "", map[string]string{"If-Match": `"stale"`}).Code)
+
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/v2/links/"+id, "", nil).Code)
+		assert.True(t, decode(do(http.MethodGet, "/api/v2/links/"+id, "", nil)).Deleted)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/v2/links/"+id, "", nil).Code)
+		assert.Equal(t, http.StatusGone, do(http.MethodPatch, "/api/v2/links/"+id, `{"original_url":"https://example.com/x"}`, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v2/links/other", "", nil).Code)
+	})
+}
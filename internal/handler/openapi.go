@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUIVersion — версия swagger-ui-dist, загружаемая страницей документации с CDN.
+const swaggerUIVersion = "5.17.14"
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>URL Shortener API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+  window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
+
+// GetOpenAPISpec возвращает Gin handler, отдающий документ OpenAPI.
+//
+// Параметры:
+//   - spec: документ OpenAPI 3 в формате JSON (api.OpenAPI)
+//
+// HTTP ответы:
+//   - 200 OK — документ application/json.
+func GetOpenAPISpec(spec []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", spec)
+	}
+}
+
+// GetAPIDocs возвращает Gin handler, отдающий HTML-страницу Swagger UI.
+//
+// Параметры:
+//   - specURL: путь, по которому страница загружает документ OpenAPI
+//
+// HTTP ответы:
+//   - 200 OK — HTML-страница text/html.
+func GetAPIDocs(specURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = docsPage.Execute(c.Writer, struct{ Version, SpecURL string }{swaggerUIVersion, specURL})
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// linkPasswordHeader — заголовок, в котором API-клиенты передают пароль ссылки.
+	linkPasswordHeader = "X-Link-Password"
+)
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Protected link</title></head>
+<body>
+<form method="POST" action="">
+<p>This link is password protected.</p>
+{{if .}}<p style="color:red">{{.}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// hashLinkPassword возвращает bcrypt-хеш пароля ссылки.
+// Пустой пароль означает отсутствие защиты, в этом случае возвращается пустая строка.
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// renderPasswordForm отдаёт HTML-форму ввода пароля с необязательным сообщением об ошибке.
+func renderPasswordForm(c *gin.Context, status int, message string) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = passwordForm.Execute(c.Writer, message)
+}
+
+// checkLinkPassword проверяет доступ к защищённой паролем ссылке.
+//
+// Пароль принимается из заголовка X-Link-Password или поля формы "password" (POST).
+// Неудачные попытки ограничиваются limiter по короткому ID ссылки.
+// Возвращает true, если редирект разрешён; иначе ответ уже записан:
+//   - 401 Unauthorized — пароль не передан или неверен (браузеру отдаётся HTML-форма).
+//   - 429 Too Many Requests — превышен лимит неудачных попыток.
+func checkLinkPassword(c *gin.Context, rec *storage.URLRecord, limiter *service.AttemptLimiter) bool {
+	if rec.PasswordHash == "" {
+		return true
+	}
+
+	wantsHTML := strings.Contains(c.GetHeader("Accept"), "text/html")
+
+	password := c.GetHeader(linkPasswordHeader)
+	if password == "" && c.Request.Method == http.MethodPost {
+		password = c.PostForm("password")
+	}
+
+	if password == "" {
+		if wantsHTML {
+			renderPasswordForm(c, http.StatusUnauthorized, "")
+			return false
+		}
+		problem.Abort(c, problem.New(problem.PasswordRequired, ""))
+		return false
+	}
+
+	if ok, retryAfter := limiter.Allow(rec.ShortID); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		problem.Abort(c, problem.New(problem.TooManyAttempts, ""))
+		return false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(rec.PasswordHash), []byte(password)); err != nil {
+		if wantsHTML {
+			renderPasswordForm(c, http.StatusUnauthorized, "Wrong password")
+			return false
+		}
+		problem.Abort(c, problem.New(problem.InvalidPassword, ""))
+		return false
+	}
+
+	limiter.Reset(rec.ShortID)
+	return true
+}
@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// --- TEST password-protected links ---
+func TestPasswordProtectedLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baseURL := "http://localhost:8080"
+	store := storage.NewInMemoryStorage()
+	auditSvc := newTestAuditService()
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten", handler.PostJSONURL(store, baseURL, auditSvc))
+	follow := handler.GetIDURL(store, auditSvc, service.NewPasswordLimiter())
+	router.GET("/:id", follow)
+	router.POST("/:id", follow)
+
+	body, _ := json.Marshal(handler.RequestJSON{URL: "https://secret.example.com", Password: "s3cret"})
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created handler.ResponseJSON
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	path := strings.TrimPrefix(created.Result, baseURL)
+
+	rec, ok := store.Get(strings.TrimPrefix(path, "/"))
+	assert.True(t, ok)
+	assert.NotEqual(t, "s3cret", rec.PasswordHash)
+
+	t.Run("browser gets password form", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `name="password"`)
+	})
+
+	t.Run("correct header password redirects", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Link-Password", "s3cret")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "https://secret.example.com", w.Header().Get("Location"))
+	})
+
+	t.Run("correct form password redirects", func(t *testing.T) {
+		form := url.Values{"password": {"s3cret"}}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusSeeOther, w.Code, "browser must not re-send the form to the destination")
+		assert.Equal(t, "https://secret.example.com", w.Header().Get("Location"))
+	})
+
+	t.Run("form password on permanent link redirects with 303", func(t *testing.T) {
+		body, _ := json.Marshal(handler.RequestJSON{URL: "https://secret.example.com/permanent", Password: "s3cret", RedirectCode: http.StatusPermanentRedirect})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created handler.ResponseJSON
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		path := strings.TrimPrefix(created.Result, baseURL)
+
+		form := url.Values{"password": {"s3cret"}}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Link-Password", "s3cret")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code, "GET keeps the link's code")
+	})
+
+	t.Run("failed attempts are rate limited", func(t *testing.T) {
+		codes := make([]int, 0, 6)
+		for i := 0; i < 6; i++ {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("X-Link-Password", "wrong")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes = append(codes, w.Code)
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, codes[0])
+		assert.Equal(t, http.StatusTooManyRequests, codes[5])
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Link-Password", "s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+}
+
+// --- TEST password attempts are shared between redirect and expand ---
+func TestPasswordLimiterShared(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	_, err = store.SaveRecord(context.Background(), storage.URLRecord{
+		ShortID: "locked", OriginalURL: "https://secret.example.com", UserID: "someone", PasswordHash: string(hash),
+	})
+	assert.NoError(t, err)
+
+	limiter := service.NewPasswordLimiter()
+	router := gin.New()
+	router.Use(testUser())
+	router.GET("/:id", handler.GetIDURL(store, newTestAuditService(), limiter))
+	router.GET("/api/expand/:id", handler.GetExpand(store, "http://localhost:8080", limiter))
+
+	for i := 0; i < service.MaxPasswordAttempts; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/locked", nil)
+		req.Header.Set("X-Link-Password", "wrong")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/expand/locked", nil)
+	req.Header.Set("X-Link-Password", "s3cret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "expand shares the redirect's attempt budget")
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/gin-gonic/gin"
 )
@@ -15,7 +16,7 @@ func PingHandler(store storage.Storage) gin.HandlerFunc {
 		defer cancel()
 
 		if err := store.Ping(ctx); err != nil {
-			c.Status(http.StatusInternalServerError)
+			problem.Abort(c, problem.Wrap(problem.Unavailable, err, ""))
 			return
 		}
 
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/qr"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// parseQROptions собирает параметры QR-кода из query-параметров size, level и margin.
+// Формат берётся из параметра formatParam ("format" для /api/qr/{id}, "qr" для эндпоинтов создания).
+func parseQROptions(c *gin.Context, formatParam string) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+
+	if v := c.Query(formatParam); v != "" {
+		opts.Format = strings.ToLower(v)
+	}
+	if v := c.Query("level"); v != "" {
+		opts.Level = strings.ToUpper(v)
+	}
+	if v := c.Query("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, qr.ErrInvalidSize
+		}
+		opts.Size = n
+	}
+	if v := c.Query("margin"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, qr.ErrInvalidMargin
+		}
+		opts.Margin = n
+	}
+
+	return opts, opts.Validate()
+}
+
+// qrDataURI кодирует shortURL в QR-код и возвращает его в виде data URI
+// для встраивания в JSON-ответы эндпоинтов создания.
+func qrDataURI(shortURL string, opts qr.Options) (string, error) {
+	img, err := qr.Encode(shortURL, opts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("data:%s;base64,%s", opts.ContentType(), base64.StdEncoding.EncodeToString(img)), nil
+}
+
+// GetQRCode возвращает Gin handler, отдающий QR-код полной короткой ссылки.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage для поиска URL по ID
+//   - baseURL: базовый адрес для формирования короткой ссылки
+//
+// Query-параметры:
+//   - format: png (по умолчанию) или svg
+//   - size: размер изображения в пикселях (64–2048, по умолчанию 256)
+//   - level: уровень коррекции ошибок L, M, Q или H (по умолчанию M)
+//   - margin: ширина отступа в модулях (0–16, по умолчанию 4)
+//
+// HTTP ответы:
+//   - 200 OK — изображение image/png или image/svg+xml.
+//   - 400 Bad Request — некорректные параметры.
+//   - 404 Not Found — ID не найден.
+//   - 410 Gone — URL помечен как удалён.
+//   - 500 Internal Server Error — ошибка генерации изображения.
+func GetQRCode(s storage.Storage, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		rec, ok := s.Get(id)
+		if !ok || rec == nil {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		if rec.Deleted {
+			problem.Abort(c, problem.New(problem.Gone, ""))
+			return
+		}
+
+		opts, err := parseQROptions(c, "format")
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+
+		shortURL := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), id)
+		img, err := qr.Encode(shortURL, opts)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to generate qr code"))
+			return
+		}
+
+		c.Data(http.StatusOK, opts.ContentType(), img)
+	}
+}
@@ -0,0 +1,109 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- TEST GET /api/qr/:id ---
+func TestGetQRCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	_, _ = store.Save(context.Background(), "user1", "alive123", "https://practicum.yandex.ru/")
+	_, _ = store.Save(context.Background(), "user1", "dead123", "https://example.com/")
+	_ = store.MarkDeleted("user1", []string{"dead123"})
+
+	router := gin.New()
+	router.GET("/api/qr/:id", handler.GetQRCode(store, "http://localhost:8080"))
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatusCode  int
+		wantContentType string
+	}{
+		{
+			name:            "png by default",
+			path:            "/api/qr/alive123",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "image/png",
+		},
+		{
+			name:            "svg with options",
+			path:            "/api/qr/alive123?format=svg&size=512&level=h&margin=2",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "image/svg+xml",
+		},
+		{
+			name:           "invalid size",
+			path:           "/api/qr/alive123?size=abc",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "deleted URL returns 410",
+			path:           "/api/qr/dead123",
+			wantStatusCode: http.StatusGone,
+		},
+		{
+			name:           "non-existent id",
+			path:           "/api/qr/unknown",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestPostJSONURL_WithQR(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(testUser())
+	router.POST("/api/shorten", handler.PostJSONURL(storage.NewInMemoryStorage(), "http://localhost:8080", newTestAuditService()))
+
+	t.Run("qr attached as data URI", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"url": "https://example.com/qr"})
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten?qr=svg", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp handler.ResponseJSON
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, strings.HasPrefix(resp.QR, "data:image/svg+xml;base64,"))
+	})
+
+	t.Run("invalid qr format", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"url": "https://example.com/qr2"})
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten?qr=gif", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
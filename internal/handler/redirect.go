@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/rules"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/split"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectOption настраивает хендлер GetIDURL.
+type RedirectOption func(*redirectConfig)
+
+// redirectConfig содержит необязательные зависимости хендлера редиректа.
+type redirectConfig struct {
+	countries   rules.CountryResolver
+	defaultCode int
+}
+
+// WithCountryResolver подключает определение страны посетителя для правил с условием country.
+// Без него такие правила никогда не срабатывают.
+func WithCountryResolver(r rules.CountryResolver) RedirectOption {
+	return func(cfg *redirectConfig) {
+		cfg.countries = r
+	}
+}
+
+// WithDefaultRedirectCode задаёт код редиректа для ссылок без собственного redirect_code.
+// Недопустимые значения игнорируются, по умолчанию используется 307.
+func WithDefaultRedirectCode(code int) RedirectOption {
+	return func(cfg *redirectConfig) {
+		if ValidRedirectCode(code) {
+			cfg.defaultCode = code
+		}
+	}
+}
+
+// permanentCacheMaxAge — время кеширования постоянного редиректа браузерами и прокси.
+const permanentCacheMaxAge = 24 * time.Hour
+
+// ValidRedirectCode сообщает, можно ли использовать code как код редиректа ссылки.
+func ValidRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// redirectCode возвращает код редиректа для записи rec: собственный код ссылки
+// или код по умолчанию из конфигурации.
+func redirectCode(rec *storage.URLRecord, cfg *redirectConfig) int {
+	if ValidRedirectCode(rec.RedirectCode) {
+		return rec.RedirectCode
+	}
+	return cfg.defaultCode
+}
+
+// setRedirectHeaders выставляет заголовки кеширования и индексации для редиректа с кодом code.
+//
+// Постоянный редирект (301, 308) разрешено кешировать, если его результат не зависит
+// от посетителя, у ссылки нет срока действия и каждый переход не нужно учитывать на сервере. Временный редирект (302, 307)
+// считается трекинговым: Cache-Control: no-store гарантирует, что каждый переход дойдёт
+// до сервера, а X-Robots-Tag: noindex исключает короткую ссылку из поисковой выдачи.
+func setRedirectHeaders(c *gin.Context, rec *storage.URLRecord, code int) {
+	permanent := code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
+	if !permanent {
+		c.Header("Cache-Control", "private, no-store")
+		c.Header("X-Robots-Tag", "noindex")
+		return
+	}
+
+	perVisitor := rec.PasswordHash != "" || rec.MaxClicks > 0 || !rec.ExpiresAt.IsZero() ||
+		len(rec.Rules) > 0 || len(rec.Destinations) > 0
+	if perVisitor {
+		c.Header("Cache-Control", "private, no-store")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(permanentCacheMaxAge.Seconds())))
+}
+
+// variantCookieTTL — срок жизни cookie, закрепляющей вариант A/B-сплита за посетителем.
+const variantCookieTTL = 30 * 24 * time.Hour
+
+// resolveDestination выбирает адрес редиректа для записи rec и метку варианта сплита.
+//
+// Порядок выбора:
+//  1. Правила условного редиректа — первое подходящее правило.
+//  2. A/B-сплит — вариант из cookie посетителя или случайный по весам (закрепляется cookie).
+//  3. rec.OriginalURL.
+func resolveDestination(c *gin.Context, rec *storage.URLRecord, cfg *redirectConfig) (dest, variant string) {
+	if dest, ok := matchRules(c, rec, cfg); ok {
+		return dest, ""
+	}
+	if len(rec.Destinations) > 0 {
+		return pickVariant(c, rec)
+	}
+	return rec.OriginalURL, ""
+}
+
+// matchRules проверяет правила условного редиректа записи rec.
+func matchRules(c *gin.Context, rec *storage.URLRecord, cfg *redirectConfig) (string, bool) {
+	if len(rec.Rules) == 0 {
+		return "", false
+	}
+
+	v := rules.Visitor{
+		Platform:  rules.DetectPlatform(c.GetHeader("User-Agent")),
+		Languages: rules.ParseAcceptLanguage(c.GetHeader("Accept-Language")),
+	}
+	if cfg.countries != nil && rules.NeedsCountry(rec.Rules) {
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			v.Country, _ = cfg.countries.Country(ip)
+		}
+	}
+
+	return rules.Match(rec.Rules, v)
+}
+
+// pickVariant выбирает вариант A/B-сплита. Повторные переходы посетителя
+// попадают в тот же вариант благодаря cookie "ab_<id>" с индексом варианта.
+func pickVariant(c *gin.Context, rec *storage.URLRecord) (dest, variant string) {
+	name := "ab_" + rec.ShortID
+
+	idx := -1
+	if v, err := c.Cookie(name); err == nil {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < len(rec.Destinations) {
+			idx = n
+		}
+	}
+	if idx < 0 {
+		idx = split.Pick(rec.Destinations)
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    strconv.Itoa(idx),
+			Path:     "/" + rec.ShortID,
+			MaxAge:   int(variantCookieTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	d := rec.Destinations[idx]
+	return d.URL, split.Variant(d, idx)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/rules"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkRules возвращает Gin handler со списком правил условного редиректа ссылки.
+//
+// Параметры:
+//   - s: интерфейс storage.Storage
+//
+// HTTP ответы:
+//   - 200 OK — JSON-массив правил (пустой, если правил нет).
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+func GetLinkRules(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		rec, ok := s.Get(c.Param("id"))
+		if !ok || rec == nil || rec.UserID != userID {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+
+		list := rec.Rules
+		if list == nil {
+			list = []storage.RedirectRule{}
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// PutLinkRules возвращает Gin handler, заменяющий правила условного редиректа ссылки.
+//
+// Тело запроса — упорядоченный JSON-массив правил:
+//
+//	[{"platform":"ios","url":"https://apps.apple.com/app/id1"},
+//	 {"platform":"android","url":"https://play.google.com/store/apps/details?id=app"},
+//	 {"language":"de","country":"DE","url":"https://example.de"}]
+//
+// Правила проверяются по порядку, срабатывает первое подходящее.
+//
+// HTTP ответы:
+//   - 200 OK — сохранённый список правил.
+//   - 400 Bad Request — некорректный JSON или правило.
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 500 Internal Server Error — ошибка хранилища.
+func PutLinkRules(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		var list []storage.RedirectRule
+		if err := c.ShouldBindJSON(&list); err != nil {
+			problem.Abort(c, problem.New(problem.InvalidJSON, ""))
+			return
+		}
+
+		list, err := rules.Validate(list)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, err.Error()))
+			return
+		}
+
+		err = s.SetRules(c.Request.Context(), userID, c.Param("id"), list)
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to save rules"))
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// DeleteLinkRules возвращает Gin handler, удаляющий все правила условного редиректа ссылки.
+//
+// HTTP ответы:
+//   - 204 No Content — правила удалены.
+//   - 401 Unauthorized — отсутствует userID.
+//   - 404 Not Found — ссылка не найдена или принадлежит другому пользователю.
+//   - 500 Internal Server Error — ошибка хранилища.
+func DeleteLinkRules(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := getUserID(c)
+		if userID == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, ""))
+			return
+		}
+
+		err := s.SetRules(c.Request.Context(), userID, c.Param("id"), nil)
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			problem.Abort(c, problem.New(problem.NotFound, ""))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to delete rules"))
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticCountry — заглушка GeoIP, всегда возвращающая одну страну.
+type staticCountry string
+
+func (s staticCountry) Country(_ net.IP) (string, error) {
+	return string(s), nil
+}
+
+// --- TEST /api/user/urls/:id/rules and conditional redirects ---
+func TestLinkRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	_, _ = store.Save(context.Background(), "test-user", "app123", "https://example.com/app")
+	_, _ = store.Save(context.Background(), "other-user", "foreign1", "https://example.com/foreign")
+
+	router := gin.New()
+	router.Use(testUser())
+	router.GET("/api/user/urls/:id/rules", handler.GetLinkRules(store))
+	router.PUT("/api/user/urls/:id/rules", handler.PutLinkRules(store))
+	router.DELETE("/api/user/urls/:id/rules", handler.DeleteLinkRules(store))
+	router.GET("/:id", handler.GetIDURL(store, newTestAuditService(), service.NewPasswordLimiter(), handler.WithCountryResolver(staticCountry("DE"))))
+
+	put := func(id string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPut, "/api/user/urls/"+id+"/rules", bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	follow := func(ua, lang string) string {
+		req := httptest.NewRequest(http.MethodGet, "/app123", nil)
+		req.Header.Set("User-Agent", ua)
+		req.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		return w.Header().Get("Location")
+	}
+
+	t.Run("set rules", func(t *testing.T) {
+		w := put("app123", []storage.RedirectRule{
+			{Platform: "ios", URL: "https://apps.apple.com/app/id1"},
+			{Platform: "android", URL: "https://play.google.com/store/apps/details?id=app"},
+			{Country: "de", Language: "de", URL: "https://example.de"},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		w := put("app123", []storage.RedirectRule{{Platform: "symbian", URL: "https://a.com"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("foreign link", func(t *testing.T) {
+		w := put("foreign1", []storage.RedirectRule{{Platform: "ios", URL: "https://a.com"}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("get rules", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls/app123/rules", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got []storage.RedirectRule
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Len(t, got, 3)
+		assert.Equal(t, "DE", got[2].Country)
+	})
+
+	t.Run("redirect follows rules", func(t *testing.T) {
+		assert.Equal(t, "https://apps.apple.com/app/id1", follow("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", "en"))
+		assert.Equal(t, "https://play.google.com/store/apps/details?id=app", follow("Mozilla/5.0 (Linux; Android 14)", "en"))
+		assert.Equal(t, "https://example.de", follow("Mozilla/5.0 (Windows NT 10.0)", "de-DE,en;q=0.5"))
+		assert.Equal(t, "https://example.com/app", follow("Mozilla/5.0 (Windows NT 10.0)", "en-US"))
+	})
+
+	t.Run("delete rules", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/urls/app123/rules", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.Equal(t, "https://example.com/app", follow("Mozilla/5.0 (iPhone)", "en"))
+	})
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader — заголовок, в котором клиент передаёт API-ключ.
+// Ключ также принимается в заголовке "Authorization: Bearer <key>", если он начинается
+// с auth.APIKeyPrefix; остальные bearer-токены считаются JWT пользователя.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyKey — ключ контекста Gin с API-ключом (*storage.APIKey), которым аутентифицирован запрос.
+const apiKeyKey = "apiKey"
+
+// APIKeyResolver находит API-ключ по его хешу.
+type APIKeyResolver interface {
+	GetAPIKey(ctx context.Context, hash string) (*storage.APIKey, error)
+}
+
+// requestAPIKey возвращает API-ключ, которым аутентифицирован запрос, или nil.
+func requestAPIKey(c *gin.Context) *storage.APIKey {
+	key, _ := c.Get(apiKeyKey)
+	found, _ := key.(*storage.APIKey)
+	return found
+}
+
+// APIKeyID возвращает ID API-ключа, которым аутентифицирован запрос, или пустую строку.
+func APIKeyID(c *gin.Context) string {
+	if key := requestAPIKey(c); key != nil {
+		return key.ID
+	}
+	return ""
+}
+
+// RequireScope возвращает Gin middleware, проверяющий область действия API-ключа:
+// запрос с ключом без scope отклоняется с 403. Запросы по cookie или JWT не ограничиваются.
+// Подключается к маршруту в router.New, например RequireScope(storage.ScopeCreate).
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := requestAPIKey(c); key != nil && !key.HasScope(scope) {
+			problem.Abort(c, problem.New(problem.InsufficientScope, "API key has no "+scope+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession возвращает Gin middleware для маршрутов, недоступных с API-ключом:
+// управление ключами (утёкший ключ нельзя использовать для выпуска новых) и учётной записью.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requestAPIKey(c) != nil {
+			problem.Abort(c, problem.New(problem.InsufficientScope, "endpoint requires a user session, not an API key"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer или пустую строку.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// apiKeyFromRequest извлекает API-ключ из X-API-Key или из Authorization: Bearer,
+// если bearer-токен имеет префикс API-ключа.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	if token := bearerToken(r); auth.IsAPIKey(token) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey проверяет API-ключ; область действия проверяют RequireScope
+// и RequireSession на маршрутах.
+// При успехе сохраняет в контекст userID владельца и ID ключа и пишет событие аудита "api_key_use".
+func authenticateAPIKey(c *gin.Context, keys APIKeyResolver, auditSvc *audit.Service, key string, logger *zap.Logger) bool {
+	if !auth.IsAPIKey(key) {
+		problem.Abort(c, problem.New(problem.InvalidAPIKey, "malformed API key"))
+		return false
+	}
+	found, err := keys.GetAPIKey(c.Request.Context(), auth.HashAPIKey(key))
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		problem.Abort(c, problem.New(problem.InvalidAPIKey, ""))
+		return false
+	}
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, "failed to check API key"))
+		return false
+	}
+	if !found.Active(time.Now()) {
+		problem.Abort(c, problem.New(problem.InvalidAPIKey, ""))
+		return false
+	}
+
+	path := c.Request.URL.Path
+	logger.Info("user id", zap.String("user_id", found.UserID), zap.String("api_key_id", found.ID), zap.String("path", path))
+	c.Set(userIDKey, found.UserID)
+	c.Set(apiKeyKey, found)
+	if auditSvc != nil {
+		auditSvc.Notify(c.Request.Context(), audit.Event{
+			TS:       time.Now().Unix(),
+			Action:   "api_key_use",
+			UserID:   found.UserID,
+			URL:      c.Request.Method + " " + path,
+			APIKeyID: found.ID,
+		})
+	}
+	return true
+}
@@ -3,7 +3,9 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -24,70 +26,154 @@ func generateToken(am *auth.Manager, logger *zap.Logger) (newID, newToken string
 	return newID, token, nil
 }
 
-// AuthMiddleware возвращает Gin middleware для авторизации пользователей через cookie "auth_token".
+// SetAuthCookie отдаёт клиенту токен в cookie auth_token со сроком жизни токена.
+func SetAuthCookie(c *gin.Context, am *auth.Manager, token string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "auth_token",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(am.TTL().Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearAuthCookie удаляет cookie auth_token; следующий запрос получит нового анонимного пользователя.
+func ClearAuthCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "auth_token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// authOptions — настройки AuthMiddleware.
+type authOptions struct {
+	// provision — выдавать новую анонимную личность, если токена нет или он невалиден.
+	provision bool
+}
+
+// AuthOption настраивает AuthMiddleware.
+type AuthOption func(*authOptions)
+
+// WithoutProvisioning включает строгий режим: AuthMiddleware только проверяет токен
+// и не выдаёт новую личность. Запрос без валидного токена остаётся без userID;
+// личность выдаёт ProvisionUser на маршрутах создания ссылок, а RequireUser отвечает
+// 401 на пользовательских маршрутах.
+func WithoutProvisioning() AuthOption {
+	return func(o *authOptions) {
+		o.provision = false
+	}
+}
+
+// provisionUser выдаёт клиенту нового анонимного пользователя и сохраняет его userID в контекст.
+// При ошибке ответ уже записан и возвращается false.
+func provisionUser(c *gin.Context, am *auth.Manager, logger *zap.Logger) bool {
+	newID, token, err := generateToken(am, logger)
+	if err != nil {
+		problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+		return false
+	}
+	SetAuthCookie(c, am, token)
+	c.Set(userIDKey, newID)
+	return true
+}
+
+// ProvisionUser возвращает Gin middleware для маршрутов создания ссылок: если
+// AuthMiddleware не определил пользователя (строгий режим), выдаётся новый анонимный
+// пользователь с cookie auth_token. В обычном режиме пользователь уже определён,
+// и middleware ничего не делает.
+func ProvisionUser(am *auth.Manager, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(userIDKey) == "" && !provisionUser(c, am, logger) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUser возвращает Gin middleware для пользовательских маршрутов: без userID
+// в контексте (нет валидного токена или API-ключа) запрос отклоняется с 401.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(userIDKey) == "" {
+			problem.Abort(c, problem.New(problem.Unauthorized, "valid auth_token cookie or API key is required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthMiddleware возвращает Gin middleware для авторизации пользователей через cookie "auth_token",
+// JWT в заголовке Authorization: Bearer или API-ключ.
 //
 // Поведение:
 // 1. Если cookie отсутствует или пустая, создаётся новый userID и токен, cookie устанавливается клиенту.
 // 2. Если cookie есть, middleware проверяет токен через auth.Manager.
 //   - Если токен валиден, userID сохраняется в контекст Gin.
-//   - Если токен невалиден, создаётся новый userID и токен, cookie обновляется.
+//   - Если прошла половина срока жизни токена или он подписан предыдущим ключом,
+//     клиенту выдаётся новый токен с тем же userID (скользящее продление).
+//   - Если токен невалиден или истёк, создаётся новый userID и токен, cookie обновляется.
 //
 // 3. userID сохраняется в контекст запроса под ключом "userID".
 // 4. Ошибки генерации токена логируются через zap.Logger.
 // 5. После проверки токена вызывается c.Next() для передачи управления следующему обработчику.
 //
+// JWT в Authorization: Bearer проверяется так же, как cookie, и имеет приоритет перед ней.
+//
+// Если передан API-ключ (X-API-Key или Authorization: Bearer с префиксом auth.APIKeyPrefix)
+// и keys не nil, cookie не проверяется и не выдаётся: запрос выполняется от имени владельца ключа. Невалидный,
+// истёкший или отозванный ключ — 401. Область действия ключа проверяется на маршрутах
+// через RequireScope и RequireSession.
+// Использование ключа пишется в аудит событием "api_key_use".
+//
+// С опцией WithoutProvisioning пункт 1 и выдача нового пользователя при невалидном
+// токене отключены (строгий режим).
+//
 // Пример использования:
 //
 //	r := gin.New()
-//	r.Use(AuthMiddleware(authManager, logger))
-func AuthMiddleware(am *auth.Manager, logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		cookie, err := c.Cookie("auth_token")
+//	r.Use(AuthMiddleware(authManager, store, auditSvc, logger))
+func AuthMiddleware(am *auth.Manager, keys APIKeyResolver, auditSvc *audit.Service, logger *zap.Logger, opts ...AuthOption) gin.HandlerFunc {
+	o := authOptions{provision: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-		if err != nil || cookie == "" {
-			newID, token, err := generateToken(am, logger)
-			if err != nil {
-				c.AbortWithStatus(http.StatusInternalServerError)
+	return func(c *gin.Context) {
+		if keys != nil {
+			if key := apiKeyFromRequest(c.Request); key != "" {
+				if authenticateAPIKey(c, keys, auditSvc, key, logger) {
+					c.Next()
+				}
 				return
 			}
-
-			http.SetCookie(c.Writer, &http.Cookie{
-				Name:     "auth_token",
-				Value:    token,
-				Path:     "/",
-				HttpOnly: true,
-				SameSite: http.SameSiteLaxMode,
-			})
-
-			c.Set(userIDKey, newID)
-			c.Next()
-			return
 		}
 
-		userID, err := am.ParseToken(cookie, logger)
-		if err != nil || userID == "" {
-			newID, token, err := generateToken(am, logger)
-			if err != nil {
-				c.AbortWithStatus(http.StatusInternalServerError)
+		token := bearerToken(c.Request)
+		if token == "" {
+			token, _ = c.Cookie("auth_token")
+		}
+		if token != "" {
+			userID, renewed, err := am.Authenticate(token)
+			if err == nil {
+				if renewed != "" {
+					SetAuthCookie(c, am, renewed)
+				}
+				logger.Info("user id", zap.String("user_id", userID), zap.String("path", c.Request.URL.Path))
+				c.Set(userIDKey, userID)
+				c.Next()
 				return
 			}
+			logger.Info("invalid auth token", zap.Error(err), zap.Bool("issue_new_user", o.provision))
+		}
 
-			http.SetCookie(c.Writer, &http.Cookie{
-				Name:     "auth_token",
-				Value:    token,
-				Path:     "/",
-				HttpOnly: true,
-				SameSite: http.SameSiteLaxMode,
-			})
-
-			c.Set(userIDKey, newID)
-			c.Next()
+		if o.provision && !provisionUser(c, am, logger) {
 			return
 		}
-		logger.Info("user id", zap.String("user_id", userID), zap.String("path", c.Request.URL.Path))
-
-		c.Set(userIDKey, userID)
 		c.Next()
 	}
 }
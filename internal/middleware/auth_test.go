@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	newManager := func(secret string, opts ...auth.Option) *auth.Manager {
+		am, err := auth.NewManager(secret, append(opts, auth.WithTTL(time.Hour), auth.WithClock(func() time.Time { return now }))...)
+		if err != nil {
+			t.Fatalf("auth manager: %v", err)
+		}
+		return am
+	}
+
+	send := func(am *auth.Manager, token string) (string, *http.Cookie) {
+		r := gin.New()
+		r.Use(AuthMiddleware(am, nil, nil, zap.NewNop()))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, c.GetString(userIDKey))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "auth_token" {
+				return w.Body.String(), c
+			}
+		}
+		return w.Body.String(), nil
+	}
+
+	am := newManager("secret")
+
+	userID, cookie := send(am, "")
+	assert.NotEmpty(t, userID)
+	if assert.NotNil(t, cookie) {
+		assert.Equal(t, int(time.Hour.Seconds()), cookie.MaxAge)
+		assert.True(t, cookie.HttpOnly)
+	}
+	token := cookie.Value
+
+	t.Run("fresh token keeps the user without a new cookie", func(t *testing.T) {
+		got, cookie := send(am, token)
+		assert.Equal(t, userID, got)
+		assert.Nil(t, cookie)
+	})
+
+	t.Run("token is renewed before expiry", func(t *testing.T) {
+		now = now.Add(45 * time.Minute)
+		got, cookie := send(am, token)
+		assert.Equal(t, userID, got)
+		if assert.NotNil(t, cookie) {
+			assert.NotEqual(t, token, cookie.Value)
+			token = cookie.Value
+		}
+
+		now = now.Add(45 * time.Minute)
+		got, _ = send(am, token)
+		assert.Equal(t, userID, got, "renewed token outlives the original one")
+	})
+
+	t.Run("rotated secret keeps the user", func(t *testing.T) {
+		rotated := newManager("new-secret", auth.WithPreviousSecrets("secret"))
+		got, cookie := send(rotated, token)
+		assert.Equal(t, userID, got)
+		assert.NotNil(t, cookie)
+	})
+
+	t.Run("invalid token issues a new user", func(t *testing.T) {
+		got, cookie := send(newManager("unrelated"), token)
+		assert.NotEmpty(t, got)
+		assert.NotEqual(t, userID, got)
+		assert.NotNil(t, cookie)
+	})
+}
+
+// auditEvents пересылает события аудита в канал.
+type auditEvents chan audit.Event
+
+func (e auditEvents) Notify(_ context.Context, event audit.Event) error {
+	e <- event
+	return nil
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	am, err := auth.NewManager("secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	store := storage.NewInMemoryStorage()
+	events := make(auditEvents, 10)
+	auditSvc := audit.NewService(zap.NewNop(), events)
+
+	newKey := func(scopes []string, mutate func(*storage.APIKey)) string {
+		id, key, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			t.Fatalf("generate api key: %v", err)
+		}
+		rec := storage.APIKey{ID: id, UserID: "owner", Hash: hash, Scopes: scopes, CreatedAt: time.Now()}
+		if mutate != nil {
+			mutate(&rec)
+		}
+		if err := store.CreateAPIKey(context.Background(), rec); err != nil {
+			t.Fatalf("create api key: %v", err)
+		}
+		return key
+	}
+
+	r := gin.New()
+	r.Use(AuthMiddleware(am, store, auditSvc, zap.NewNop()))
+	handle := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(userIDKey)+" "+APIKeyID(c))
+	}
+	r.GET("/api/user/urls", RequireScope(storage.ScopeRead), handle)
+	r.DELETE("/api/user/urls", RequireScope(storage.ScopeDelete), handle)
+	r.POST("/api/user/keys", RequireSession(), handle)
+	r.POST("/api/user/login", RequireSession(), handle)
+	r.POST("/public", handle)
+
+	send := func(method, path string, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	readKey := newKey([]string{storage.ScopeRead}, nil)
+
+	t.Run("key in X-API-Key resolves to the owner", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/user/urls", APIKeyHeader, readKey)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "owner ")
+		assert.Empty(t, w.Result().Cookies(), "no cookie is issued for api keys")
+
+		select {
+		case e := <-events:
+			assert.Equal(t, "api_key_use", e.Action)
+			assert.Equal(t, "owner", e.UserID)
+			assert.NotEmpty(t, e.APIKeyID)
+			assert.Equal(t, "GET /api/user/urls", e.URL)
+		case <-time.After(time.Second):
+			t.Fatal("api key usage is not audited")
+		}
+	})
+
+	t.Run("key in Authorization: Bearer", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/user/urls", "Authorization", "Bearer "+readKey)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "owner ")
+	})
+
+	t.Run("bearer JWT is not treated as a key", func(t *testing.T) {
+		token, err := am.GenerateToken("jwt-user")
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		w := send(http.MethodGet, "/api/user/urls", "Authorization", "Bearer "+token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "jwt-user ", w.Body.String())
+		assert.Empty(t, w.Result().Cookies(), "valid token does not issue a new user")
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"missing scope", http.MethodDelete, "/api/user/urls", readKey, http.StatusForbidden},
+		{"delete scope", http.MethodDelete, "/api/user/urls", newKey([]string{storage.ScopeDelete}, nil), http.StatusOK},
+		{"keys cannot manage keys", http.MethodPost, "/api/user/keys", newKey([]string{storage.ScopeCreate}, nil), http.StatusForbidden},
+		{"keys cannot log in", http.MethodPost, "/api/user/login", newKey([]string{storage.ScopeCreate}, nil), http.StatusForbidden},
+		{"public route has no scope", http.MethodPost, "/public", readKey, http.StatusOK},
+		{"unknown key", http.MethodGet, "/api/user/urls", auth.APIKeyPrefix + "0_unknown", http.StatusUnauthorized},
+		{"malformed key", http.MethodGet, "/api/user/urls", "garbage", http.StatusUnauthorized},
+		{"expired key", http.MethodGet, "/api/user/urls", newKey([]string{storage.ScopeRead}, func(k *storage.APIKey) {
+			k.ExpiresAt = time.Now().Add(-time.Minute)
+		}), http.StatusUnauthorized},
+		{"revoked key", http.MethodGet, "/api/user/urls", newKey([]string{storage.ScopeRead}, func(k *storage.APIKey) {
+			k.RevokedAt = time.Now()
+		}), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.method, tt.path, APIKeyHeader, tt.key)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want != http.StatusOK {
+				assert.Empty(t, w.Result().Cookies(), "rejected key does not create a user")
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Strict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	am, err := auth.NewManager("secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	r := gin.New()
+	r.Use(AuthMiddleware(am, nil, nil, zap.NewNop(), WithoutProvisioning()))
+	handle := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(userIDKey))
+	}
+	r.POST("/api/shorten", ProvisionUser(am, zap.NewNop()), handle)
+	r.GET("/api/user/urls", RequireUser(), handle)
+	r.GET("/public", handle)
+
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	token, err := am.GenerateToken("user-1")
+	assert.NoError(t, err)
+
+	t.Run("user-scoped route without token is rejected", func(t *testing.T) {
+		for _, token := range []string{"", "invalid"} {
+			w := send(http.MethodGet, "/api/user/urls", token)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Empty(t, w.Result().Cookies(), "no identity is issued")
+		}
+	})
+
+	t.Run("user-scoped route with token", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/user/urls", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user-1", w.Body.String())
+	})
+
+	t.Run("public route stays anonymous", func(t *testing.T) {
+		w := send(http.MethodGet, "/public", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("create route provisions an identity", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/shorten", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Body.String())
+		if assert.Len(t, w.Result().Cookies(), 1) {
+			userID, err := am.ParseToken(w.Result().Cookies()[0].Value, zap.NewNop())
+			assert.NoError(t, err)
+			assert.Equal(t, w.Body.String(), userID)
+		}
+
+		w = send(http.MethodPost, "/api/shorten", token)
+		assert.Equal(t, "user-1", w.Body.String(), "existing identity is kept")
+		assert.Empty(t, w.Result().Cookies())
+	})
+}
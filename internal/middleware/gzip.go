@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -21,6 +22,20 @@ func (g *gzipWriter) Write(data []byte) (int, error) {
 	return g.Writer.Write(data)
 }
 
+// Flush сбрасывает буфер gzip и отправляет накопленные данные клиенту.
+// Нужен потоковым ответам (NDJSON, SSE), иначе данные задерживаются в буфере компрессора.
+func (g *gzipWriter) Flush() {
+	if f, ok := g.Writer.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	g.ResponseWriter.Flush()
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
 // GzipMiddleware возвращает Gin middleware для сжатия и разжатия HTTP-трафика.
 //
 // Поведение:
@@ -40,8 +55,7 @@ func GzipMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			reader, err := gzip.NewReader(c.Request.Body)
 			if err != nil {
 				logger.Error("Failed to create gzip reader", zap.Error(err))
-				c.String(http.StatusBadRequest, "invalid gzip body")
-				c.Abort()
+				abortWithProblem(c, problem.New(problem.InvalidRequest, "invalid gzip body"))
 				return
 			}
 			gr = reader
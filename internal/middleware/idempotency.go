@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader — заголовок с ключом идемпотентности запроса.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader — заголовок ответа, повторённого по ключу идемпотентности.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyKeyPattern ограничивает ключ печатными ASCII-символами.
+var idempotencyKeyPattern = regexp.MustCompile(`^[\x21-\x7E]{1,255}$`)
+
+// captureWriter дублирует тело ответа в буфер, чтобы сохранить его для повторов.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write записывает данные в ответ и в буфер.
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString записывает строку в ответ и в буфер.
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency возвращает Gin middleware, поддерживающий заголовок Idempotency-Key
+// на эндпоинтах создания ссылок.
+//
+// Поведение:
+//  1. Запрос без заголовка обрабатывается как обычно; некорректный ключ — 400 Bad Request.
+//  2. Ключ уникален в пределах пользователя (userID из AuthMiddleware). Первый запрос
+//     занимает ключ на ttl и сохраняет ответ: статус, Content-Type и тело.
+//  3. Повтор с тем же ключом и тем же телом получает сохранённый ответ байт в байт
+//     с заголовком Idempotent-Replayed: true; хендлер не вызывается.
+//  4. Повтор с другим методом, путём или телом — 422 Unprocessable Entity,
+//     повтор во время выполнения первого запроса — 409 Conflict.
+//  5. Ответы с ошибкой (problem.Abort) не сохраняются: ключ освобождается,
+//     и клиент может повторить запрос с тем же ключом.
+//
+// Middleware подключается к маршруту после AuthMiddleware и GzipMiddleware,
+// поэтому хеш считается по разжатому телу, а сохраняется несжатый ответ.
+func Idempotency(store storage.Storage, ttl time.Duration, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !idempotencyKeyPattern.MatchString(key) {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "invalid Idempotency-Key header"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Abort(c, problem.New(problem.InvalidRequest, "cannot read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetString(userIDKey)
+		rec := storage.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash(c.Request, body),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		existing, err := store.ClaimIdempotencyKey(c.Request.Context(), rec)
+		if err != nil {
+			problem.Abort(c, problem.Wrap(problem.Internal, err, ""))
+			return
+		}
+		if existing != nil {
+			replayIdempotent(c, existing, rec.RequestHash)
+			return
+		}
+
+		// Ключ освобождается и после отмены запроса клиентом.
+		ctx := context.WithoutCancel(c.Request.Context())
+		w := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+
+		if len(c.Errors) > 0 || w.Status() >= http.StatusInternalServerError {
+			if err := store.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+				logger.Error("failed to release idempotency key", zap.String("key", key), zap.Error(err))
+			}
+			return
+		}
+
+		rec.Status = w.Status()
+		rec.ContentType = w.Header().Get("Content-Type")
+		rec.Body = w.body.Bytes()
+		if err := store.CompleteIdempotencyKey(ctx, rec); err != nil {
+			logger.Error("failed to store idempotent response", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// replayIdempotent отвечает на повтор запроса с занятым ключом.
+func replayIdempotent(c *gin.Context, rec *storage.IdempotencyRecord, hash string) {
+	if rec.RequestHash != hash {
+		problem.Abort(c, problem.New(problem.KeyReused, ""))
+		return
+	}
+	if !rec.Completed {
+		problem.Abort(c, problem.New(problem.KeyInProgress, ""))
+		return
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(rec.Status, rec.ContentType, rec.Body)
+	c.Abort()
+}
+
+// requestHash возвращает хеш метода, пути и тела запроса.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
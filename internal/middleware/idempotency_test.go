@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := storage.NewInMemoryStorage()
+	calls := 0
+	fail := false
+
+	r := gin.New()
+	r.Use(Problems(zap.NewNop()), func(c *gin.Context) {
+		c.Set(userIDKey, c.GetHeader("X-User"))
+		c.Next()
+	})
+	r.POST("/create", Idempotency(store, time.Hour, zap.NewNop()), func(c *gin.Context) {
+		calls++
+		if fail {
+			problem.Abort(c, problem.New(problem.Internal, ""))
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	send := func(user, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	problemType := func(w *httptest.ResponseRecorder) string {
+		var p problem.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		return p.Type
+	}
+
+	first := send("u1", "key-1", `{"url":"https://a.example"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, `{"call":1}`, first.Body.String())
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	t.Run("retry replays stored response", func(t *testing.T) {
+		w := send("u1", "key-1", `{"url":"https://a.example"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, first.Body.Bytes(), w.Body.Bytes())
+		assert.Equal(t, first.Header().Get("Content-Type"), w.Header().Get("Content-Type"))
+		assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("different body is rejected", func(t *testing.T) {
+		w := send("u1", "key-1", `{"url":"https://b.example"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, "urn:shortener:problem:idempotency-key-reused", problemType(w))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("keys are scoped per user", func(t *testing.T) {
+		w := send("u2", "key-1", `{"url":"https://a.example"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("requests without key are not stored", func(t *testing.T) {
+		send("u1", "", `{}`)
+		w := send("u1", "", `{}`)
+		assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		w := send("u1", strings.Repeat("k", 256), `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("failed request releases key", func(t *testing.T) {
+		fail = true
+		w := send("u1", "key-2", `{}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		fail = false
+		w = send("u1", "key-2", `{}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, 6, calls)
+	})
+
+	t.Run("request in progress", func(t *testing.T) {
+		hash := requestHash(httptest.NewRequest(http.MethodPost, "/create", nil), []byte(`{}`))
+		_, err := store.ClaimIdempotencyKey(t.Context(), storage.IdempotencyRecord{
+			UserID: "u1", Key: "key-3", RequestHash: hash, ExpiresAt: time.Now().Add(time.Hour),
+		})
+		assert.NoError(t, err)
+
+		w := send("u1", "key-3", `{}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "urn:shortener:problem:idempotency-key-in-progress", problemType(w))
+		assert.Equal(t, 6, calls)
+	})
+}
@@ -16,6 +16,8 @@ import (
 // - Размер ответа в байтах
 // - Задержку обработки запроса
 // - IP клиента
+// - Идентификатор запроса (см. RequestID)
+// - Ошибки, добавленные хендлером через c.Error (если есть)
 //
 // Logger используется для логирования через zap.Logger.
 //
@@ -35,13 +37,20 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 			size = 0
 		}
 
-		logger.Info("Request",
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.Int("status", status),
 			zap.Int("size", size),
 			zap.Duration("latency", latency),
 			zap.String("ip", c.ClientIP()),
-		)
+		}
+		if id := GetRequestID(c); id != "" {
+			fields = append(fields, zap.String("request_id", id))
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+		logger.Info("Request", fields...)
 	}
 }
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// ginParamPattern находит параметры пути gin (":id", "*rest").
+var ginParamPattern = regexp.MustCompile(`[:*]([^/]+)`)
+
+// OpenAPIPath переводит шаблон маршрута gin в шаблон пути OpenAPI:
+// "/api/user/urls/:id/rules" → "/api/user/urls/{id}/rules", "/:id/*rest" → "/{id}/{rest}".
+func OpenAPIPath(ginPath string) string {
+	return ginParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// ValidateRequest возвращает Gin middleware, проверяющий JSON-тела запросов по документу OpenAPI.
+//
+// Поведение:
+//  1. Операция ищется по шаблону маршрута gin (c.FullPath()) и методу запроса;
+//     маршруты без описания в документе и операции без JSON-тела пропускаются.
+//  2. Проверяются только запросы с Content-Type application/json: текстовые, потоковые
+//     (NDJSON, CSV) и form-тела разбирают сами хендлеры.
+//  3. Тело читается целиком и возвращается в c.Request.Body для хендлера.
+//  4. При ошибке запрос прерывается с 400 Bad Request через problem.Abort: тип invalid-json
+//     для неразбираемого тела, иначе invalid-request с JSON Pointer поля и причиной
+//     в detail, например "/max_clicks: number must be at least 0".
+//
+// Middleware нужно подключать после GzipMiddleware, чтобы проверялось разжатое тело.
+func ValidateRequest(doc *openapi3.T) gin.HandlerFunc {
+	opts := &openapi3filter.Options{SkipSettingDefaults: true}
+
+	return func(c *gin.Context) {
+		body := jsonRequestBody(doc, c)
+		if body == nil {
+			c.Next()
+			return
+		}
+
+		err := openapi3filter.ValidateRequestBody(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request: c.Request,
+			Options: opts,
+		}, body)
+		if err != nil {
+			problem.Abort(c, validationProblem(err))
+			return
+		}
+		c.Next()
+	}
+}
+
+// jsonRequestBody возвращает описание тела операции, если запрос нужно проверять.
+func jsonRequestBody(doc *openapi3.T, c *gin.Context) *openapi3.RequestBody {
+	if c.FullPath() == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return nil
+	}
+
+	item := doc.Paths.Find(OpenAPIPath(c.FullPath()))
+	if item == nil {
+		return nil
+	}
+	op := item.GetOperation(c.Request.Method)
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	if op.RequestBody.Value.Content.Get("application/json") == nil {
+		return nil
+	}
+	return op.RequestBody.Value
+}
+
+// validationProblem преобразует ошибку проверки тела запроса в ошибку API.
+func validationProblem(err error) *problem.Error {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		pointer := "/" + strings.Join(schemaErr.JSONPointer(), "/")
+		return problem.New(problem.InvalidRequest, pointer+": "+schemaErr.Reason)
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		if errors.Is(reqErr.Err, openapi3filter.ErrInvalidRequired) {
+			return problem.New(problem.InvalidRequest, "request body is required")
+		}
+		if reqErr.Err != nil {
+			return problem.New(problem.InvalidJSON, "")
+		}
+	}
+	return problem.New(problem.InvalidRequest, "invalid request body")
+}
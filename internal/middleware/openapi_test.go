@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/items/{id}": {
+      "put": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {
+            "type": "object",
+            "required": ["name"],
+            "properties": {"name": {"type": "string", "minLength": 1}, "count": {"type": "integer", "minimum": 0}}
+          }}}
+        },
+        "responses": {"200": {"description": "ok"}}
+      }
+    }
+  }
+}`
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := map[string]string{
+		"/":                        "/",
+		"/ping":                    "/ping",
+		"/:id":                     "/{id}",
+		"/:id/*rest":               "/{id}/{rest}",
+		"/api/user/urls/:id/rules": "/api/user/urls/{id}/rules",
+		"/api/expand/:id":          "/api/expand/{id}",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, OpenAPIPath(in), in)
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r := gin.New()
+	r.Use(Problems(zap.NewNop()), ValidateRequest(doc))
+	r.PUT("/items/:id", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	r.PUT("/undocumented", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "valid body reaches handler", path: "/items/1", contentType: "application/json", body: `{"name":"a","count":2}`, wantStatus: http.StatusOK, wantBody: `{"name":"a","count":2}`},
+		{name: "missing property", path: "/items/1", contentType: "application/json", body: `{"count":2}`, wantStatus: http.StatusBadRequest, wantBody: `/name: property "name" is missing`},
+		{name: "constraint violation", path: "/items/1", contentType: "application/json", body: `{"name":"a","count":-1}`, wantStatus: http.StatusBadRequest, wantBody: `/count: number must be at least 0`},
+		{name: "malformed json", path: "/items/1", contentType: "application/json", body: `{"name":`, wantStatus: http.StatusBadRequest, wantBody: ``},
+		{name: "empty body", path: "/items/1", contentType: "application/json", body: ``, wantStatus: http.StatusBadRequest, wantBody: `request body is required`},
+		{name: "non-json body is skipped", path: "/items/1", contentType: "text/plain", body: `anything`, wantStatus: http.StatusOK, wantBody: `anything`},
+		{name: "undocumented route is skipped", path: "/undocumented", contentType: "application/json", body: `{`, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusBadRequest {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+				return
+			}
+			var p problem.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, tt.wantBody, p.Detail)
+		})
+	}
+}
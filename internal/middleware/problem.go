@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// RequestIDHeader — заголовок с идентификатором запроса.
+	RequestIDHeader = "X-Request-ID"
+	// requestIDKey — ключ контекста Gin с идентификатором запроса.
+	requestIDKey = "requestID"
+)
+
+// requestIDPattern ограничивает идентификатор, принятый от клиента, чтобы он безопасно попадал в логи.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID возвращает Gin middleware, присваивающий запросу идентификатор.
+//
+// Идентификатор берётся из заголовка X-Request-ID, если клиент передал корректное
+// значение, иначе генерируется UUID. Он возвращается в заголовке ответа X-Request-ID,
+// пишется в лог запроса и попадает в поле request_id ответов об ошибках.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID возвращает идентификатор запроса, присвоенный RequestID.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Problems возвращает Gin middleware, отрисовывающий ошибки хендлеров в формате RFC 7807.
+//
+// Поведение:
+//  1. После обработки запроса берёт последнюю ошибку из c.Errors (см. problem.Abort).
+//  2. Если ответ уже начат (например, оборван поток экспорта), ничего не пишет.
+//  3. Отдаёт application/problem+json с полями type, title, status, detail,
+//     instance (путь запроса) и request_id; для эндпоинтов, вызвавших problem.UseText, —
+//     text/plain с detail (или title).
+//  4. Ошибки, не являющиеся *problem.Error, отдаются как internal без подробностей;
+//     ошибки со статусом 5xx логируются с внутренней причиной.
+//
+// Middleware нужно подключать после GzipMiddleware, чтобы ответ проходил через сжатие.
+func Problems(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		p := problem.From(err)
+		if p.Status >= http.StatusInternalServerError {
+			logger.Error("request failed",
+				zap.String("request_id", GetRequestID(c)),
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err))
+		}
+		writeProblem(c, p)
+	}
+}
+
+// abortWithProblem прерывает запрос ошибкой и сразу отрисовывает её;
+// используется middleware, которые выполняются до Problems.
+func abortWithProblem(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+	writeProblem(c, problem.From(err))
+}
+
+// writeProblem пишет описание ошибки в ответ.
+func writeProblem(c *gin.Context, p problem.Problem) {
+	if problem.WantsText(c) {
+		msg := p.Detail
+		if msg == "" {
+			msg = p.Title
+		}
+		c.String(p.Status, msg)
+		return
+	}
+
+	p.Instance = c.Request.URL.Path
+	p.RequestID = GetRequestID(c)
+	c.Header("Content-Type", problem.ContentType)
+	c.JSON(p.Status, p)
+}
+
+// NoRoute возвращает Gin handler для неизвестных маршрутов и методов.
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		problem.Abort(c, problem.New(problem.RouteNotFound, ""))
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{name: "client id is kept", incoming: "req-42.a_b", wantSame: true},
+		{name: "missing id is generated", incoming: ""},
+		{name: "unsafe id is replaced", incoming: "bad id\nwith newline"},
+		{name: "too long id is replaced", incoming: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			r := gin.New()
+			r.Use(RequestID())
+			r.GET("/", func(c *gin.Context) {
+				seen = GetRequestID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			assert.NotEmpty(t, got)
+			assert.Equal(t, got, seen)
+			if tt.wantSame {
+				assert.Equal(t, tt.incoming, got)
+			} else {
+				assert.NotEqual(t, tt.incoming, got)
+			}
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		handler     gin.HandlerFunc
+		wantStatus  int
+		wantType    string
+		wantDetail  string
+		wantLogged  bool
+		wantText    string
+		contentType string
+	}{
+		{
+			name: "typed error",
+			handler: func(c *gin.Context) {
+				problem.Abort(c, problem.New(problem.NotFound, "no such link"))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantType:    "urn:shortener:problem:not-found",
+			wantDetail:  "no such link",
+			contentType: problem.ContentType,
+		},
+		{
+			name: "internal cause is logged but not exposed",
+			handler: func(c *gin.Context) {
+				problem.Abort(c, problem.Wrap(problem.Internal, errors.New("pq: connection refused"), ""))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantType:    "urn:shortener:problem:internal",
+			wantLogged:  true,
+			contentType: problem.ContentType,
+		},
+		{
+			name: "untyped error is internal",
+			handler: func(c *gin.Context) {
+				problem.Abort(c, errors.New("secret details"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantType:    "urn:shortener:problem:internal",
+			wantLogged:  true,
+			contentType: problem.ContentType,
+		},
+		{
+			name: "text mode",
+			handler: func(c *gin.Context) {
+				problem.UseText(c)
+				problem.Abort(c, problem.New(problem.InvalidRequest, "empty body"))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantText:    "empty body",
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name: "text mode falls back to title",
+			handler: func(c *gin.Context) {
+				problem.UseText(c)
+				problem.Abort(c, problem.New(problem.Gone, ""))
+			},
+			wantStatus:  http.StatusGone,
+			wantText:    problem.Gone.Title,
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name: "written response is left untouched",
+			handler: func(c *gin.Context) {
+				c.String(http.StatusOK, "partial")
+				problem.Abort(c, errors.New("stream broken"))
+			},
+			wantStatus:  http.StatusOK,
+			wantText:    "partial",
+			contentType: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.InfoLevel)
+
+			r := gin.New()
+			r.Use(RequestID(), Problems(zap.New(core)))
+			r.GET("/links/:id", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/links/abc", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.NotContains(t, w.Body.String(), "pq:")
+			assert.NotContains(t, w.Body.String(), "secret")
+			assert.Equal(t, tt.wantLogged, strings.Contains(buf.String(), `"request_id":"req-1"`))
+
+			if tt.wantType == "" {
+				assert.Equal(t, tt.wantText, w.Body.String())
+				return
+			}
+			var p problem.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, tt.wantType, p.Type)
+			assert.Equal(t, tt.wantStatus, p.Status)
+			assert.Equal(t, tt.wantDetail, p.Detail)
+			assert.NotEmpty(t, p.Title)
+			assert.Equal(t, "/links/abc", p.Instance)
+			assert.Equal(t, "req-1", p.RequestID)
+		})
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Problems(zap.NewNop()))
+	r.NoRoute(NoRoute())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var p problem.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "urn:shortener:problem:route-not-found", p.Type)
+}
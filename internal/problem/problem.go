@@ -0,0 +1,143 @@
+// Package problem описывает ошибки HTTP API в формате RFC 7807 (application/problem+json).
+//
+// Хендлеры прерывают запрос через Abort, передавая *Error с одним из типов ниже;
+// тело ответа формирует middleware.Problems. Внутренняя причина (Error.Err) только
+// логируется и никогда не попадает в ответ.
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType — MIME-тип ответа с описанием ошибки.
+const ContentType = "application/problem+json"
+
+// typePrefix — префикс стабильных идентификаторов типов ошибок.
+const typePrefix = "urn:shortener:problem:"
+
+// Type — вид ошибки: стабильный машиночитаемый идентификатор, заголовок и HTTP-статус.
+type Type struct {
+	// URI — значение поля type, например "urn:shortener:problem:not-found".
+	URI string
+	// Title — краткое описание вида ошибки, одинаковое для всех её экземпляров.
+	Title string
+	// Status — HTTP-статус ответа.
+	Status int
+}
+
+func newType(slug, title string, status int) *Type {
+	return &Type{URI: typePrefix + slug, Title: title, Status: status}
+}
+
+// Типы ошибок API.
+var (
+	InvalidRequest     = newType("invalid-request", "Invalid request", http.StatusBadRequest)
+	InvalidJSON        = newType("invalid-json", "Request body is not valid JSON", http.StatusBadRequest)
+	Unauthorized       = newType("unauthorized", "User is not identified", http.StatusUnauthorized)
+	InvalidAPIKey      = newType("invalid-api-key", "API key is invalid, expired or revoked", http.StatusUnauthorized)
+	InsufficientScope  = newType("insufficient-scope", "API key does not allow this operation", http.StatusForbidden)
+	InvalidCredentials = newType("invalid-credentials", "Invalid username or password", http.StatusUnauthorized)
+	PasswordRequired   = newType("password-required", "Link is password protected", http.StatusUnauthorized)
+	InvalidPassword    = newType("invalid-password", "Invalid link password", http.StatusUnauthorized)
+	NotFound           = newType("not-found", "Link not found", http.StatusNotFound)
+	RouteNotFound      = newType("route-not-found", "Route not found", http.StatusNotFound)
+	APIKeyNotFound     = newType("api-key-not-found", "API key not found", http.StatusNotFound)
+	LinkExists         = newType("link-exists", "URL is already shortened", http.StatusConflict)
+	OptionsNotApplied  = newType("link-options-not-applied", "URL is already shortened, link options were not applied", http.StatusConflict)
+	UsernameTaken      = newType("username-taken", "Username is already taken", http.StatusConflict)
+	Gone               = newType("gone", "Link is deleted or exhausted", http.StatusGone)
+	PreconditionFail   = newType("precondition-failed", "Link was modified since it was read", http.StatusPreconditionFailed)
+	TooManyAttempts    = newType("too-many-attempts", "Too many password attempts", http.StatusTooManyRequests)
+	KeyInProgress      = newType("idempotency-key-in-progress", "Request with this Idempotency-Key is in progress", http.StatusConflict)
+	KeyReused          = newType("idempotency-key-reused", "Idempotency-Key was used with a different request", http.StatusUnprocessableEntity)
+	Internal           = newType("internal", "Internal server error", http.StatusInternalServerError)
+	Unavailable        = newType("storage-unavailable", "Storage is unavailable", http.StatusInternalServerError)
+)
+
+// Error — ошибка API определённого типа.
+type Error struct {
+	// Type — вид ошибки.
+	Type *Type
+	// Detail — пояснение для клиента; не должно содержать внутренних подробностей.
+	Detail string
+	// Err — внутренняя причина для логов.
+	Err error
+}
+
+// New создаёт ошибку типа t с пояснением detail.
+func New(t *Type, detail string) *Error {
+	return &Error{Type: t, Detail: detail}
+}
+
+// Wrap создаёт ошибку типа t, сохраняя внутреннюю причину err для логов.
+func Wrap(t *Type, err error, detail string) *Error {
+	return &Error{Type: t, Detail: detail, Err: err}
+}
+
+// Error возвращает текст ошибки вместе с внутренней причиной.
+func (e *Error) Error() string {
+	msg := e.Type.Title
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap возвращает внутреннюю причину.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Problem — тело ответа application/problem+json.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// From строит тело ответа по ошибке. Ошибка, не являющаяся *Error,
+// считается внутренней: её текст в ответ не попадает.
+func From(err error) Problem {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Type: Internal}
+	}
+	return Problem{
+		Type:   e.Type.URI,
+		Title:  e.Type.Title,
+		Status: e.Type.Status,
+		Detail: e.Detail,
+	}
+}
+
+// Abort прерывает обработку запроса ошибкой err: выставляет статус ответа
+// и добавляет ошибку в c.Errors, откуда её отрисовывает middleware.Problems.
+// Заголовки не отправляются, чтобы middleware мог выставить Content-Type.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Status(From(err).Status)
+	c.Abort()
+}
+
+// textKey — ключ контекста, включающий текстовый формат ошибок.
+const textKey = "problem.text"
+
+// UseText включает для запроса ответ об ошибке в text/plain вместо problem+json.
+// Нужен эндпоинтам, которые принимают и отдают простой текст.
+func UseText(c *gin.Context) {
+	c.Set(textKey, true)
+}
+
+// WantsText сообщает, что для запроса включён текстовый формат ошибок.
+func WantsText(c *gin.Context) bool {
+	return c.GetBool(textKey)
+}
@@ -0,0 +1,55 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("db down")
+
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "typed error",
+			err:  New(InvalidRequest, "url is empty"),
+			want: Problem{Type: "urn:shortener:problem:invalid-request", Title: "Invalid request", Status: http.StatusBadRequest, Detail: "url is empty"},
+		},
+		{
+			name: "wrapped cause is hidden",
+			err:  Wrap(Unavailable, cause, ""),
+			want: Problem{Type: "urn:shortener:problem:storage-unavailable", Title: "Storage is unavailable", Status: http.StatusInternalServerError},
+		},
+		{
+			name: "typed error inside fmt wrap",
+			err:  fmt.Errorf("handler: %w", New(NotFound, "")),
+			want: Problem{Type: "urn:shortener:problem:not-found", Title: "Link not found", Status: http.StatusNotFound},
+		},
+		{
+			name: "plain error is internal",
+			err:  cause,
+			want: Problem{Type: "urn:shortener:problem:internal", Title: "Internal server error", Status: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, From(tt.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("db down")
+	err := Wrap(Internal, cause, "failed to save")
+
+	assert.Equal(t, "Internal server error: failed to save: db down", err.Error())
+	assert.ErrorIs(t, err, cause)
+}
@@ -0,0 +1,120 @@
+// Package router собирает HTTP API сервиса: middleware и маршруты Gin.
+package router
+
+import (
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/api"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/config"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service/rules"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// New создает и настраивает маршрутизатор Gin.
+// cfg — конфигурация приложения.
+// store — интерфейс хранилища.
+// am — менеджер авторизации.
+// deleter — сервис Deleter для удаления URL.
+// auditSvc — сервис аудита.
+// broker — брокер живых событий для SSE.
+// limiter — общий ограничитель попыток ввода пароля ссылки.
+// loginLimiter — ограничитель попыток входа в учётную запись.
+// countries — определение страны для правил редиректа (может быть nil).
+// spec — документ OpenAPI для проверки тел запросов.
+// logger — Zap логгер.
+// Возвращает *gin.Engine.
+func New(
+	cfg *config.Config,
+	store storage.Storage,
+	am *auth.Manager,
+	deleter *service.Deleter,
+	auditSvc *audit.Service,
+	broker *audit.Broker,
+	limiter *service.AttemptLimiter,
+	loginLimiter *service.LoginLimiter,
+	countries rules.CountryResolver,
+	spec *openapi3.T,
+	logger *zap.Logger) *gin.Engine {
+
+	// В строгом режиме личность выдаётся только на маршрутах создания ссылок (provision),
+	// а пользовательские маршруты (requireUser) без валидного токена отвечают 401.
+	var authOpts []middleware.AuthOption
+	if cfg.AuthStrict {
+		authOpts = append(authOpts, middleware.WithoutProvisioning())
+	}
+	provision := middleware.ProvisionUser(am, logger)
+	requireUser := middleware.RequireUser()
+	// Области действия API-ключа задаются на маршрутах; публичные маршруты их не проверяют,
+	// а маршруты учётной записи и управления ключами доступны только по сессии.
+	canCreate := middleware.RequireScope(storage.ScopeCreate)
+	canRead := middleware.RequireScope(storage.ScopeRead)
+	canDelete := middleware.RequireScope(storage.ScopeDelete)
+	session := middleware.RequireSession()
+
+	r := gin.New()
+	r.Use(
+		middleware.RequestID(),
+		middleware.Logger(logger),
+		middleware.GzipMiddleware(logger),
+		middleware.Problems(logger),
+		middleware.AuthMiddleware(am, store, auditSvc, logger, authOpts...),
+		middleware.ValidateRequest(spec),
+	)
+	r.NoRoute(middleware.NoRoute())
+	r.NoMethod(middleware.NoRoute())
+
+	r.POST("/", provision, canCreate, handler.PostRawURL(store, cfg.ShortenAddress, auditSvc))
+	if cfg.RedirectCode != 0 && !handler.ValidRedirectCode(cfg.RedirectCode) {
+		logger.Warn("Invalid default redirect code, using 307", zap.Int("code", cfg.RedirectCode))
+	}
+	redirectOpts := []handler.RedirectOption{handler.WithDefaultRedirectCode(cfg.RedirectCode)}
+	if countries != nil {
+		redirectOpts = append(redirectOpts, handler.WithCountryResolver(countries))
+	}
+
+	follow := handler.GetIDURL(store, auditSvc, limiter, redirectOpts...)
+	r.GET("/:id", follow)
+	r.POST("/:id", follow)
+	r.GET("/:id/*rest", follow)
+	r.POST("/:id/*rest", follow)
+	idempotent := middleware.Idempotency(store, cfg.IdempotencyTTL, logger)
+	r.POST("/api/shorten", provision, canCreate, idempotent, handler.PostJSONURL(store, cfg.ShortenAddress, auditSvc))
+	r.GET("/ping", handler.PingHandler(store))
+	r.POST("/api/shorten/batch", provision, canCreate, idempotent, handler.PostBatchURL(store, cfg.ShortenAddress))
+	r.POST("/api/shorten/import", provision, canCreate, handler.PostImportURL(store, cfg.ShortenAddress))
+	r.GET("/api/user/urls", requireUser, canRead, handler.GetUserURLs(store, cfg.ShortenAddress))
+	r.GET("/api/user/urls/export", requireUser, canRead, handler.GetUserURLsExport(store, cfg.ShortenAddress))
+	r.GET("/api/user/events", requireUser, canRead, handler.GetUserEvents(broker))
+	r.DELETE("/api/user/urls", requireUser, canDelete, handler.DeleteUserURLs(deleter))
+	r.GET("/api/qr/:id", handler.GetQRCode(store, cfg.ShortenAddress))
+	r.GET("/api/expand/:id", handler.GetExpand(store, cfg.ShortenAddress, limiter))
+	r.GET("/api/lookup", handler.GetLookup(store, cfg.ShortenAddress))
+	r.GET("/api/user/urls/:id/rules", requireUser, canRead, handler.GetLinkRules(store))
+	r.PUT("/api/user/urls/:id/rules", requireUser, canCreate, handler.PutLinkRules(store))
+	r.DELETE("/api/user/urls/:id/rules", requireUser, canDelete, handler.DeleteLinkRules(store))
+	r.GET("/api/user/urls/:id/destinations", requireUser, canRead, handler.GetLinkDestinations(store))
+	r.PUT("/api/user/urls/:id/destinations", requireUser, canCreate, handler.PutLinkDestinations(store))
+	r.DELETE("/api/user/urls/:id/destinations", requireUser, canDelete, handler.DeleteLinkDestinations(store))
+	r.GET("/api/v2/links", requireUser, canRead, handler.ListLinks(store, cfg.ShortenAddress))
+	r.POST("/api/v2/links", provision, canCreate, idempotent, handler.CreateLink(store, cfg.ShortenAddress, auditSvc))
+	r.GET("/api/v2/links/:id", requireUser, canRead, handler.GetLink(store, cfg.ShortenAddress))
+	r.PATCH("/api/v2/links/:id", requireUser, canCreate, handler.PatchLink(store, cfg.ShortenAddress))
+	r.DELETE("/api/v2/links/:id", requireUser, canDelete, handler.DeleteLink(store, cfg.ShortenAddress, auditSvc))
+	r.POST(handler.RegisterPath, session, handler.Register(store, am, auditSvc))
+	r.POST(handler.LoginPath, session, handler.Login(store, am, auditSvc, loginLimiter))
+	r.POST(handler.LogoutPath, session, handler.Logout())
+	r.POST(handler.APIKeysPath, session, requireUser, handler.CreateAPIKey(store, auditSvc))
+	r.GET(handler.APIKeysPath, session, requireUser, handler.ListAPIKeys(store))
+	r.DELETE(handler.APIKeysPath+"/:id", session, requireUser, handler.RevokeAPIKey(store, auditSvc))
+	r.GET(handler.JWKSPath, handler.GetJWKS(am))
+	r.GET("/api/openapi.json", handler.GetOpenAPISpec(api.OpenAPI))
+	r.GET("/api/docs", handler.GetAPIDocs("/api/openapi.json"))
+	return r
+}
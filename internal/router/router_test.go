@@ -0,0 +1,513 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/api"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/config"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/router"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testRouter(t *testing.T, opts ...func(*config.Config)) *gin.Engine {
+	t.Helper()
+	return testRouterWithStore(t, storage.NewInMemoryStorage(), opts...)
+}
+
+// testRouterWithStore собирает router.New поверх переданного хранилища.
+func testRouterWithStore(t *testing.T, store storage.Storage, opts ...func(*config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	spec, err := api.LoadOpenAPI()
+	if err != nil {
+		t.Fatalf("load openapi: %v", err)
+	}
+
+	logger := zap.NewNop()
+	am, err := auth.NewManager("test-secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	deleter := service.NewDeleter(store.MarkDeleted)
+	t.Cleanup(deleter.Close)
+
+	cfg := &config.Config{ShortenAddress: "http://localhost:8080", RedirectCode: http.StatusTemporaryRedirect, IdempotencyTTL: time.Hour}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return router.New(cfg, store, am, deleter,
+		audit.NewService(logger), audit.NewBroker(0), service.NewPasswordLimiter(), service.NewLoginLimiter(), nil, spec, logger)
+}
+
+// TestRoutesMatchOpenAPI падает, если маршруты router.New расходятся с api/openapi.json.
+// TestRouter_V1DuplicateCreate фиксирует ответы v1-эндпоинтов создания на повтор URL:
+// хранилища в памяти и в файле возвращают существующую ссылку с 201, как до API v2.
+func TestRouter_V1DuplicateCreate(t *testing.T) {
+	fileStore, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "storage.json"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("file storage: %v", err)
+	}
+	t.Cleanup(func() { _ = fileStore.Close() })
+
+	backends := map[string]storage.Storage{
+		"memory": storage.NewInMemoryStorage(),
+		"file":   fileStore,
+	}
+	for name, store := range backends {
+		t.Run(name, func(t *testing.T) {
+			r := testRouterWithStore(t, store)
+
+			raw := func(u string) (int, string) {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(u))
+				req.Header.Set("Content-Type", "text/plain")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code, w.Body.String()
+			}
+			shorten := func(u string) (int, string) {
+				req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"`+u+`"}`))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				var resp struct {
+					Result string `json:"result"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &resp)
+				return w.Code, resp.Result
+			}
+
+			code, first := raw("https://example.com/raw")
+			assert.Equal(t, http.StatusCreated, code)
+			code, again := raw("https://example.com/raw")
+			assert.Equal(t, http.StatusCreated, code)
+			assert.Equal(t, first, again)
+			code, viaJSON := shorten("https://example.com/raw")
+			assert.Equal(t, http.StatusCreated, code)
+			assert.Equal(t, first, viaJSON)
+
+			code, first = shorten("https://example.com/json")
+			assert.Equal(t, http.StatusCreated, code)
+			code, again = shorten("https://example.com/json")
+			assert.Equal(t, http.StatusCreated, code)
+			assert.Equal(t, first, again)
+			code, viaRaw := raw("https://example.com/json")
+			assert.Equal(t, http.StatusCreated, code)
+			assert.Equal(t, first, viaRaw)
+		})
+	}
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	r := testRouter(t)
+	spec, err := api.LoadOpenAPI()
+	if err != nil {
+		t.Fatalf("load openapi: %v", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+middleware.OpenAPIPath(route.Path)] = true
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var undocumented, unregistered []string
+	for op := range registered {
+		if !documented[op] {
+			undocumented = append(undocumented, op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			unregistered = append(unregistered, op)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unregistered)
+
+	assert.Empty(t, undocumented, "routes missing from api/openapi.json")
+	assert.Empty(t, unregistered, "operations in api/openapi.json without a route")
+}
+
+func TestRouter_ValidatesRequestBodies(t *testing.T) {
+	r := testRouter(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantType    string
+		wantDetail  string
+	}{
+		{
+			name:        "valid shorten",
+			method:      http.MethodPost,
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":"https://example.com"}`,
+			wantStatus:  http.StatusCreated,
+		},
+		{
+			name:        "negative max_clicks",
+			method:      http.MethodPost,
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":"https://example.com/limited","max_clicks":-1}`,
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "urn:shortener:problem:invalid-request",
+			wantDetail:  "/max_clicks: number must be at least 0",
+		},
+		{
+			name:        "missing url",
+			method:      http.MethodPost,
+			path:        "/api/shorten",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"password":"secret"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "urn:shortener:problem:invalid-request",
+			wantDetail:  `/url: property "url" is missing`,
+		},
+		{
+			name:        "malformed json",
+			method:      http.MethodPost,
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":`,
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "urn:shortener:problem:invalid-json",
+		},
+		{
+			name:        "empty batch",
+			method:      http.MethodPost,
+			path:        "/api/shorten/batch",
+			contentType: "application/json",
+			body:        `[]`,
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "urn:shortener:problem:invalid-request",
+			wantDetail:  "/: minimum number of items is 1",
+		},
+		{
+			name:        "wrong type in rules",
+			method:      http.MethodPut,
+			path:        "/api/user/urls/abc/rules",
+			contentType: "application/json",
+			body:        `[{"url":42}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "urn:shortener:problem:invalid-request",
+			wantDetail:  "/0/url: value must be a string",
+		},
+		{
+			name:        "text body is left to handler",
+			method:      http.MethodPost,
+			path:        "/",
+			contentType: "text/plain",
+			body:        "https://example.com/text",
+			wantStatus:  http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantType == "" {
+				return
+			}
+			var p problem.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantType, p.Type)
+			assert.Equal(t, tt.wantDetail, p.Detail)
+			assert.Equal(t, tt.wantStatus, p.Status)
+		})
+	}
+}
+
+func TestRouter_ServesOpenAPI(t *testing.T) {
+	r := testRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, api.OpenAPI, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "SwaggerUIBundle")
+	assert.Contains(t, w.Body.String(), `openapi.json`)
+}
+
+func TestRouter_ServesJWKS(t *testing.T) {
+	r := testRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "jwks is not shadowed by the redirect route")
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+}
+
+func TestRouter_RendersProblems(t *testing.T) {
+	r := testRouter(t)
+
+	t.Run("json endpoints return problem+json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/expand/missing", nil)
+		req.Header.Set(middleware.RequestIDHeader, "trace-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, "trace-1", w.Header().Get(middleware.RequestIDHeader))
+		var p problem.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, "urn:shortener:problem:not-found", p.Type)
+		assert.Equal(t, "/api/expand/missing", p.Instance)
+		assert.Equal(t, "trace-1", p.RequestID)
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nope/x/y", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.NotEmpty(t, w.Header().Get(middleware.RequestIDHeader))
+	})
+
+	t.Run("text endpoint keeps plain text", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		req.Header.Set("Content-Type", "text/plain")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "empty body", w.Body.String())
+	})
+}
+
+func TestRouter_IdempotencyKey(t *testing.T) {
+	r := testRouter(t)
+
+	var cookies []*http.Cookie
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "retry-1")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send(`{"url":"https://idempotent.example"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	cookies = first.Result().Cookies()
+
+	retry := send(`{"url":"https://idempotent.example"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+
+	other := send(`{"url":"https://other.example"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+	assert.Equal(t, problem.ContentType, other.Header().Get("Content-Type"))
+}
+
+func TestRouter_APIKeys(t *testing.T) {
+	r := testRouter(t)
+
+	send := func(method, path, body string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if prepare != nil {
+			prepare(req)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/user/keys", `{"name":"ci","scopes":["create","read"]}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	withCookie := func(req *http.Request) {
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+	}
+	var created struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	withKey := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+	}
+
+	w = send(http.MethodPost, "/api/shorten", `{"url":"https://api-key.example"}`, withKey)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+	var short struct {
+		Result string `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &short))
+	linkPath := strings.TrimPrefix(short.Result, "http://localhost:8080")
+
+	// Публичные маршруты не проверяют области действия ключа.
+	w = send(http.MethodPost, "/api/user/keys", `{"name":"cleanup","scopes":["delete"]}`, withCookie)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var deleteOnly struct {
+		Key string `json:"key"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deleteOnly))
+	withDeleteKey := func(req *http.Request) {
+		req.Header.Set(middleware.APIKeyHeader, deleteOnly.Key)
+	}
+	assert.Equal(t, http.StatusSeeOther, send(http.MethodPost, linkPath, "", withDeleteKey).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/ping", "", withDeleteKey).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/openapi.json", "", withDeleteKey).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/urls", "", withDeleteKey).Code)
+
+	w = send(http.MethodGet, "/api/user/urls", "", withCookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://api-key.example", "link belongs to the key owner")
+
+	w = send(http.MethodDelete, "/api/user/urls", `["x"]`, withKey)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/keys", "", withKey).Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/user/keys/"+created.ID, "", withCookie).Code)
+
+	w = send(http.MethodGet, "/api/user/urls", "", withKey)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var p problem.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "urn:shortener:problem:invalid-api-key", p.Type)
+}
+
+func TestRouter_Accounts(t *testing.T) {
+	r := testRouter(t)
+
+	// client хранит cookie между запросами, как браузер.
+	type client struct{ cookies map[string]*http.Cookie }
+	send := func(cl *client, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cl.cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		for _, c := range w.Result().Cookies() {
+			if c.MaxAge < 0 {
+				delete(cl.cookies, c.Name)
+			} else {
+				cl.cookies[c.Name] = c
+			}
+		}
+		return w
+	}
+
+	laptop := &client{cookies: map[string]*http.Cookie{}}
+	assert.Equal(t, http.StatusCreated, send(laptop, http.MethodPost, "/api/shorten", `{"url":"https://account.example"}`).Code)
+	w := send(laptop, http.MethodPost, "/api/user/register", `{"username":"carol","password":"carol password","claim":true}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"claimed":1`)
+
+	assert.Equal(t, http.StatusNoContent, send(laptop, http.MethodPost, "/api/user/logout", "").Code)
+	assert.Empty(t, laptop.cookies)
+	w = send(laptop, http.MethodGet, "/api/user/urls", "")
+	assert.NotContains(t, w.Body.String(), "https://account.example", "logged out client is a new anonymous user")
+
+	phone := &client{cookies: map[string]*http.Cookie{}}
+	assert.Equal(t, http.StatusOK, send(phone, http.MethodPost, "/api/user/login", `{"username":"carol","password":"carol password"}`).Code)
+	w = send(phone, http.MethodGet, "/api/user/urls", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://account.example")
+}
+
+func TestRouter_StrictAuth(t *testing.T) {
+	send := func(r *gin.Engine, method, path, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("default mode issues identities everywhere", func(t *testing.T) {
+		r := testRouter(t)
+		w := send(r, http.MethodGet, "/api/user/urls", "", nil)
+		assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Result().Cookies())
+	})
+
+	r := testRouter(t, func(cfg *config.Config) { cfg.AuthStrict = true })
+
+	for _, op := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/user/urls", ""},
+		{http.MethodDelete, "/api/user/urls", `["abc"]`},
+		{http.MethodGet, "/api/v2/links", ""},
+		{http.MethodGet, "/api/user/keys", ""},
+	} {
+		t.Run("strict "+op.method+" "+op.path, func(t *testing.T) {
+			w := send(r, op.method, op.path, op.body, nil)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			assert.Empty(t, w.Result().Cookies())
+		})
+	}
+
+	t.Run("strict mode provisions on create", func(t *testing.T) {
+		w := send(r, http.MethodPost, "/api/shorten", `{"url":"https://strict.example"}`, nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		cookies := w.Result().Cookies()
+		assert.NotEmpty(t, cookies)
+
+		w = send(r, http.MethodGet, "/api/user/urls", "", cookies)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://strict.example")
+	})
+
+	t.Run("strict mode keeps public routes anonymous", func(t *testing.T) {
+		w := send(r, http.MethodGet, "/ping", "", nil)
+		assert.Empty(t, w.Result().Cookies())
+	})
+}
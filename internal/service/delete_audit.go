@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+)
+
+// AuditedMarkDeleted возвращает markFunc для NewDeleter: помечает ссылки удалёнными в store
+// и после успешной пометки отправляет в auditSvc событие "delete" для каждой ссылки
+// пользователя, которая ещё не была удалена.
+//
+// Записи читаются в воркере Deleter, поэтому хендлеры удаления отвечают 202, не обращаясь
+// к хранилищу за каждой ссылкой.
+func AuditedMarkDeleted(store storage.Storage, auditSvc *audit.Service) func(userID string, shorts []string) error {
+	return func(userID string, shorts []string) error {
+		var deleted []storage.URLRecord
+		for _, id := range shorts {
+			rec, ok := store.Get(id)
+			if ok && rec != nil && rec.UserID == userID && !rec.Deleted {
+				deleted = append(deleted, *rec)
+			}
+		}
+
+		if err := store.MarkDeleted(userID, shorts); err != nil {
+			return err
+		}
+
+		for _, rec := range deleted {
+			auditSvc.Notify(context.Background(), audit.Event{
+				TS:      time.Now().Unix(),
+				Action:  "delete",
+				UserID:  userID,
+				OwnerID: userID,
+				ShortID: rec.ShortID,
+				URL:     rec.OriginalURL,
+			})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// auditEvents — наблюдатель аудита, складывающий события в канал.
+type auditEvents chan audit.Event
+
+func (e auditEvents) Notify(_ context.Context, event audit.Event) error {
+	e <- event
+	return nil
+}
+
+func TestAuditedMarkDeleted(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	for _, rec := range []storage.URLRecord{
+		{ShortID: "mine", OriginalURL: "https://example.com/mine", UserID: "u1"},
+		{ShortID: "gone", OriginalURL: "https://example.com/gone", UserID: "u1"},
+		{ShortID: "theirs", OriginalURL: "https://example.com/theirs", UserID: "u2"},
+	} {
+		_, err := store.SaveRecord(ctx, rec)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, store.MarkDeleted("u1", []string{"gone"}))
+
+	events := make(auditEvents, 10)
+	mark := AuditedMarkDeleted(store, audit.NewService(zap.NewNop(), events))
+	assert.NoError(t, mark("u1", []string{"mine", "gone", "theirs", "missing"}))
+
+	rec, _ := store.Get("mine")
+	assert.True(t, rec.Deleted)
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "delete", e.Action)
+		assert.Equal(t, "u1", e.UserID)
+		assert.Equal(t, "mine", e.ShortID)
+		assert.Equal(t, "https://example.com/mine", e.URL)
+	case <-time.After(time.Second):
+		t.Fatal("delete is not audited")
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event for %q", e.ShortID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
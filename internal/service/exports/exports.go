@@ -0,0 +1,246 @@
+package exports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnknownLayout возвращается для неизвестного имени формата выгрузки.
+	ErrUnknownLayout = errors.New("exports: unknown layout")
+	// ErrUnrecognizedHeader возвращается, если по заголовку CSV не удалось определить
+	// колонки оригинального URL и короткого идентификатора.
+	ErrUnrecognizedHeader = errors.New("exports: unrecognized csv header")
+)
+
+// Layout описывает колонки CSV-выгрузки стороннего сокращателя.
+// Имена колонок сравниваются без учёта регистра, пробелов, "_" и "-".
+type Layout struct {
+	// Name — имя формата для query-параметра layout.
+	Name string
+	// Slug — колонки с коротким идентификатором без домена.
+	Slug []string
+	// ShortLink — колонки с полной короткой ссылкой; идентификатор берётся из её пути.
+	ShortLink []string
+	// URL — колонки с оригинальным URL.
+	URL []string
+	// Created — колонки со временем создания ссылки.
+	Created []string
+}
+
+// Layouts — поддерживаемые форматы в порядке автоопределения: сначала специфичные, затем общий.
+var Layouts = []Layout{
+	{Name: "bitly", ShortLink: []string{"link", "bitlink"}, URL: []string{"long_url"}, Created: []string{"created_at"}},
+	{Name: "rebrandly", Slug: []string{"slashtag"}, ShortLink: []string{"short_url"}, URL: []string{"destination"}, Created: []string{"created_at", "created"}},
+	{Name: "shortio", Slug: []string{"path"}, ShortLink: []string{"short_url"}, URL: []string{"original_url"}, Created: []string{"created_at"}},
+	{Name: "yourls", Slug: []string{"keyword"}, URL: []string{"url"}, Created: []string{"timestamp"}},
+	{Name: "tinyurl", Slug: []string{"alias"}, ShortLink: []string{"tiny_url"}, URL: []string{"url", "long_url"}, Created: []string{"created_at"}},
+	{Name: "generic", Slug: []string{"short_id", "slug", "id"}, ShortLink: []string{"short_url"}, URL: []string{"original_url", "url"}, Created: []string{"created_at"}},
+}
+
+// LayoutByName возвращает формат по имени.
+func LayoutByName(name string) (Layout, bool) {
+	for _, l := range Layouts {
+		if l.Name == strings.ToLower(name) {
+			return l, true
+		}
+	}
+	return Layout{}, false
+}
+
+// slugPattern ограничивает допустимые символы и длину короткого идентификатора.
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// reservedSlugs совпадают с первыми сегментами служебных маршрутов сервиса.
+var reservedSlugs = map[string]bool{
+	"api":  true,
+	"ping": true,
+}
+
+// ValidSlug сообщает, можно ли использовать slug как короткий идентификатор.
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug) && !reservedSlugs[strings.ToLower(slug)]
+}
+
+// Link — ссылка из выгрузки стороннего сокращателя.
+type Link struct {
+	// Line — номер строки в исходном файле.
+	Line int
+	// Slug — короткий идентификатор, который нужно сохранить.
+	Slug string
+	// URL — оригинальный URL.
+	URL string
+	// CreatedAt — время создания; нулевое, если колонки нет или значение не распознано.
+	CreatedAt time.Time
+	// Err — описание ошибки строки; такая строка не импортируется.
+	Err string
+}
+
+// columns — номера колонок выбранного формата, -1 если колонки нет.
+type columns struct {
+	slug, shortLink, url, created int
+}
+
+// Reader читает ссылки из CSV-выгрузки.
+type Reader struct {
+	csv    *csv.Reader
+	layout Layout
+	cols   columns
+}
+
+// NewReader читает заголовок CSV и сопоставляет колонки.
+// Если layout пуст, формат определяется по заголовку.
+func NewReader(r io.Reader, layout string) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("exports: read header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := normalize(name)
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	candidates := Layouts
+	if layout != "" {
+		l, ok := LayoutByName(layout)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownLayout, layout)
+		}
+		candidates = []Layout{l}
+	}
+
+	for _, l := range candidates {
+		cols := columns{
+			slug:      lookup(index, l.Slug),
+			shortLink: lookup(index, l.ShortLink),
+			url:       lookup(index, l.URL),
+			created:   lookup(index, l.Created),
+		}
+		if cols.url >= 0 && (cols.slug >= 0 || cols.shortLink >= 0) {
+			return &Reader{csv: cr, layout: l, cols: cols}, nil
+		}
+	}
+	return nil, ErrUnrecognizedHeader
+}
+
+// Layout возвращает формат, выбранный по заголовку.
+func (r *Reader) Layout() Layout {
+	return r.layout
+}
+
+// Next возвращает очередную ссылку; в конце файла возвращает io.EOF.
+// Ошибки отдельных строк возвращаются в поле Link.Err, чтение при этом продолжается.
+func (r *Reader) Next() (Link, error) {
+	for {
+		record, err := r.csv.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Link{Line: parseErr.Line, Err: "invalid CSV: " + parseErr.Err.Error()}, nil
+		}
+		if err != nil {
+			return Link{}, err
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		line, _ := r.csv.FieldPos(0)
+		link := Link{
+			Line: line,
+			URL:  field(record, r.cols.url),
+			Slug: field(record, r.cols.slug),
+		}
+		if link.Slug == "" {
+			link.Slug = slugFromShortLink(field(record, r.cols.shortLink))
+		}
+		link.CreatedAt = parseTime(field(record, r.cols.created))
+
+		switch {
+		case link.URL == "":
+			link.Err = "original url is empty"
+		case link.Slug == "":
+			link.Err = "short id is empty"
+		case !ValidSlug(link.Slug):
+			link.Err = fmt.Sprintf("short id %q is not allowed", link.Slug)
+		}
+		return link, nil
+	}
+}
+
+// normalize приводит имя колонки к виду для сравнения: "Long URL" и "long_url" совпадают.
+func normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+// lookup возвращает номер первой найденной колонки из names или -1.
+func lookup(index map[string]int, names []string) int {
+	for _, n := range names {
+		if i, ok := index[normalize(n)]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// slugFromShortLink извлекает идентификатор из короткой ссылки вида "bit.ly/abc" или "https://rebrand.ly/abc".
+func slugFromShortLink(link string) string {
+	if link == "" {
+		return ""
+	}
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// timeLayouts — форматы времени, встречающиеся в выгрузках.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02",
+}
+
+// parseTime распознаёт время создания; unix-время в секундах тоже поддерживается.
+func parseTime(v string) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t
+		}
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	return time.Time{}
+}
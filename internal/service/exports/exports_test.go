@@ -0,0 +1,108 @@
+package exports
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, r *Reader) []Link {
+	var links []Link
+	for {
+		l, err := r.Next()
+		if err == io.EOF {
+			return links
+		}
+		assert.NoError(t, err)
+		links = append(links, l)
+	}
+}
+
+func TestNewReader_DetectLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		want     string
+		wantSlug string
+		wantURL  string
+	}{
+		{
+			name:     "bitly",
+			csv:      "link,long_url,created_at,title\nbit.ly/promo1,https://example.com/a,2024-01-02T10:00:00Z,Promo\n",
+			want:     "bitly",
+			wantSlug: "promo1",
+			wantURL:  "https://example.com/a",
+		},
+		{
+			name:     "rebrandly",
+			csv:      "Title,Slashtag,Destination,Short URL,Created\nx,summer,https://example.com/s,https://rebrand.ly/summer,2024-05-01\n",
+			want:     "rebrandly",
+			wantSlug: "summer",
+			wantURL:  "https://example.com/s",
+		},
+		{
+			name:     "yourls",
+			csv:      "keyword,url,title,timestamp,ip,clicks\ndocs,https://example.com/docs,Docs,2023-07-01 12:30:00,127.0.0.1,42\n",
+			want:     "yourls",
+			wantSlug: "docs",
+			wantURL:  "https://example.com/docs",
+		},
+		{
+			name:     "generic",
+			csv:      "short_id,original_url\nabc,https://example.com/g\n",
+			want:     "generic",
+			wantSlug: "abc",
+			wantURL:  "https://example.com/g",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.csv), "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, r.Layout().Name)
+
+			links := readAll(t, r)
+			if assert.Len(t, links, 1) {
+				assert.Equal(t, tt.wantSlug, links[0].Slug)
+				assert.Equal(t, tt.wantURL, links[0].URL)
+				assert.Empty(t, links[0].Err)
+				assert.Equal(t, 2, links[0].Line)
+			}
+		})
+	}
+}
+
+func TestNewReader_Errors(t *testing.T) {
+	_, err := NewReader(strings.NewReader("foo,bar\n1,2\n"), "")
+	assert.ErrorIs(t, err, ErrUnrecognizedHeader)
+
+	_, err = NewReader(strings.NewReader("keyword,url\n"), "unknown")
+	assert.ErrorIs(t, err, ErrUnknownLayout)
+
+	_, err = NewReader(strings.NewReader("keyword,url\n"), "bitly")
+	assert.ErrorIs(t, err, ErrUnrecognizedHeader)
+}
+
+func TestReader_RowErrors(t *testing.T) {
+	r, err := NewReader(strings.NewReader("keyword,url,timestamp\n,https://a.com,\napi,https://b.com,\nok,,\nfine,https://c.com,1700000000\n"), "yourls")
+	assert.NoError(t, err)
+
+	links := readAll(t, r)
+	assert.Len(t, links, 4)
+	assert.Equal(t, "short id is empty", links[0].Err)
+	assert.Contains(t, links[1].Err, "not allowed")
+	assert.Equal(t, "original url is empty", links[2].Err)
+	assert.Empty(t, links[3].Err)
+	assert.True(t, links[3].CreatedAt.Equal(time.Unix(1700000000, 0)))
+}
+
+func TestValidSlug(t *testing.T) {
+	assert.True(t, ValidSlug("my-link_1"))
+	assert.False(t, ValidSlug("with/slash"))
+	assert.False(t, ValidSlug("API"))
+	assert.False(t, ValidSlug(strings.Repeat("a", 65)))
+}
@@ -0,0 +1,96 @@
+package forward
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+)
+
+// ErrInvalidUTM возвращается при недопустимом UTM-параметре.
+var ErrInvalidUTM = errors.New("invalid utm parameter")
+
+// utmKeys — допустимые UTM-параметры.
+var utmKeys = map[string]bool{
+	"utm_source":   true,
+	"utm_medium":   true,
+	"utm_campaign": true,
+	"utm_term":     true,
+	"utm_content":  true,
+	"utm_id":       true,
+}
+
+// Request описывает входящий переход, из которого берутся параметры для передачи.
+type Request struct {
+	// ID — короткий идентификатор ссылки, подставляется вместо {id} в UTM-шаблонах.
+	ID string
+	// Variant — метка варианта A/B-сплита, подставляется вместо {variant}.
+	Variant string
+	// Query — query-параметры, с которыми пришёл посетитель.
+	Query url.Values
+	// Suffix — часть пути после короткого ID, например "/rest/of/path".
+	Suffix string
+}
+
+// ValidateUTM проверяет, что заданы только известные UTM-параметры с непустыми значениями.
+func ValidateUTM(utm map[string]string) error {
+	for k, v := range utm {
+		if !utmKeys[k] {
+			return fmt.Errorf("%w: unknown key %q", ErrInvalidUTM, k)
+		}
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("%w: empty value for %q", ErrInvalidUTM, k)
+		}
+	}
+	return nil
+}
+
+// Build формирует итоговый адрес редиректа из dest по настройкам f.
+//
+//   - PathSuffix: суффикс пути дописывается к пути dest (сегменты ".." отбрасываются).
+//   - UTM: параметры добавляются, если их ещё нет в dest; в значениях подставляются {id} и {variant}.
+//   - PassQuery: параметры посетителя добавляются, если таких ключей ещё нет в dest.
+//
+// Параметры, заданные в самом dest, имеют приоритет, поэтому посетитель не может
+// переопределить кампанию. Если настройки пустые, dest возвращается без изменений.
+func Build(dest string, f storage.Forwarding, r Request) (string, error) {
+	if f.IsZero() {
+		return dest, nil
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", err
+	}
+
+	if f.PathSuffix && r.Suffix != "" && r.Suffix != "/" {
+		u.Path = strings.TrimRight(u.Path, "/") + path.Clean("/"+r.Suffix)
+		u.RawPath = ""
+	}
+
+	q := u.Query()
+	added := false
+	replacer := strings.NewReplacer("{id}", r.ID, "{variant}", r.Variant)
+	for k, v := range f.UTM {
+		if !q.Has(k) {
+			q.Set(k, replacer.Replace(v))
+			added = true
+		}
+	}
+	if f.PassQuery {
+		for k, vs := range r.Query {
+			if !q.Has(k) {
+				q[k] = vs
+				added = true
+			}
+		}
+	}
+	if added {
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
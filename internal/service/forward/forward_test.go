@@ -0,0 +1,75 @@
+package forward
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		dest string
+		f    storage.Forwarding
+		req  Request
+		want string
+	}{
+		{
+			name: "no forwarding keeps destination",
+			dest: "https://example.com/landing?b=2&a=1",
+			req:  Request{Query: url.Values{"x": {"1"}}, Suffix: "/docs"},
+			want: "https://example.com/landing?b=2&a=1",
+		},
+		{
+			name: "query passthrough does not override destination",
+			dest: "https://example.com/landing?ref=site",
+			f:    storage.Forwarding{PassQuery: true},
+			req:  Request{Query: url.Values{"ref": {"visitor"}, "gclid": {"abc"}}},
+			want: "https://example.com/landing?gclid=abc&ref=site",
+		},
+		{
+			name: "utm templates",
+			dest: "https://example.com/",
+			f:    storage.Forwarding{UTM: map[string]string{"utm_source": "short", "utm_content": "{id}-{variant}"}},
+			req:  Request{ID: "abc123", Variant: "b"},
+			want: "https://example.com/?utm_content=abc123-b&utm_source=short",
+		},
+		{
+			name: "path suffix",
+			dest: "https://docs.example.com/v1/",
+			f:    storage.Forwarding{PathSuffix: true},
+			req:  Request{Suffix: "/guide/intro"},
+			want: "https://docs.example.com/v1/guide/intro",
+		},
+		{
+			name: "path suffix cannot escape destination path",
+			dest: "https://docs.example.com/v1",
+			f:    storage.Forwarding{PathSuffix: true},
+			req:  Request{Suffix: "/../../admin"},
+			want: "https://docs.example.com/v1/admin",
+		},
+		{
+			name: "suffix ignored when disabled",
+			dest: "https://docs.example.com/v1",
+			f:    storage.Forwarding{PassQuery: true},
+			req:  Request{Suffix: "/guide"},
+			want: "https://docs.example.com/v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Build(tt.dest, tt.f, tt.req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateUTM(t *testing.T) {
+	assert.NoError(t, ValidateUTM(map[string]string{"utm_source": "qr", "utm_campaign": "spring"}))
+	assert.ErrorIs(t, ValidateUTM(map[string]string{"source": "qr"}), ErrInvalidUTM)
+	assert.ErrorIs(t, ValidateUTM(map[string]string{"utm_source": " "}), ErrInvalidUTM)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPasswordAttempts — число неудачных попыток ввода пароля за окно.
+	MaxPasswordAttempts = 5
+	// PasswordAttemptWindow — длительность окна ограничения попыток ввода пароля.
+	PasswordAttemptWindow = 15 * time.Minute
+)
+
+// attemptWindow хранит число попыток в текущем окне.
+type attemptWindow struct {
+	attempts int
+	start    time.Time
+}
+
+// AttemptLimiter ограничивает число неудачных попыток по ключу (например, короткому ID ссылки)
+// в скользящем окне фиксированной длины. Безопасен для конкурентного использования.
+//
+// Попытка учитывается в Allow до проверки пароля, а успешная проверка сбрасывает счётчик
+// через Reset. Поэтому параллельные запросы не могут проверить больше max паролей, пока
+// идёт медленное сравнение bcrypt.
+type AttemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string]*attemptWindow
+	now      func() time.Time
+}
+
+// NewAttemptLimiter создаёт ограничитель, допускающий max неудачных попыток за window.
+func NewAttemptLimiter(max int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attemptWindow),
+		now:      time.Now,
+	}
+}
+
+// NewPasswordLimiter создаёт ограничитель попыток ввода пароля с настройками по умолчанию.
+// Для паролей ссылок приложение использует один экземпляр на все точки входа
+// (редирект, expand, gRPC), ключом служит короткий ID ссылки.
+func NewPasswordLimiter() *AttemptLimiter {
+	return NewAttemptLimiter(MaxPasswordAttempts, PasswordAttemptWindow)
+}
+
+// Allow резервирует попытку для key и сообщает, разрешена ли она.
+// Разрешённая попытка сразу учитывается как неудачная; при успехе вызывающий сбрасывает
+// счётчик через Reset. Если лимит исчерпан, возвращает false и время до сброса окна.
+func (l *AttemptLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	a, ok := l.attempts[key]
+	if !ok || now.Sub(a.start) >= l.window {
+		l.prune(now)
+		l.attempts[key] = &attemptWindow{attempts: 1, start: now}
+		return true, 0
+	}
+	if a.attempts >= l.max {
+		return false, l.window - now.Sub(a.start)
+	}
+	a.attempts++
+	return true, 0
+}
+
+// Reset сбрасывает счётчик попыток для key после успешной проверки.
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
+
+// prune удаляет истёкшие окна, чтобы карта не росла бесконечно.
+// Вызывается под блокировкой.
+func (l *AttemptLimiter) prune(now time.Time) {
+	if len(l.attempts) < 1024 {
+		return
+	}
+	for k, a := range l.attempts {
+		if now.Sub(a.start) >= l.window {
+			delete(l.attempts, k)
+		}
+	}
+}
+
+// LoginLimiter ограничивает неудачные попытки входа в учётную запись.
+// Ключом служит пара «адрес клиента + имя пользователя»: перебор с одного адреса
+// блокируется, но чужие попытки не блокируют вход владельцу с другого адреса.
+type LoginLimiter struct {
+	limiter *AttemptLimiter
+}
+
+// NewLoginLimiter создаёт ограничитель попыток входа с настройками по умолчанию.
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{limiter: NewAttemptLimiter(MaxPasswordAttempts, PasswordAttemptWindow)}
+}
+
+// Allow резервирует попытку входа username с адреса clientIP; см. AttemptLimiter.Allow.
+func (l *LoginLimiter) Allow(clientIP, username string) (bool, time.Duration) {
+	return l.limiter.Allow(loginKey(clientIP, username))
+}
+
+// Reset сбрасывает счётчик попыток после успешного входа.
+func (l *LoginLimiter) Reset(clientIP, username string) {
+	l.limiter.Reset(loginKey(clientIP, username))
+}
+
+// loginKey составляет ключ ограничителя; нулевой байт не встречается в адресе.
+func loginKey(clientIP, username string) string {
+	return clientIP + "\x00" + username
+}
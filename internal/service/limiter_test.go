@@ -0,0 +1,75 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewAttemptLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("id")
+	assert.True(t, ok)
+	ok, _ = l.Allow("id")
+	assert.True(t, ok)
+
+	ok, retry := l.Allow("id")
+	assert.False(t, ok, "attempts are counted before they are checked")
+	assert.Equal(t, time.Minute, retry)
+
+	ok, _ = l.Allow("other")
+	assert.True(t, ok, "limits are tracked per key")
+
+	now = now.Add(time.Minute)
+	ok, _ = l.Allow("id")
+	assert.True(t, ok, "window expires")
+
+	l.Allow("id")
+	l.Reset("id")
+	ok, _ = l.Allow("id")
+	assert.True(t, ok, "reset clears attempts")
+}
+
+func TestAttemptLimiter_Concurrent(t *testing.T) {
+	l := NewAttemptLimiter(5, time.Minute)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Allow("id"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, allowed, "concurrent guesses cannot exceed the limit")
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := NewLoginLimiter()
+	for i := 0; i < MaxPasswordAttempts; i++ {
+		l.Allow("192.0.2.1", "bob")
+	}
+
+	ok, _ := l.Allow("192.0.2.1", "bob")
+	assert.False(t, ok)
+	ok, _ = l.Allow("198.51.100.7", "bob")
+	assert.True(t, ok, "another client keeps its own budget")
+	ok, _ = l.Allow("192.0.2.1", "alice")
+	assert.True(t, ok, "another user keeps its own budget")
+
+	l.Reset("192.0.2.1", "bob")
+	ok, _ = l.Allow("192.0.2.1", "bob")
+	assert.True(t, ok)
+}
@@ -0,0 +1,177 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Поддерживаемые форматы QR-кода.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения параметров генерации.
+const (
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+)
+
+var (
+	// ErrInvalidFormat возвращается при неизвестном формате изображения.
+	ErrInvalidFormat = errors.New("qr: unsupported format")
+	// ErrInvalidSize возвращается, если размер выходит за допустимые границы.
+	ErrInvalidSize = errors.New("qr: size out of range")
+	// ErrInvalidLevel возвращается при неизвестном уровне коррекции ошибок.
+	ErrInvalidLevel = errors.New("qr: unsupported error correction level")
+	// ErrInvalidMargin возвращается, если отступ выходит за допустимые границы.
+	ErrInvalidMargin = errors.New("qr: margin out of range")
+)
+
+var levels = map[string]goqrcode.RecoveryLevel{
+	"L": goqrcode.Low,
+	"M": goqrcode.Medium,
+	"Q": goqrcode.High,
+	"H": goqrcode.Highest,
+}
+
+// Options описывает параметры генерации QR-кода.
+type Options struct {
+	// Format — формат изображения: "png" или "svg".
+	Format string
+	// Size — ширина и высота изображения в пикселях.
+	Size int
+	// Level — уровень коррекции ошибок: L, M, Q или H.
+	Level string
+	// Margin — ширина «тихой зоны» вокруг кода в модулях.
+	Margin int
+}
+
+// DefaultOptions возвращает параметры по умолчанию: PNG 256x256, уровень M, отступ 4 модуля.
+func DefaultOptions() Options {
+	return Options{
+		Format: FormatPNG,
+		Size:   256,
+		Level:  "M",
+		Margin: 4,
+	}
+}
+
+// Validate проверяет параметры генерации.
+func (o Options) Validate() error {
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return ErrInvalidFormat
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return ErrInvalidSize
+	}
+	if _, ok := levels[strings.ToUpper(o.Level)]; !ok {
+		return ErrInvalidLevel
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return ErrInvalidMargin
+	}
+	return nil
+}
+
+// ContentType возвращает MIME-тип изображения для выбранного формата.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Encode кодирует content в QR-код и возвращает изображение в выбранном формате.
+// Кодировщик написан на чистом Go и не требует внешних сервисов.
+func Encode(content string, o Options) ([]byte, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := goqrcode.New(content, levels[strings.ToUpper(o.Level)])
+	if err != nil {
+		return nil, fmt.Errorf("qr: encode: %w", err)
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	if o.Format == FormatSVG {
+		return renderSVG(bitmap, o), nil
+	}
+	return renderPNG(bitmap, o)
+}
+
+// layout вычисляет размер модуля в пикселях и смещение для центрирования кода.
+// Если запрошенный размер слишком мал, изображение увеличивается до минимально возможного.
+func layout(modules, size int) (scale, offset, total int) {
+	scale = size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	total = size
+	if scale*modules > total {
+		total = scale * modules
+	}
+	offset = (total - scale*modules) / 2
+	return scale, offset, total
+}
+
+func renderPNG(bitmap [][]bool, o Options) ([]byte, error) {
+	modules := len(bitmap) + 2*o.Margin
+	scale, offset, total := layout(modules, o.Size)
+
+	img := image.NewPaletted(
+		image.Rect(0, 0, total, total),
+		color.Palette{color.White, color.Black},
+	)
+
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := offset + (x+o.Margin)*scale
+			y0 := offset + (y+o.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("qr: png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, o Options) []byte {
+	modules := len(bitmap) + 2*o.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, modules, modules)
+	buf.WriteString(`<path fill="#000" d="`)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+o.Margin, y+o.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,69 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{
+			name: "png with defaults",
+			opts: DefaultOptions(),
+		},
+		{
+			name: "svg without margin",
+			opts: Options{Format: FormatSVG, Size: 128, Level: "H", Margin: 0},
+		},
+		{
+			name:    "unknown format",
+			opts:    Options{Format: "gif", Size: 256, Level: "M", Margin: 4},
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "size too small",
+			opts:    Options{Format: FormatPNG, Size: 10, Level: "M", Margin: 4},
+			wantErr: ErrInvalidSize,
+		},
+		{
+			name:    "unknown level",
+			opts:    Options{Format: FormatPNG, Size: 256, Level: "X", Margin: 4},
+			wantErr: ErrInvalidLevel,
+		},
+		{
+			name:    "negative margin",
+			opts:    Options{Format: FormatPNG, Size: 256, Level: "M", Margin: -1},
+			wantErr: ErrInvalidMargin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Encode("http://localhost:8080/abc123", tt.opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			switch tt.opts.Format {
+			case FormatPNG:
+				img, err := png.Decode(bytes.NewReader(data))
+				assert.NoError(t, err)
+				assert.Equal(t, tt.opts.Size, img.Bounds().Dx())
+				assert.Equal(t, tt.opts.Size, img.Bounds().Dy())
+			case FormatSVG:
+				assert.True(t, strings.HasPrefix(string(data), "<svg"))
+				assert.Contains(t, string(data), `width="128"`)
+			}
+		})
+	}
+}
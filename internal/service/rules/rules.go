@@ -0,0 +1,210 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Платформы, распознаваемые по User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// MaxRules — максимальное число правил на одну ссылку.
+const MaxRules = 50
+
+var platforms = map[string]bool{
+	PlatformIOS:     true,
+	PlatformAndroid: true,
+	PlatformWindows: true,
+	PlatformMacOS:   true,
+	PlatformLinux:   true,
+}
+
+// ErrInvalidRule возвращается при некорректном правиле редиректа.
+var ErrInvalidRule = errors.New("invalid redirect rule")
+
+// CountryResolver определяет код страны (ISO 3166-1 alpha-2) по IP-адресу.
+type CountryResolver interface {
+	Country(ip net.IP) (string, error)
+}
+
+// Visitor описывает признаки посетителя, по которым сопоставляются правила.
+type Visitor struct {
+	// Platform — платформа из User-Agent, пустая строка если не распознана.
+	Platform string
+	// Languages — языки из Accept-Language в порядке убывания приоритета.
+	Languages []string
+	// Country — код страны в верхнем регистре, пустая строка если неизвестна.
+	Country string
+}
+
+// Validate проверяет список правил и приводит значения условий к каноническому виду.
+func Validate(list []storage.RedirectRule) ([]storage.RedirectRule, error) {
+	if len(list) > MaxRules {
+		return nil, fmt.Errorf("%w: at most %d rules allowed", ErrInvalidRule, MaxRules)
+	}
+
+	out := make([]storage.RedirectRule, 0, len(list))
+	for i, r := range list {
+		r.Platform = strings.ToLower(strings.TrimSpace(r.Platform))
+		r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+		r.Country = strings.ToUpper(strings.TrimSpace(r.Country))
+		r.URL = strings.TrimSpace(r.URL)
+
+		if r.Platform != "" && !platforms[r.Platform] {
+			return nil, fmt.Errorf("%w: rule %d: unknown platform %q", ErrInvalidRule, i, r.Platform)
+		}
+		if r.Country != "" && len(r.Country) != 2 {
+			return nil, fmt.Errorf("%w: rule %d: country must be a two-letter code", ErrInvalidRule, i)
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%w: rule %d: url must be absolute", ErrInvalidRule, i)
+		}
+
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// NeedsCountry сообщает, используют ли правила условие по стране.
+func NeedsCountry(list []storage.RedirectRule) bool {
+	for _, r := range list {
+		if r.Country != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Match возвращает URL первого правила, все условия которого выполняются для посетителя v.
+// Если ни одно правило не подошло, возвращает false.
+func Match(list []storage.RedirectRule, v Visitor) (string, bool) {
+	for _, r := range list {
+		if r.Platform != "" && r.Platform != v.Platform {
+			continue
+		}
+		if r.Country != "" && r.Country != v.Country {
+			continue
+		}
+		if r.Language != "" && !matchLanguage(r.Language, v.Languages) {
+			continue
+		}
+		return r.URL, true
+	}
+	return "", false
+}
+
+// matchLanguage сопоставляет язык правила с языками посетителя.
+// Правило "en" подходит для "en" и "en-us", правило "en-us" — только для "en-us".
+func matchLanguage(want string, langs []string) bool {
+	for _, l := range langs {
+		if l == want || strings.HasPrefix(l, want+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectPlatform определяет платформу посетителя по заголовку User-Agent.
+func DetectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return PlatformIOS
+	case strings.Contains(ua, "android"):
+		return PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return PlatformLinux
+	default:
+		return ""
+	}
+}
+
+// ParseAcceptLanguage разбирает заголовок Accept-Language и возвращает языки
+// в нижнем регистре, отсортированные по убыванию веса q. Языки с q=0 отбрасываются.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range fields[1:] {
+			p = strings.TrimSpace(p)
+			if v, ok := strings.CutPrefix(p, "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, weighted{lang: lang, q: q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	langs := make([]string, 0, len(items))
+	for _, it := range items {
+		langs = append(langs, it.lang)
+	}
+	return langs
+}
+
+// GeoIP определяет страну по локальной базе MaxMind (GeoLite2/GeoIP2 Country или City, формат mmdb).
+type GeoIP struct {
+	reader *maxminddb.Reader
+}
+
+// NewGeoIP открывает файл базы GeoIP по пути path.
+func NewGeoIP(path string) (*GeoIP, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open geoip database: %w", err)
+	}
+	return &GeoIP{reader: reader}, nil
+}
+
+// Country возвращает код страны для ip или пустую строку, если адрес не найден в базе.
+func (g *GeoIP) Country(ip net.IP) (string, error) {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := g.reader.Lookup(ip, &record); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(record.Country.ISOCode), nil
+}
+
+// Close закрывает базу GeoIP.
+func (g *GeoIP) Close() error {
+	return g.reader.Close()
+}
@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	list := []storage.RedirectRule{
+		{Platform: PlatformIOS, URL: "https://apps.apple.com"},
+		{Platform: PlatformAndroid, URL: "https://play.google.com"},
+		{Language: "de", Country: "DE", URL: "https://example.de"},
+		{Language: "fr", URL: "https://example.fr"},
+	}
+
+	tests := []struct {
+		name    string
+		visitor Visitor
+		want    string
+		wantOK  bool
+	}{
+		{
+			name:    "ios",
+			visitor: Visitor{Platform: PlatformIOS, Languages: []string{"de"}, Country: "DE"},
+			want:    "https://apps.apple.com",
+			wantOK:  true,
+		},
+		{
+			name:    "android",
+			visitor: Visitor{Platform: PlatformAndroid},
+			want:    "https://play.google.com",
+			wantOK:  true,
+		},
+		{
+			name:    "all conditions must hold",
+			visitor: Visitor{Platform: PlatformWindows, Languages: []string{"de-at"}, Country: "AT"},
+		},
+		{
+			name:    "language prefix and country",
+			visitor: Visitor{Platform: PlatformLinux, Languages: []string{"de-de"}, Country: "DE"},
+			want:    "https://example.de",
+			wantOK:  true,
+		},
+		{
+			name:    "secondary language",
+			visitor: Visitor{Languages: []string{"en-us", "fr"}},
+			want:    "https://example.fr",
+			wantOK:  true,
+		},
+		{
+			name:    "fallback",
+			visitor: Visitor{Platform: PlatformMacOS, Languages: []string{"en"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(list, tt.visitor)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15":    PlatformIOS,
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36":                    PlatformAndroid,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36":                   PlatformWindows,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15":              PlatformMacOS,
+		"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0": PlatformLinux,
+		"curl/8.4.0": "",
+	}
+
+	for ua, want := range tests {
+		assert.Equal(t, want, DetectPlatform(ua), ua)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr;q=0.7, en-US, de;q=0.9, *;q=0.5, ru;q=0")
+	assert.Equal(t, []string{"en-us", "de", "fr"}, got)
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestValidate(t *testing.T) {
+	got, err := Validate([]storage.RedirectRule{{Platform: " iOS ", Country: "de", URL: "https://a.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []storage.RedirectRule{{Platform: "ios", Country: "DE", URL: "https://a.com"}}, got)
+
+	_, err = Validate([]storage.RedirectRule{{Platform: "symbian", URL: "https://a.com"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+
+	_, err = Validate([]storage.RedirectRule{{Country: "DEU", URL: "https://a.com"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+
+	_, err = Validate([]storage.RedirectRule{{Platform: "ios", URL: "apps.apple.com"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
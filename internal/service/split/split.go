@@ -0,0 +1,81 @@
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+)
+
+// MaxDestinations — максимальное число вариантов в одном сплите.
+const MaxDestinations = 10
+
+// ErrInvalidDestinations возвращается при некорректном списке вариантов.
+var ErrInvalidDestinations = errors.New("invalid split destinations")
+
+// Validate проверяет список вариантов: абсолютные URL, положительные веса,
+// уникальные метки. Пустой список допустим и означает отсутствие сплита.
+func Validate(dests []storage.Destination) ([]storage.Destination, error) {
+	if len(dests) == 0 {
+		return nil, nil
+	}
+	if len(dests) == 1 || len(dests) > MaxDestinations {
+		return nil, fmt.Errorf("%w: between 2 and %d destinations required", ErrInvalidDestinations, MaxDestinations)
+	}
+
+	names := make(map[string]bool, len(dests))
+	out := make([]storage.Destination, 0, len(dests))
+	for i, d := range dests {
+		d.Name = strings.TrimSpace(d.Name)
+		d.URL = strings.TrimSpace(d.URL)
+
+		u, err := url.Parse(d.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%w: destination %d: url must be absolute", ErrInvalidDestinations, i)
+		}
+		if d.Weight <= 0 {
+			return nil, fmt.Errorf("%w: destination %d: weight must be positive", ErrInvalidDestinations, i)
+		}
+		name := Variant(d, i)
+		if names[name] {
+			return nil, fmt.Errorf("%w: duplicate variant %q", ErrInvalidDestinations, name)
+		}
+		names[name] = true
+
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Variant возвращает метку варианта для аудита: Name или порядковый номер.
+func Variant(d storage.Destination, index int) string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return strconv.Itoa(index)
+}
+
+// Pick выбирает индекс варианта случайно пропорционально весам.
+// Возвращает -1 для пустого списка.
+func Pick(dests []storage.Destination) int {
+	total := 0
+	for _, d := range dests {
+		total += d.Weight
+	}
+	if total <= 0 {
+		return -1
+	}
+
+	n := rand.IntN(total)
+	for i, d := range dests {
+		if n < d.Weight {
+			return i
+		}
+		n -= d.Weight
+	}
+	return len(dests) - 1
+}
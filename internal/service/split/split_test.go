@@ -0,0 +1,47 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPick(t *testing.T) {
+	dests := []storage.Destination{
+		{URL: "https://a.com", Weight: 70},
+		{URL: "https://b.com", Weight: 30},
+	}
+
+	counts := make([]int, len(dests))
+	for i := 0; i < 10000; i++ {
+		counts[Pick(dests)]++
+	}
+
+	assert.InDelta(t, 7000, counts[0], 500)
+	assert.InDelta(t, 3000, counts[1], 500)
+	assert.Equal(t, -1, Pick(nil))
+}
+
+func TestValidate(t *testing.T) {
+	got, err := Validate([]storage.Destination{
+		{Name: " landing-a ", URL: "https://a.com", Weight: 1},
+		{URL: "https://b.com", Weight: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "landing-a", got[0].Name)
+	assert.Equal(t, "1", Variant(got[1], 1))
+
+	got, err = Validate(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = Validate([]storage.Destination{{URL: "https://a.com", Weight: 1}})
+	assert.ErrorIs(t, err, ErrInvalidDestinations)
+
+	_, err = Validate([]storage.Destination{{URL: "https://a.com", Weight: 0}, {URL: "https://b.com", Weight: 1}})
+	assert.ErrorIs(t, err, ErrInvalidDestinations)
+
+	_, err = Validate([]storage.Destination{{Name: "x", URL: "https://a.com", Weight: 1}, {Name: "x", URL: "https://b.com", Weight: 1}})
+	assert.ErrorIs(t, err, ErrInvalidDestinations)
+}
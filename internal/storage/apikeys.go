@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// apiKeys хранит API-ключи в памяти процесса.
+// Используется InMemoryStorage и FileStorage; FileStorage задаёт persist,
+// чтобы ключи переживали перезапуск.
+type apiKeys struct {
+	mu     sync.RWMutex
+	byID   map[string]APIKey
+	byHash map[string]string
+	// persist сохраняет новое состояние ключа; вызывается под блокировкой.
+	persist func(APIKey) error
+}
+
+// putAPIKey записывает ключ в память. Вызывается под блокировкой.
+func (k *apiKeys) putAPIKey(key APIKey) {
+	if k.byID == nil {
+		k.byID = make(map[string]APIKey)
+		k.byHash = make(map[string]string)
+	}
+	key.Scopes = append([]string(nil), key.Scopes...)
+	k.byID[key.ID] = key
+	k.byHash[key.Hash] = key.ID
+}
+
+// CreateAPIKey сохраняет новый ключ.
+func (k *apiKeys) CreateAPIKey(ctx context.Context, key APIKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.persist != nil {
+		if err := k.persist(key); err != nil {
+			return err
+		}
+	}
+	k.putAPIKey(key)
+	return nil
+}
+
+// GetAPIKey находит ключ по хешу.
+func (k *apiKeys) GetAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	id, ok := k.byHash[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	key := k.byID[id]
+	key.Scopes = append([]string(nil), key.Scopes...)
+	return &key, nil
+}
+
+// ListAPIKeys возвращает ключи пользователя в порядке создания.
+func (k *apiKeys) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var keys []APIKey
+	for _, key := range k.byID {
+		if key.UserID == userID {
+			key.Scopes = append([]string(nil), key.Scopes...)
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ пользователя.
+func (k *apiKeys) RevokeAPIKey(ctx context.Context, userID, id string) (*APIKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.byID[id]
+	if !ok || key.UserID != userID {
+		return nil, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = time.Now().UTC()
+		if k.persist != nil {
+			if err := k.persist(key); err != nil {
+				return nil, err
+			}
+		}
+		k.putAPIKey(key)
+	}
+	key.Scopes = append([]string(nil), key.Scopes...)
+	return &key, nil
+}
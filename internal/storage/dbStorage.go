@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -20,6 +21,30 @@ type DBStorage struct {
 // ErrURLExists возвращается, если сохраняемый URL уже существует.
 var ErrURLExists = fmt.Errorf("url already exists")
 
+// ErrLinkExhausted возвращается, если у ссылки закончились разрешённые переходы.
+var ErrLinkExhausted = fmt.Errorf("link click limit exhausted")
+
+// ErrLinkNotFound возвращается, если ссылка не найдена или принадлежит другому пользователю.
+var ErrLinkNotFound = fmt.Errorf("link not found")
+
+// ErrLinkChanged возвращается условным UpdateLink, если ссылка изменилась с момента чтения.
+var ErrLinkChanged = fmt.Errorf("link changed")
+
+// ErrAPIKeyNotFound возвращается, если API-ключ не найден или принадлежит другому пользователю.
+var ErrAPIKeyNotFound = fmt.Errorf("api key not found")
+
+// ErrUserExists возвращается, если имя пользователя уже занято.
+var ErrUserExists = fmt.Errorf("user already exists")
+
+// ErrUserNotFound возвращается, если учётная запись не найдена.
+var ErrUserNotFound = fmt.Errorf("user not found")
+
+// ErrShortIDExists возвращается, если сохраняемый короткий идентификатор уже занят.
+var ErrShortIDExists = fmt.Errorf("short id already exists")
+
+// pgUniqueViolation — код ошибки PostgreSQL при нарушении уникального индекса.
+const pgUniqueViolation = "23505"
+
 // NewDBStorage создаёт новое подключение к базе данных PostgreSQL.
 // Параметры:
 //   - dsn: Data Source Name для подключения к БД.
@@ -151,33 +176,349 @@ func (s *DBStorage) Save(ctx context.Context, userID, id, url string) (string, e
 	}
 }
 
+// SaveRecord сохраняет запись URL вместе с дополнительными атрибутами ссылки.
+// Параметры:
+//   - ctx: context запроса.
+//   - rec: запись для сохранения, владелец берётся из rec.UserID;
+//     если rec.CreatedAt не задано, используется текущее время.
+//
+// Возвращает:
+//   - string: короткий идентификатор, который был сохранён или уже существовал.
+//   - error: ErrURLExists если URL уже существует, ErrShortIDExists если занят rec.ShortID,
+//     или другую ошибку.
+func (s *DBStorage) SaveRecord(ctx context.Context, rec URLRecord) (string, error) {
+	query := `
+        INSERT INTO urls (short_url, original_url, user_id, password_hash, max_clicks, clicks_left, rules, destinations, forwarding, redirect_code, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $5, $6, $7, $8, $9, $10, $11)
+        ON CONFLICT (original_url) DO NOTHING
+        RETURNING short_url;
+    `
+
+	rules, err := marshalRules(rec.Rules)
+	if err != nil {
+		return "", err
+	}
+	dests, err := marshalDestinations(rec.Destinations)
+	if err != nil {
+		return "", err
+	}
+	forwarding, err := json.Marshal(rec.Forwarding)
+	if err != nil {
+		return "", err
+	}
+
+	createdAt := rec.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var savedID string
+	err = s.DB.QueryRowContext(ctx, query,
+		rec.ShortID, rec.OriginalURL, rec.UserID, rec.PasswordHash, rec.MaxClicks, rules, dests, forwarding, rec.RedirectCode, createdAt,
+		nullTime(rec.ExpiresAt),
+	).Scan(&savedID)
+
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		return savedID, nil
+
+	case errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation:
+		// Конфликт по original_url обрабатывает ON CONFLICT, значит занят short_url.
+		return "", ErrShortIDExists
+
+	case errors.Is(err, sql.ErrNoRows):
+		var existingID string
+		sel := `SELECT short_url FROM urls WHERE original_url = $1`
+		if err := s.DB.QueryRowContext(ctx, sel, rec.OriginalURL).Scan(&existingID); err != nil {
+			return "", err
+		}
+		return existingID, ErrURLExists
+
+	default:
+		return "", err
+	}
+}
+
 // Get возвращает запись URL по короткому идентификатору.
 // Параметры:
 //   - id: короткий идентификатор URL.
 //
 // Возвращает:
-//   - *URLRecord: запись URL с полями ShortID, OriginalURL, UserID, Deleted.
+//   - *URLRecord: запись URL со всеми сохранёнными атрибутами ссылки.
 //   - bool: true если запись найдена, false если не найдена.
 func (s *DBStorage) Get(id string) (*URLRecord, bool) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	query := `SELECT original_url, user_id, is_deleted FROM urls WHERE short_url = $1`
-	var original, userID string
+	query := `SELECT original_url, user_id, is_deleted, password_hash, max_clicks, clicks_left, rules, destinations, forwarding, redirect_code, created_at, expires_at, clicks FROM urls WHERE short_url = $1`
+	var original, userID, passwordHash string
 	var isDeleted bool
-	err := s.DB.QueryRowContext(ctx, query, id).Scan(&original, &userID, &isDeleted)
+	var maxClicks, clicksLeft, redirectCode, clicks int
+	var createdAt time.Time
+	var expiresAt sql.NullTime
+	var rawRules, rawDests, rawForwarding []byte
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(
+		&original, &userID, &isDeleted, &passwordHash, &maxClicks, &clicksLeft, &rawRules, &rawDests, &rawForwarding, &redirectCode, &createdAt,
+		&expiresAt, &clicks,
+	)
 	if err != nil {
 		s.Logger.Debug("Get: not found or db error", zap.String("id", id), zap.Error(err))
 		return nil, false
 	}
+	var rules []RedirectRule
+	if len(rawRules) > 0 {
+		if err := json.Unmarshal(rawRules, &rules); err != nil {
+			s.Logger.Error("Get: invalid rules", zap.String("id", id), zap.Error(err))
+		}
+	}
+	var dests []Destination
+	if len(rawDests) > 0 {
+		if err := json.Unmarshal(rawDests, &dests); err != nil {
+			s.Logger.Error("Get: invalid destinations", zap.String("id", id), zap.Error(err))
+		}
+	}
+	var forwarding Forwarding
+	if len(rawForwarding) > 0 {
+		if err := json.Unmarshal(rawForwarding, &forwarding); err != nil {
+			s.Logger.Error("Get: invalid forwarding", zap.String("id", id), zap.Error(err))
+		}
+	}
 	rec := &URLRecord{
-		ShortID:     id,
-		OriginalURL: original,
-		UserID:      userID,
-		Deleted:     isDeleted,
+		ShortID:      id,
+		OriginalURL:  original,
+		UserID:       userID,
+		Deleted:      isDeleted,
+		PasswordHash: passwordHash,
+		MaxClicks:    maxClicks,
+		ClicksLeft:   clicksLeft,
+		Rules:        rules,
+		Destinations: dests,
+		Forwarding:   forwarding,
+		RedirectCode: redirectCode,
+		CreatedAt:    createdAt,
+		ExpiresAt:    expiresAt.Time,
+		Clicks:       clicks,
 	}
 	return rec, true
 }
 
+// Lookup возвращает короткий идентификатор по оригинальному URL.
+// Параметры:
+//   - ctx: context запроса.
+//   - originalURL: оригинальный URL.
+//
+// Возвращает:
+//   - string: короткий идентификатор.
+//   - bool: true если URL найден, false если не найден или произошла ошибка запроса.
+func (s *DBStorage) Lookup(ctx context.Context, originalURL string) (string, bool) {
+	var id string
+	err := s.DB.QueryRowContext(ctx, `SELECT short_url FROM urls WHERE original_url = $1`, originalURL).Scan(&id)
+	if err != nil {
+		s.Logger.Debug("Lookup: not found or db error", zap.String("url", originalURL), zap.Error(err))
+		return "", false
+	}
+	return id, true
+}
+
+// SetRules заменяет список правил условного редиректа ссылки пользователя.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор владельца ссылки.
+//   - id: короткий идентификатор URL.
+//   - rules: новый список правил; пустой список удаляет правила.
+//
+// Возвращает:
+//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю, или ошибку запроса.
+func (s *DBStorage) SetRules(ctx context.Context, userID, id string, rules []RedirectRule) error {
+	raw, err := marshalRules(rules)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.DB.ExecContext(ctx,
+		`UPDATE urls SET rules = $3 WHERE short_url = $1 AND user_id = $2`,
+		id, userID, raw,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// SetDestinations заменяет взвешенные варианты адреса назначения ссылки пользователя.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор владельца ссылки.
+//   - id: короткий идентификатор URL.
+//   - dests: новый список вариантов; пустой список отключает A/B-сплит.
+//
+// Возвращает:
+//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю, или ошибку запроса.
+func (s *DBStorage) SetDestinations(ctx context.Context, userID, id string, dests []Destination) error {
+	raw, err := marshalDestinations(dests)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.DB.ExecContext(ctx,
+		`UPDATE urls SET destinations = $3 WHERE short_url = $1 AND user_id = $2`,
+		id, userID, raw,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// marshalDestinations сериализует варианты в JSON для колонки destinations; nil сохраняется как пустой массив.
+func marshalDestinations(dests []Destination) ([]byte, error) {
+	if dests == nil {
+		dests = []Destination{}
+	}
+	return json.Marshal(dests)
+}
+
+// marshalRules сериализует правила в JSON для колонки rules; nil сохраняется как пустой массив.
+func marshalRules(rules []RedirectRule) ([]byte, error) {
+	if rules == nil {
+		rules = []RedirectRule{}
+	}
+	return json.Marshal(rules)
+}
+
+// ConsumeClick атомарно уменьшает число оставшихся переходов одной командой UPDATE ... RETURNING,
+// поэтому параллельные запросы не могут израсходовать больше переходов, чем задано.
+// Параметры:
+//   - ctx: context запроса.
+//   - id: короткий идентификатор URL.
+//
+// Возвращает:
+//   - int: число переходов, оставшихся после текущего.
+//   - error: ErrLinkExhausted если переходов не осталось, или ошибку запроса.
+func (s *DBStorage) ConsumeClick(ctx context.Context, id string) (int, error) {
+	query := `
+        UPDATE urls
+        SET clicks_left = clicks_left - 1, clicks = clicks + 1
+        WHERE short_url = $1 AND max_clicks > 0 AND clicks_left > 0
+        RETURNING clicks_left
+    `
+
+	var left int
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(&left)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrLinkExhausted
+	}
+	if err != nil {
+		return 0, err
+	}
+	return left, nil
+}
+
+// RecordClick увеличивает счётчик переходов ссылки.
+// Параметры:
+//   - ctx: context запроса.
+//   - id: короткий идентификатор URL.
+//
+// Возвращает:
+//   - error: ErrLinkNotFound если ссылка не найдена, или ошибку запроса.
+func (s *DBStorage) RecordClick(ctx context.Context, id string) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE urls SET clicks = clicks + 1 WHERE short_url = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// UpdateLink изменяет адрес назначения и срок действия ссылки пользователя.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор владельца ссылки.
+//   - id: короткий идентификатор URL.
+//   - upd: изменяемые поля; nil-поля не меняются.
+//
+// Возвращает:
+//   - *URLRecord: запись после изменения.
+//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю,
+//     ErrURLExists если новый адрес уже сокращён, ErrLinkChanged если не выполнено условие
+//     upd.Match, или ошибку запроса.
+//
+// Условие upd.Match проверяется в том же UPDATE, поэтому изменение атомарно.
+func (s *DBStorage) UpdateLink(ctx context.Context, userID, id string, upd LinkUpdate) (*URLRecord, error) {
+	var newURL sql.NullString
+	if upd.OriginalURL != nil {
+		newURL = sql.NullString{String: *upd.OriginalURL, Valid: true}
+	}
+	var expiresAt any
+	if upd.ExpiresAt != nil {
+		expiresAt = nullTime(*upd.ExpiresAt)
+	}
+
+	query := `
+        UPDATE urls
+        SET original_url = COALESCE($3::text, original_url),
+            expires_at = CASE WHEN $4::boolean THEN $5::timestamptz ELSE expires_at END
+        WHERE short_url = $1 AND user_id = $2`
+	args := []any{id, userID, newURL, upd.ExpiresAt != nil, expiresAt}
+	if m := upd.Match; m != nil {
+		query += ` AND original_url = $6 AND expires_at IS NOT DISTINCT FROM $7 AND is_deleted = $8`
+		args = append(args, m.OriginalURL, nullTime(m.ExpiresAt), m.Deleted)
+	}
+
+	res, err := s.DB.ExecContext(ctx, query, args...)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return nil, ErrURLExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if upd.Match != nil {
+			if rec, ok := s.Get(id); ok && rec.UserID == userID {
+				return nil, ErrLinkChanged
+			}
+		}
+		return nil, ErrLinkNotFound
+	}
+
+	rec, ok := s.Get(id)
+	if !ok {
+		return nil, ErrLinkNotFound
+	}
+	return rec, nil
+}
+
+// nullTime переводит нулевое время в NULL для колонок TIMESTAMPTZ.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 func (s *DBStorage) Ping(ctx context.Context) error {
 	return s.DB.PingContext(ctx)
 }
@@ -219,6 +560,74 @@ func (s *DBStorage) GetUserURLs(ctx context.Context, userID string) ([]BatchItem
 	return result, nil
 }
 
+// exportFetchSize — число строк, получаемых из курсора экспорта за один FETCH.
+const exportFetchSize = 1000
+
+// ExportUserURLs передаёт в fn все ссылки пользователя, читая их серверным курсором
+// порциями по exportFetchSize строк, поэтому объём экспорта не ограничен памятью.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор пользователя.
+//   - fn: обработчик очередной записи.
+//
+// Возвращает:
+//   - error: ошибка запроса к базе или ошибка, возвращённая fn.
+func (s *DBStorage) ExportUserURLs(ctx context.Context, userID string, fn func(URLRecord) error) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        DECLARE export_urls NO SCROLL CURSOR FOR
+        SELECT short_url, original_url, is_deleted, created_at, max_clicks, clicks_left, expires_at, clicks
+        FROM urls WHERE user_id = $1 ORDER BY uuid
+    `, userID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := s.fetchExport(ctx, tx, userID, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `CLOSE export_urls`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// fetchExport читает очередную порцию строк курсора export_urls и возвращает их число.
+func (s *DBStorage) fetchExport(ctx context.Context, tx *sql.Tx, userID string, fn func(URLRecord) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM export_urls`, exportFetchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		rec := URLRecord{UserID: userID}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&rec.ShortID, &rec.OriginalURL, &rec.Deleted, &rec.CreatedAt, &rec.MaxClicks, &rec.ClicksLeft, &expiresAt, &rec.Clicks); err != nil {
+			return n, err
+		}
+		rec.ExpiresAt = expiresAt.Time
+		n++
+		if err := fn(rec); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
+
 // MarkDeleted помечает список URL как удалённые для указанного пользователя.
 // Параметры:
 //   - userID: идентификатор пользователя.
@@ -239,3 +648,268 @@ func (s *DBStorage) MarkDeleted(userID string, shorts []string) error {
 	_, err := s.DB.Exec(query, userID, pq.Array(shorts))
 	return err
 }
+
+// claimIdempotencyAttempts ограничивает повторы ClaimIdempotencyKey, если занявший ключ
+// запрос освободил его между вставкой и чтением.
+const claimIdempotencyAttempts = 3
+
+// ClaimIdempotencyKey занимает ключ идемпотентности пользователя.
+// Перед вставкой удаляет истёкшие ключи всех пользователей.
+// Параметры:
+//   - ctx: context запроса.
+//   - rec: запись с UserID, Key, RequestHash и ExpiresAt.
+//
+// Возвращает:
+//   - *IdempotencyRecord: nil, если ключ занят этим вызовом, иначе действующая запись.
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) ClaimIdempotencyKey(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`); err != nil {
+		return nil, err
+	}
+
+	for range claimIdempotencyAttempts {
+		var userID string
+		err := s.DB.QueryRowContext(ctx, `
+            INSERT INTO idempotency_keys (user_id, idem_key, request_hash, expires_at)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (user_id, idem_key) DO NOTHING
+            RETURNING user_id
+        `, rec.UserID, rec.Key, rec.RequestHash, rec.ExpiresAt).Scan(&userID)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		existing := IdempotencyRecord{UserID: rec.UserID, Key: rec.Key}
+		err = s.DB.QueryRowContext(ctx, `
+            SELECT request_hash, completed, status, content_type, body, expires_at
+            FROM idempotency_keys
+            WHERE user_id = $1 AND idem_key = $2
+        `, rec.UserID, rec.Key).Scan(
+			&existing.RequestHash, &existing.Completed, &existing.Status,
+			&existing.ContentType, &existing.Body, &existing.ExpiresAt,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	return nil, fmt.Errorf("cannot claim idempotency key %q", rec.Key)
+}
+
+// CompleteIdempotencyKey сохраняет ответ для занятого ключа.
+// Параметры:
+//   - ctx: context запроса.
+//   - rec: запись с UserID, Key, Status, ContentType и Body.
+//
+// Возвращает:
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) CompleteIdempotencyKey(ctx context.Context, rec IdempotencyRecord) error {
+	_, err := s.DB.ExecContext(ctx, `
+        UPDATE idempotency_keys
+        SET completed = TRUE, status = $3, content_type = $4, body = $5
+        WHERE user_id = $1 AND idem_key = $2
+    `, rec.UserID, rec.Key, rec.Status, rec.ContentType, rec.Body)
+	return err
+}
+
+// ReleaseIdempotencyKey удаляет незавершённую запись ключа.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор пользователя.
+//   - key: ключ идемпотентности.
+//
+// Возвращает:
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	_, err := s.DB.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND idem_key = $2 AND NOT completed`,
+		userID, key,
+	)
+	return err
+}
+
+// CreateAPIKey сохраняет новый API-ключ пользователя.
+// Параметры:
+//   - ctx: context запроса.
+//   - key: ключ с ID, UserID, Hash и атрибутами.
+//
+// Возвращает:
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) CreateAPIKey(ctx context.Context, key APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, `
+        INSERT INTO api_keys (id, user_id, name, key_hash, scopes, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, key.ID, key.UserID, key.Name, key.Hash, scopes, key.CreatedAt, nullTime(key.ExpiresAt))
+	return err
+}
+
+// apiKeyColumns — столбцы api_keys в порядке, ожидаемом scanAPIKey.
+const apiKeyColumns = `id, user_id, name, key_hash, scopes, created_at, expires_at, revoked_at`
+
+// scanAPIKey читает строку api_keys.
+func scanAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var key APIKey
+	var scopes []byte
+	var expiresAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+		return nil, fmt.Errorf("invalid scopes of api key %s: %w", key.ID, err)
+	}
+	key.ExpiresAt = expiresAt.Time
+	key.RevokedAt = revokedAt.Time
+	return &key, nil
+}
+
+// GetAPIKey находит API-ключ по хешу.
+// Параметры:
+//   - ctx: context запроса.
+//   - hash: SHA-256 хеш ключа в hex.
+//
+// Возвращает:
+//   - *APIKey: найденный ключ.
+//   - error: ErrAPIKeyNotFound если ключ не найден, либо ошибку запроса к базе.
+func (s *DBStorage) GetAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// ListAPIKeys возвращает API-ключи пользователя в порядке создания.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - []APIKey: ключи пользователя, включая отозванные.
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя; у уже отозванного ключа время отзыва не меняется.
+// Параметры:
+//   - ctx: context запроса.
+//   - userID: идентификатор владельца ключа.
+//   - id: идентификатор ключа.
+//
+// Возвращает:
+//   - *APIKey: ключ после отзыва.
+//   - error: ErrAPIKeyNotFound если ключ не найден или принадлежит другому пользователю,
+//     либо ошибку запроса к базе.
+func (s *DBStorage) RevokeAPIKey(ctx context.Context, userID, id string) (*APIKey, error) {
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx, `
+        UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+        WHERE id = $1 AND user_id = $2
+        RETURNING `+apiKeyColumns, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// CreateUser сохраняет новую учётную запись.
+// Параметры:
+//   - ctx: context запроса.
+//   - user: учётная запись с ID, Username и PasswordHash.
+//
+// Возвращает:
+//   - error: ErrUserExists если имя занято, либо ошибку запроса к базе.
+func (s *DBStorage) CreateUser(ctx context.Context, user User) error {
+	_, err := s.DB.ExecContext(ctx, `
+        INSERT INTO users (id, username, password_hash, created_at)
+        VALUES ($1, $2, $3, $4)
+    `, user.ID, user.Username, user.PasswordHash, user.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return ErrUserExists
+	}
+	return err
+}
+
+// getUser выполняет запрос одной учётной записи по условию where.
+func (s *DBStorage) getUser(ctx context.Context, where string, arg string) (*User, error) {
+	var user User
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT id, username, password_hash, created_at FROM users WHERE `+where+` = $1`, arg,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser находит учётную запись по ID.
+// Параметры:
+//   - ctx: context запроса.
+//   - id: идентификатор пользователя.
+//
+// Возвращает:
+//   - *User: найденная учётная запись.
+//   - error: ErrUserNotFound если учётной записи нет, либо ошибку запроса к базе.
+func (s *DBStorage) GetUser(ctx context.Context, id string) (*User, error) {
+	return s.getUser(ctx, "id", id)
+}
+
+// GetUserByName находит учётную запись по имени.
+// Параметры:
+//   - ctx: context запроса.
+//   - username: имя в нижнем регистре.
+//
+// Возвращает:
+//   - *User: найденная учётная запись.
+//   - error: ErrUserNotFound если учётной записи нет, либо ошибку запроса к базе.
+func (s *DBStorage) GetUserByName(ctx context.Context, username string) (*User, error) {
+	return s.getUser(ctx, "username", username)
+}
+
+// ClaimURLs переносит все ссылки пользователя fromUserID на пользователя toUserID
+// одним запросом.
+// Параметры:
+//   - ctx: context запроса.
+//   - fromUserID: анонимный пользователь, чьи ссылки переносятся.
+//   - toUserID: учётная запись, получающая ссылки.
+//
+// Возвращает:
+//   - int: число перенесённых ссылок.
+//   - error: ошибка запроса к базе.
+func (s *DBStorage) ClaimURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	res, err := s.DB.ExecContext(ctx, `UPDATE urls SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	_ "errors"
 	"testing"
+	"time"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -56,6 +58,37 @@ func TestDBStorage_Save(t *testing.T) {
 	})
 }
 
+func TestDBStorage_SaveRecord(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+
+	mock.ExpectQuery("INSERT INTO urls \\(short_url, original_url, user_id, password_hash, max_clicks, clicks_left, rules, destinations, forwarding, redirect_code, created_at, expires_at\\) .* RETURNING short_url").
+		WithArgs("short1", "https://example.com", "user123", "hash", 0, []byte("[]"), []byte("[]"), []byte("{}"), 308, sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"short_url"}).AddRow("short1"))
+
+	shortID, err := s.SaveRecord(ctx, storage.URLRecord{
+		ShortID:      "short1",
+		OriginalURL:  "https://example.com",
+		UserID:       "user123",
+		PasswordHash: "hash",
+		RedirectCode: 308,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "short1", shortID)
+
+	mock.ExpectQuery("INSERT INTO urls").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "urls_short_url_key"})
+
+	_, err = s.SaveRecord(ctx, storage.URLRecord{ShortID: "short1", OriginalURL: "https://other.com", UserID: "user123"})
+	assert.ErrorIs(t, err, storage.ErrShortIDExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestDBStorage_SaveBatch(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	db, mock, err := sqlmock.New()
@@ -104,21 +137,36 @@ func TestDBStorage_Get(t *testing.T) {
 	s := &storage.DBStorage{DB: db, Logger: logger}
 
 	t.Run("existing ID", func(t *testing.T) {
-		mock.ExpectQuery("SELECT original_url, user_id, is_deleted FROM urls WHERE short_url = \\$1").
+		created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		expires := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT original_url, user_id, is_deleted, password_hash, max_clicks, clicks_left, rules, destinations, forwarding, redirect_code, created_at, expires_at, clicks FROM urls WHERE short_url = \\$1").
 			WithArgs("short1").
-			WillReturnRows(sqlmock.NewRows([]string{"original_url", "user_id", "is_deleted"}).
-				AddRow("https://example.com", "user123", false))
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "user_id", "is_deleted", "password_hash", "max_clicks", "clicks_left", "rules", "destinations", "forwarding", "redirect_code", "created_at", "expires_at", "clicks"}).
+				AddRow("https://example.com", "user123", false, "", 0, 0,
+					[]byte(`[{"platform":"ios","url":"https://apps.apple.com"}]`),
+					[]byte(`[{"url":"https://a.example.com","weight":70},{"url":"https://b.example.com","weight":30}]`),
+					[]byte(`{"pass_query":true,"utm":{"utm_source":"qr"}}`), 301, created, expires, 12))
 
 		rec, ok := s.Get("short1")
 		assert.True(t, ok)
 		assert.Equal(t, "https://example.com", rec.OriginalURL)
 		assert.Equal(t, "user123", rec.UserID)
 		assert.False(t, rec.Deleted)
+		assert.Equal(t, []storage.RedirectRule{{Platform: "ios", URL: "https://apps.apple.com"}}, rec.Rules)
+		assert.Equal(t, []storage.Destination{
+			{URL: "https://a.example.com", Weight: 70},
+			{URL: "https://b.example.com", Weight: 30},
+		}, rec.Destinations)
+		assert.Equal(t, storage.Forwarding{PassQuery: true, UTM: map[string]string{"utm_source": "qr"}}, rec.Forwarding)
+		assert.Equal(t, 301, rec.RedirectCode)
+		assert.True(t, created.Equal(rec.CreatedAt))
+		assert.True(t, expires.Equal(rec.ExpiresAt))
+		assert.Equal(t, 12, rec.Clicks)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("non-existent ID", func(t *testing.T) {
-		mock.ExpectQuery("SELECT original_url, user_id, is_deleted FROM urls WHERE short_url = \\$1").
+		mock.ExpectQuery("SELECT original_url, user_id, is_deleted, password_hash, max_clicks, clicks_left, rules, destinations, forwarding, redirect_code, created_at, expires_at, clicks FROM urls WHERE short_url = \\$1").
 			WithArgs("unknown").
 			WillReturnError(sql.ErrNoRows)
 
@@ -174,3 +222,390 @@ func TestDBStorage_MarkDeleted(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestDBStorage_ConsumeClick(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+
+	t.Run("decrements remaining clicks", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE urls SET clicks_left = clicks_left - 1, clicks = clicks \\+ 1 .* RETURNING clicks_left").
+			WithArgs("once").
+			WillReturnRows(sqlmock.NewRows([]string{"clicks_left"}).AddRow(0))
+
+		left, err := s.ConsumeClick(ctx, "once")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, left)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exhausted link", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE urls SET clicks_left = clicks_left - 1, clicks = clicks \\+ 1 .* RETURNING clicks_left").
+			WithArgs("once").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := s.ConsumeClick(ctx, "once")
+		assert.ErrorIs(t, err, storage.ErrLinkExhausted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDBStorage_SetRules(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+	rules := []storage.RedirectRule{{Platform: "android", URL: "https://play.google.com"}}
+
+	t.Run("owner updates rules", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET rules = \\$3 WHERE short_url = \\$1 AND user_id = \\$2").
+			WithArgs("short1", "user123", []byte(`[{"platform":"android","url":"https://play.google.com"}]`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, s.SetRules(ctx, "user123", "short1", rules))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("foreign link", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET rules = \\$3 WHERE short_url = \\$1 AND user_id = \\$2").
+			WithArgs("short1", "intruder", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, s.SetRules(ctx, "intruder", "short1", rules), storage.ErrLinkNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDBStorage_Lookup(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT short_url FROM urls WHERE original_url = \\$1").
+		WithArgs("https://example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"short_url"}).AddRow("short1"))
+
+	id, ok := s.Lookup(ctx, "https://example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "short1", id)
+
+	mock.ExpectQuery("SELECT short_url FROM urls WHERE original_url = \\$1").
+		WithArgs("https://unknown.example.com").
+		WillReturnError(sql.ErrNoRows)
+
+	_, ok = s.Lookup(ctx, "https://unknown.example.com")
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBStorage_ExportUserURLs(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE export_urls NO SCROLL CURSOR FOR").
+		WithArgs("user123").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 1000 FROM export_urls").
+		WillReturnRows(sqlmock.NewRows([]string{"short_url", "original_url", "is_deleted", "created_at", "max_clicks", "clicks_left", "expires_at", "clicks"}).
+			AddRow("shortA", "https://a.com", false, created, 0, 0, nil, 7).
+			AddRow("shortB", "https://b.com", true, created, 5, 2, created, 3))
+	mock.ExpectExec("CLOSE export_urls").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var got []storage.URLRecord
+	err = s.ExportUserURLs(context.Background(), "user123", func(rec storage.URLRecord) error {
+		got = append(got, rec)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "shortA", got[0].ShortID)
+	assert.True(t, got[1].Deleted)
+	assert.Equal(t, 5, got[1].MaxClicks)
+	assert.Equal(t, 2, got[1].ClicksLeft)
+	assert.Equal(t, "user123", got[1].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBStorage_IdempotencyKeys(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+	rec := storage.IdempotencyRecord{UserID: "user1", Key: "k1", RequestHash: "h1", ExpiresAt: expires}
+
+	t.Run("claims free key", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at <= now()").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO idempotency_keys .* ON CONFLICT .* DO NOTHING RETURNING user_id").
+			WithArgs("user1", "k1", "h1", expires).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1"))
+
+		existing, err := s.ClaimIdempotencyKey(ctx, rec)
+		assert.NoError(t, err)
+		assert.Nil(t, existing)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns stored response", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at <= now()").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO idempotency_keys").
+			WithArgs("user1", "k1", "h1", expires).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT request_hash, completed, status, content_type, body, expires_at FROM idempotency_keys").
+			WithArgs("user1", "k1").
+			WillReturnRows(sqlmock.NewRows([]string{"request_hash", "completed", "status", "content_type", "body", "expires_at"}).
+				AddRow("h1", true, 201, "application/json", []byte(`{"result":"x"}`), expires))
+
+		existing, err := s.ClaimIdempotencyKey(ctx, rec)
+		assert.NoError(t, err)
+		if assert.NotNil(t, existing) {
+			assert.True(t, existing.Completed)
+			assert.Equal(t, 201, existing.Status)
+			assert.Equal(t, `{"result":"x"}`, string(existing.Body))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("complete and release", func(t *testing.T) {
+		mock.ExpectExec("UPDATE idempotency_keys SET completed = TRUE").
+			WithArgs("user1", "k1", 201, "application/json", []byte("ok")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM idempotency_keys WHERE user_id = \\$1 AND idem_key = \\$2 AND NOT completed").
+			WithArgs("user1", "k1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		done := rec
+		done.Status, done.ContentType, done.Body = 201, "application/json", []byte("ok")
+		assert.NoError(t, s.CompleteIdempotencyKey(ctx, done))
+		assert.NoError(t, s.ReleaseIdempotencyKey(ctx, "user1", "k1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDBStorage_UpdateLink(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+	newURL := "https://new.example"
+
+	t.Run("record click", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET clicks = clicks \\+ 1 WHERE short_url = \\$1").
+			WithArgs("short1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE urls SET clicks = clicks \\+ 1 WHERE short_url = \\$1").
+			WithArgs("missing").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, s.RecordClick(ctx, "short1"))
+		assert.ErrorIs(t, s.RecordClick(ctx, "missing"), storage.ErrLinkNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET original_url = COALESCE").
+			WithArgs("short1", "intruder", sql.NullString{String: newURL, Valid: true}, false, nil).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := s.UpdateLink(ctx, "intruder", "short1", storage.LinkUpdate{OriginalURL: &newURL})
+		assert.ErrorIs(t, err, storage.ErrLinkNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("url already shortened", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET original_url = COALESCE").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "urls_original_url_key"})
+
+		_, err := s.UpdateLink(ctx, "user1", "short1", storage.LinkUpdate{OriginalURL: &newURL})
+		assert.ErrorIs(t, err, storage.ErrURLExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("conditional update of a changed link", func(t *testing.T) {
+		match := storage.URLRecord{OriginalURL: "https://example.com"}
+		mock.ExpectExec("UPDATE urls SET original_url = COALESCE.* AND original_url = \\$6 AND expires_at IS NOT DISTINCT FROM \\$7 AND is_deleted = \\$8").
+			WithArgs("short1", "user1", sql.NullString{String: newURL, Valid: true}, false, nil, "https://example.com", sql.NullTime{}, false).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT original_url, user_id.* FROM urls WHERE short_url = \\$1").
+			WithArgs("short1").
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "user_id", "is_deleted", "password_hash", "max_clicks", "clicks_left", "rules", "destinations", "forwarding", "redirect_code", "created_at", "expires_at", "clicks"}).
+				AddRow("https://moved.example", "user1", false, "", 0, 0, nil, nil, nil, 0, time.Now(), nil, 0))
+
+		_, err := s.UpdateLink(ctx, "user1", "short1", storage.LinkUpdate{OriginalURL: &newURL, Match: &match})
+		assert.ErrorIs(t, err, storage.ErrLinkChanged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("clear expiry", func(t *testing.T) {
+		var never time.Time
+		mock.ExpectExec("UPDATE urls SET original_url = COALESCE").
+			WithArgs("short1", "user1", sql.NullString{}, true, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT original_url, user_id.* FROM urls WHERE short_url = \\$1").
+			WithArgs("short1").
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "user_id", "is_deleted", "password_hash", "max_clicks", "clicks_left", "rules", "destinations", "forwarding", "redirect_code", "created_at", "expires_at", "clicks"}).
+				AddRow("https://example.com", "user1", false, "", 0, 0, nil, nil, nil, 0, time.Now(), nil, 4))
+
+		rec, err := s.UpdateLink(ctx, "user1", "short1", storage.LinkUpdate{ExpiresAt: &never})
+		assert.NoError(t, err)
+		assert.True(t, rec.ExpiresAt.IsZero())
+		assert.Equal(t, 4, rec.Clicks)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDBStorage_APIKeys(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+	created := time.Now()
+	columns := []string{"id", "user_id", "name", "key_hash", "scopes", "created_at", "expires_at", "revoked_at"}
+
+	t.Run("create", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO api_keys").
+			WithArgs("k1", "user1", "ci", "h1", []byte(`["read"]`), created, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := s.CreateAPIKey(ctx, storage.APIKey{ID: "k1", UserID: "user1", Name: "ci", Hash: "h1", Scopes: []string{"read"}, CreatedAt: created})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get by hash", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM api_keys WHERE key_hash = \\$1").
+			WithArgs("h1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("k1", "user1", "ci", "h1", []byte(`["read"]`), created, nil, nil))
+		mock.ExpectQuery("SELECT .* FROM api_keys WHERE key_hash = \\$1").
+			WithArgs("missing").
+			WillReturnError(sql.ErrNoRows)
+
+		key, err := s.GetAPIKey(ctx, "h1")
+		assert.NoError(t, err)
+		if assert.NotNil(t, key) {
+			assert.Equal(t, "user1", key.UserID)
+			assert.Equal(t, []string{"read"}, key.Scopes)
+			assert.True(t, key.Active(time.Now()))
+		}
+		_, err = s.GetAPIKey(ctx, "missing")
+		assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM api_keys WHERE user_id = \\$1 ORDER BY created_at, id").
+			WithArgs("user1").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("k1", "user1", "ci", "h1", []byte(`["read"]`), created, nil, created).
+				AddRow("k2", "user1", "", "h2", []byte(`["create","read","delete"]`), created, created.Add(time.Hour), nil))
+
+		keys, err := s.ListAPIKeys(ctx, "user1")
+		assert.NoError(t, err)
+		if assert.Len(t, keys, 2) {
+			assert.False(t, keys[0].RevokedAt.IsZero())
+			assert.True(t, keys[1].HasScope(storage.ScopeDelete))
+			assert.False(t, keys[1].ExpiresAt.IsZero())
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE api_keys SET revoked_at = COALESCE\\(revoked_at, now\\(\\)\\)").
+			WithArgs("k1", "user1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("k1", "user1", "ci", "h1", []byte(`["read"]`), created, nil, created))
+		mock.ExpectQuery("UPDATE api_keys").
+			WithArgs("k1", "user2").
+			WillReturnError(sql.ErrNoRows)
+
+		key, err := s.RevokeAPIKey(ctx, "user1", "k1")
+		assert.NoError(t, err)
+		if assert.NotNil(t, key) {
+			assert.False(t, key.Active(time.Now()))
+		}
+		_, err = s.RevokeAPIKey(ctx, "user2", "k1")
+		assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDBStorage_Users(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &storage.DBStorage{DB: db, Logger: logger}
+	ctx := context.Background()
+	created := time.Now()
+	user := storage.User{ID: "acc", Username: "alice", PasswordHash: "hash", CreatedAt: created}
+
+	t.Run("create", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs("acc", "alice", "hash", created).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs("acc", "alice", "hash", created).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		assert.NoError(t, s.CreateUser(ctx, user))
+		assert.ErrorIs(t, s.CreateUser(ctx, user), storage.ErrUserExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		columns := []string{"id", "username", "password_hash", "created_at"}
+		mock.ExpectQuery("SELECT id, username, password_hash, created_at FROM users WHERE username = \\$1").
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("acc", "alice", "hash", created))
+		mock.ExpectQuery("SELECT .* FROM users WHERE id = \\$1").
+			WithArgs("anon").
+			WillReturnError(sql.ErrNoRows)
+
+		got, err := s.GetUserByName(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, &user, got)
+		_, err = s.GetUser(ctx, "anon")
+		assert.ErrorIs(t, err, storage.ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		mock.ExpectExec("UPDATE urls SET user_id = \\$2 WHERE user_id = \\$1").
+			WithArgs("anon", "acc").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		n, err := s.ClaimURLs(ctx, "anon", "acc")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
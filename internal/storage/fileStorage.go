@@ -2,31 +2,97 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type ShortURLRecord struct {
-	UUID        int    `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	Deleted     bool   `json:"deleted"`
+	UUID         int            `json:"uuid"`
+	ShortURL     string         `json:"short_url"`
+	OriginalURL  string         `json:"original_url"`
+	UserID       string         `json:"user_id,omitempty"`
+	Deleted      bool           `json:"deleted"`
+	PasswordHash string         `json:"password_hash,omitempty"`
+	MaxClicks    int            `json:"max_clicks,omitempty"`
+	ClicksLeft   int            `json:"clicks_left,omitempty"`
+	Rules        []RedirectRule `json:"rules,omitempty"`
+	Destinations []Destination  `json:"destinations,omitempty"`
+	Forwarding   *Forwarding    `json:"forwarding,omitempty"`
+	RedirectCode int            `json:"redirect_code,omitempty"`
+	CreatedAt    *time.Time     `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`
+	Clicks       int            `json:"clicks,omitempty"`
+}
+
+// clickDelta — строка файла с одним переходом по ссылке. Переходы дописываются короткими
+// записями поверх последней полной строки ссылки, а не её полной копией.
+type clickDelta struct {
+	// Click — короткий ID ссылки.
+	Click string `json:"click"`
+	// Consumed — переход списан из лимита max_clicks (ConsumeClick).
+	Consumed bool `json:"consumed,omitempty"`
+}
+
+// clickDeltaPrefix — начало строки clickDelta в файле.
+var clickDeltaPrefix = []byte(`{"click":`)
+
+// recordFile — файл записей ссылок. Обычно это *os.File; тесты подменяют его,
+// чтобы проверить обработку ошибок записи.
+type recordFile interface {
+	io.ReadWriteSeeker
+	io.Closer
 }
 
 type FileStorage struct {
 	mu              sync.RWMutex
 	path            string
-	file            *os.File
+	file            recordFile
 	data            map[string]URLRecord
 	originalToShort map[string]string
 	userURLs        map[string][]BatchItem
 	logger          *zap.Logger
 	nextID          int
+
+	idempotencyKeys
+	apiKeys
+	keysFile *os.File
+	users
+	usersFile *os.File
+}
+
+// apiKeysFileSuffix — суффикс файла с API-ключами рядом с файлом ссылок.
+const apiKeysFileSuffix = ".keys"
+
+// usersFileSuffix — суффикс файла с учётными записями рядом с файлом ссылок.
+const usersFileSuffix = ".users"
+
+// userRecord — строка файла учётных записей.
+type userRecord struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// apiKeyRecord — строка файла API-ключей; при загрузке побеждает последняя строка для ID.
+type apiKeyRecord struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name,omitempty"`
+	Hash      string     `json:"hash"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
 }
 
 func NewFileStorage(path string, logger *zap.Logger) (*FileStorage, error) {
@@ -59,6 +125,16 @@ func NewFileStorage(path string, logger *zap.Logger) (*FileStorage, error) {
 		return nil, err
 	}
 
+	if err := fs.openAPIKeys(); err != nil {
+		fs.file.Close()
+		return nil, err
+	}
+	if err := fs.openUsers(); err != nil {
+		fs.keysFile.Close()
+		fs.file.Close()
+		return nil, err
+	}
+
 	logger.Info("File storage initialized",
 		zap.String("path", path),
 		zap.Int("count", len(fs.data)))
@@ -66,10 +142,26 @@ func NewFileStorage(path string, logger *zap.Logger) (*FileStorage, error) {
 	return fs, nil
 }
 
+// maxLineSize — максимальная длина строки файлов хранилища. Запись ссылки с правилами
+// и вариантами сплита может превышать 64 КиБ — предел bufio.Scanner по умолчанию.
+const maxLineSize = 16 << 20
+
+// newLineScanner возвращает построчный сканер файла хранилища с буфером до maxLineSize.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return scanner
+}
+
 func (fs *FileStorage) load() error {
-	scanner := bufio.NewScanner(fs.file)
+	scanner := newLineScanner(fs.file)
 
 	for scanner.Scan() {
+		if bytes.HasPrefix(scanner.Bytes(), clickDeltaPrefix) {
+			fs.applyClick(scanner.Bytes())
+			continue
+		}
+
 		var rec ShortURLRecord
 		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
 			fs.logger.Warn("invalid record",
@@ -78,12 +170,45 @@ func (fs *FileStorage) load() error {
 			continue
 		}
 
-		fs.data[rec.ShortURL] = URLRecord{
-			ShortID:     rec.ShortURL,
-			OriginalURL: rec.OriginalURL,
-			UserID:      "",
-			Deleted:     rec.Deleted,
+		prev, seen := fs.data[rec.ShortURL]
+		if seen && prev.UserID != rec.UserID {
+			// Ссылка перенесена на учётную запись через ClaimURLs.
+			fs.userURLs[prev.UserID] = removeUserURL(fs.userURLs[prev.UserID], rec.ShortURL)
+		}
+		if (!seen || prev.UserID != rec.UserID) && rec.UserID != "" {
+			fs.userURLs[rec.UserID] = append(fs.userURLs[rec.UserID], BatchItem{ShortID: rec.ShortURL, OriginalURL: rec.OriginalURL})
+		}
+		if seen && prev.OriginalURL != rec.OriginalURL {
+			// Адрес назначения изменён через UpdateLink.
+			if fs.originalToShort[prev.OriginalURL] == rec.ShortURL {
+				delete(fs.originalToShort, prev.OriginalURL)
+			}
+			renameUserURL(fs.userURLs[rec.UserID], rec.ShortURL, rec.OriginalURL)
+		}
+
+		stored := URLRecord{
+			ShortID:      rec.ShortURL,
+			OriginalURL:  rec.OriginalURL,
+			UserID:       rec.UserID,
+			Deleted:      rec.Deleted,
+			PasswordHash: rec.PasswordHash,
+			MaxClicks:    rec.MaxClicks,
+			ClicksLeft:   rec.ClicksLeft,
+			Rules:        rec.Rules,
+			Destinations: rec.Destinations,
+			RedirectCode: rec.RedirectCode,
+			Clicks:       rec.Clicks,
 		}
+		if rec.Forwarding != nil {
+			stored.Forwarding = *rec.Forwarding
+		}
+		if rec.CreatedAt != nil {
+			stored.CreatedAt = *rec.CreatedAt
+		}
+		if rec.ExpiresAt != nil {
+			stored.ExpiresAt = *rec.ExpiresAt
+		}
+		fs.data[rec.ShortURL] = stored
 		fs.originalToShort[rec.OriginalURL] = rec.ShortURL
 
 		if rec.UUID > fs.nextID {
@@ -94,6 +219,24 @@ func (fs *FileStorage) load() error {
 	return scanner.Err()
 }
 
+// applyClick применяет к загруженной ссылке строку clickDelta.
+func (fs *FileStorage) applyClick(line []byte) {
+	var delta clickDelta
+	if err := json.Unmarshal(line, &delta); err != nil {
+		fs.logger.Warn("invalid click record", zap.ByteString("line", line), zap.Error(err))
+		return
+	}
+	rec, ok := fs.data[delta.Click]
+	if !ok {
+		return
+	}
+	rec.Clicks++
+	if delta.Consumed {
+		rec.ClicksLeft--
+	}
+	fs.data[delta.Click] = rec
+}
+
 func (fs *FileStorage) Save(ctx context.Context, userID, id, url string) (string, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -103,11 +246,14 @@ func (fs *FileStorage) Save(ctx context.Context, userID, id, url string) (string
 	}
 
 	fs.nextID++
+	now := time.Now()
 	rec := ShortURLRecord{
 		UUID:        fs.nextID,
 		ShortURL:    id,
 		OriginalURL: url,
+		UserID:      userID,
 		Deleted:     false,
+		CreatedAt:   &now,
 	}
 
 	// save in-memory
@@ -116,6 +262,7 @@ func (fs *FileStorage) Save(ctx context.Context, userID, id, url string) (string
 		OriginalURL: url,
 		UserID:      userID,
 		Deleted:     false,
+		CreatedAt:   now,
 	}
 	fs.originalToShort[url] = id
 	fs.userURLs[userID] = append(fs.userURLs[userID], BatchItem{ShortID: id, OriginalURL: url})
@@ -133,6 +280,168 @@ func (fs *FileStorage) Save(ctx context.Context, userID, id, url string) (string
 	return id, nil
 }
 
+func (fs *FileStorage) SaveRecord(ctx context.Context, rec URLRecord) (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if existing, ok := fs.originalToShort[rec.OriginalURL]; ok {
+		return existing, ErrURLExists
+	}
+	if _, ok := fs.data[rec.ShortID]; ok {
+		return "", ErrShortIDExists
+	}
+
+	rec.Deleted = false
+	rec.ClicksLeft = rec.MaxClicks
+	rec.Clicks = 0
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	if err := fs.appendRecord(rec); err != nil {
+		return "", err
+	}
+
+	fs.data[rec.ShortID] = rec
+	fs.originalToShort[rec.OriginalURL] = rec.ShortID
+	fs.userURLs[rec.UserID] = append(fs.userURLs[rec.UserID], BatchItem{ShortID: rec.ShortID, OriginalURL: rec.OriginalURL})
+
+	return rec.ShortID, nil
+}
+
+func (fs *FileStorage) Lookup(ctx context.Context, originalURL string) (string, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	id, ok := fs.originalToShort[originalURL]
+	return id, ok
+}
+
+func (fs *FileStorage) ConsumeClick(ctx context.Context, id string) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rec, ok := fs.data[id]
+	if !ok || rec.MaxClicks <= 0 || rec.ClicksLeft <= 0 {
+		return 0, ErrLinkExhausted
+	}
+	rec.ClicksLeft--
+	rec.Clicks++
+	if err := fs.appendClick(clickDelta{Click: id, Consumed: true}); err != nil {
+		return 0, err
+	}
+	fs.data[id] = rec
+
+	return rec.ClicksLeft, nil
+}
+
+func (fs *FileStorage) RecordClick(ctx context.Context, id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rec, ok := fs.data[id]
+	if !ok {
+		return ErrLinkNotFound
+	}
+	rec.Clicks++
+	if err := fs.appendClick(clickDelta{Click: id}); err != nil {
+		return err
+	}
+	fs.data[id] = rec
+
+	return nil
+}
+
+func (fs *FileStorage) UpdateLink(ctx context.Context, userID, id string, upd LinkUpdate) (*URLRecord, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rec, ok := fs.data[id]
+	if !ok || rec.UserID != userID {
+		return nil, ErrLinkNotFound
+	}
+	if !upd.matches(rec) {
+		return nil, ErrLinkChanged
+	}
+	oldURL := rec.OriginalURL
+	if upd.OriginalURL != nil && *upd.OriginalURL != oldURL {
+		if _, ok := fs.originalToShort[*upd.OriginalURL]; ok {
+			return nil, ErrURLExists
+		}
+		rec.OriginalURL = *upd.OriginalURL
+	}
+	if upd.ExpiresAt != nil {
+		rec.ExpiresAt = *upd.ExpiresAt
+	}
+	if err := fs.appendRecord(rec); err != nil {
+		return nil, err
+	}
+
+	if rec.OriginalURL != oldURL {
+		delete(fs.originalToShort, oldURL)
+		fs.originalToShort[rec.OriginalURL] = id
+		renameUserURL(fs.userURLs[userID], id, rec.OriginalURL)
+	}
+	fs.data[id] = rec
+
+	c := rec
+	return &c, nil
+}
+
+// appendRecord дописывает актуальное состояние записи в конец файла.
+// При загрузке побеждает последняя строка для каждого короткого ID.
+// Вызывается под блокировкой.
+func (fs *FileStorage) appendRecord(rec URLRecord) error {
+	fs.nextID++
+	out := ShortURLRecord{
+		UUID:         fs.nextID,
+		ShortURL:     rec.ShortID,
+		OriginalURL:  rec.OriginalURL,
+		UserID:       rec.UserID,
+		Deleted:      rec.Deleted,
+		PasswordHash: rec.PasswordHash,
+		MaxClicks:    rec.MaxClicks,
+		ClicksLeft:   rec.ClicksLeft,
+		Rules:        rec.Rules,
+		Destinations: rec.Destinations,
+		RedirectCode: rec.RedirectCode,
+		Clicks:       rec.Clicks,
+	}
+	if !rec.Forwarding.IsZero() {
+		out.Forwarding = &rec.Forwarding
+	}
+	if !rec.CreatedAt.IsZero() {
+		out.CreatedAt = &rec.CreatedAt
+	}
+	if !rec.ExpiresAt.IsZero() {
+		out.ExpiresAt = &rec.ExpiresAt
+	}
+
+	bytes, err := json.Marshal(out)
+	if err != nil {
+		fs.logger.Error("Failed to marshal record", zap.Error(err))
+		return err
+	}
+
+	if _, err := fs.file.Write(append(bytes, '\n')); err != nil {
+		fs.logger.Error("Failed to append record to file", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// appendClick дописывает в конец файла строку clickDelta. Вызывается под блокировкой.
+func (fs *FileStorage) appendClick(delta clickDelta) error {
+	line, err := json.Marshal(delta)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		fs.logger.Error("Failed to append click to file", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func (fs *FileStorage) SaveBatch(ctx context.Context, userID string, batch []BatchItem) (map[string]string, map[string]string, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -146,17 +455,21 @@ func (fs *FileStorage) SaveBatch(ctx context.Context, userID string, batch []Bat
 			continue
 		}
 		fs.nextID++
+		now := time.Now()
 		rec := ShortURLRecord{
 			UUID:        fs.nextID,
 			ShortURL:    item.ShortID,
 			OriginalURL: item.OriginalURL,
+			UserID:      userID,
 			Deleted:     false,
+			CreatedAt:   &now,
 		}
 		fs.data[item.ShortID] = URLRecord{
 			ShortID:     item.ShortID,
 			OriginalURL: item.OriginalURL,
 			UserID:      userID,
 			Deleted:     false,
+			CreatedAt:   now,
 		}
 		fs.originalToShort[item.OriginalURL] = item.ShortID
 		fs.userURLs[userID] = append(fs.userURLs[userID], item)
@@ -169,6 +482,40 @@ func (fs *FileStorage) SaveBatch(ctx context.Context, userID string, batch []Bat
 	return newMap, conflictMap, nil
 }
 
+func (fs *FileStorage) SetRules(ctx context.Context, userID, id string, rules []RedirectRule) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rec, ok := fs.data[id]
+	if !ok || rec.UserID != userID {
+		return ErrLinkNotFound
+	}
+	rec.Rules = rules
+	if err := fs.appendRecord(rec); err != nil {
+		return err
+	}
+	fs.data[id] = rec
+
+	return nil
+}
+
+func (fs *FileStorage) SetDestinations(ctx context.Context, userID, id string, dests []Destination) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rec, ok := fs.data[id]
+	if !ok || rec.UserID != userID {
+		return ErrLinkNotFound
+	}
+	rec.Destinations = dests
+	if err := fs.appendRecord(rec); err != nil {
+		return err
+	}
+	fs.data[id] = rec
+
+	return nil
+}
+
 func (fs *FileStorage) Get(id string) (*URLRecord, bool) {
 	fs.mu.RLock()
 	defer fs.mu.RUnlock()
@@ -177,6 +524,8 @@ func (fs *FileStorage) Get(id string) (*URLRecord, bool) {
 		return nil, false
 	}
 	c := rec
+	c.Rules = append([]RedirectRule(nil), rec.Rules...)
+	c.Destinations = append([]Destination(nil), rec.Destinations...)
 	return &c, true
 }
 
@@ -187,7 +536,65 @@ func (fs *FileStorage) GetUserURLs(ctx context.Context, userID string) ([]BatchI
 	if !ok || len(list) == 0 {
 		return nil, nil
 	}
-	return list, nil
+	// Копия: UpdateLink меняет элементы списка под блокировкой, а вызывающий читает без неё.
+	return slices.Clone(list), nil
+}
+
+func (fs *FileStorage) ExportUserURLs(ctx context.Context, userID string, fn func(URLRecord) error) error {
+	fs.mu.RLock()
+	list := fs.userURLs[userID]
+	recs := make([]URLRecord, 0, len(list))
+	for _, item := range list {
+		if rec, ok := fs.data[item.ShortID]; ok {
+			recs = append(recs, rec)
+		}
+	}
+	fs.mu.RUnlock()
+
+	for _, rec := range recs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileStorage) ClaimURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	list := fs.userURLs[fromUserID]
+	claimed := 0
+	for i, item := range list {
+		rec, ok := fs.data[item.ShortID]
+		if !ok {
+			continue
+		}
+		rec.UserID = toUserID
+		if err := fs.appendRecord(rec); err != nil {
+			// Уже перенесённые ссылки записаны в файл, остальные остаются у fromUserID.
+			fs.userURLs[fromUserID] = list[i:]
+			return claimed, err
+		}
+		fs.data[item.ShortID] = rec
+		fs.userURLs[toUserID] = append(fs.userURLs[toUserID], item)
+		claimed++
+	}
+	delete(fs.userURLs, fromUserID)
+	return claimed, nil
+}
+
+// removeUserURL удаляет ссылку id из списка ссылок пользователя.
+func removeUserURL(list []BatchItem, id string) []BatchItem {
+	for i := range list {
+		if list[i].ShortID == id {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
 }
 
 func (fs *FileStorage) MarkDeleted(userID string, shorts []string) error {
@@ -205,15 +612,7 @@ func (fs *FileStorage) MarkDeleted(userID string, shorts []string) error {
 		rec.Deleted = true
 		fs.data[s] = rec
 
-		fs.nextID++
-		out := ShortURLRecord{
-			UUID:        fs.nextID,
-			ShortURL:    rec.ShortID,
-			OriginalURL: rec.OriginalURL,
-			Deleted:     true,
-		}
-		bytes, _ := json.Marshal(out)
-		_, _ = fs.file.Write(append(bytes, '\n'))
+		_ = fs.appendRecord(rec)
 	}
 
 	return nil
@@ -226,5 +625,122 @@ func (fs *FileStorage) Ping(ctx context.Context) error {
 func (fs *FileStorage) Close() error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
+	if fs.keysFile != nil {
+		fs.keysFile.Close()
+	}
+	if fs.usersFile != nil {
+		fs.usersFile.Close()
+	}
 	return fs.file.Close()
 }
+
+// openAPIKeys загружает API-ключи из файла <path>.keys и открывает его на дозапись.
+// Файл содержит хеши ключей, поэтому создаётся с правами 0600.
+func (fs *FileStorage) openAPIKeys() error {
+	path := fs.path + apiKeysFileSuffix
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open api keys file: %w", err)
+	}
+
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		var rec apiKeyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			fs.logger.Warn("invalid api key record", zap.Error(err))
+			continue
+		}
+		key := APIKey{
+			ID:        rec.ID,
+			UserID:    rec.UserID,
+			Name:      rec.Name,
+			Hash:      rec.Hash,
+			Scopes:    rec.Scopes,
+			CreatedAt: rec.CreatedAt,
+		}
+		if rec.ExpiresAt != nil {
+			key.ExpiresAt = *rec.ExpiresAt
+		}
+		if rec.RevokedAt != nil {
+			key.RevokedAt = *rec.RevokedAt
+		}
+		fs.putAPIKey(key)
+	}
+	if err := scanner.Err(); err != nil {
+		fs.logger.Warn("Failed to load api keys", zap.Error(err))
+	}
+
+	fs.keysFile = file
+	fs.persist = fs.appendAPIKey
+	return nil
+}
+
+// appendAPIKey дописывает состояние ключа в файл API-ключей.
+// Вызывается под блокировкой apiKeys.
+func (fs *FileStorage) appendAPIKey(key APIKey) error {
+	rec := apiKeyRecord{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Hash:      key.Hash,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if !key.ExpiresAt.IsZero() {
+		rec.ExpiresAt = &key.ExpiresAt
+	}
+	if !key.RevokedAt.IsZero() {
+		rec.RevokedAt = &key.RevokedAt
+	}
+
+	bytes, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.keysFile.Write(append(bytes, '\n')); err != nil {
+		fs.logger.Error("Failed to append api key to file", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// openUsers загружает учётные записи из файла <path>.users и открывает его на дозапись.
+// Файл содержит хеши паролей, поэтому создаётся с правами 0600.
+func (fs *FileStorage) openUsers() error {
+	path := fs.path + usersFileSuffix
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open users file: %w", err)
+	}
+
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		var rec userRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			fs.logger.Warn("invalid user record", zap.Error(err))
+			continue
+		}
+		fs.putUser(User(rec))
+	}
+	if err := scanner.Err(); err != nil {
+		fs.logger.Warn("Failed to load users", zap.Error(err))
+	}
+
+	fs.usersFile = file
+	fs.persistUser = fs.appendUser
+	return nil
+}
+
+// appendUser дописывает учётную запись в файл учётных записей.
+// Вызывается под блокировкой users.
+func (fs *FileStorage) appendUser(user User) error {
+	bytes, err := json.Marshal(userRecord(user))
+	if err != nil {
+		return err
+	}
+	if _, err := fs.usersFile.Write(append(bytes, '\n')); err != nil {
+		fs.logger.Error("Failed to append user to file", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// failingFile пропускает первые ok записей и затем возвращает ошибку.
+type failingFile struct {
+	recordFile
+	ok int
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.ok == 0 {
+		return 0, errors.New("disk full")
+	}
+	f.ok--
+	return f.recordFile.Write(p)
+}
+
+func TestFileStorage_ClaimURLsWriteFailure(t *testing.T) {
+	fs, err := NewFileStorage(filepath.Join(t.TempDir(), "storage.json"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("file storage: %v", err)
+	}
+	defer fs.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"a1", "a2", "a3"} {
+		_, err := fs.Save(ctx, "anon", id, "https://example.com/"+id)
+		assert.NoError(t, err)
+	}
+	// Ссылка без записи в data пропускается и не должна сбивать оставшийся список.
+	fs.userURLs["anon"] = append([]BatchItem{{ShortID: "gone"}}, fs.userURLs["anon"]...)
+	fs.file = &failingFile{recordFile: fs.file, ok: 1}
+
+	claimed, err := fs.ClaimURLs(ctx, "anon", "acc")
+	assert.Error(t, err)
+	assert.Equal(t, 1, claimed)
+
+	moved, err := fs.GetUserURLs(ctx, "acc")
+	assert.NoError(t, err)
+	if assert.Len(t, moved, 1) {
+		assert.Equal(t, "a1", moved[0].ShortID)
+	}
+	left, err := fs.GetUserURLs(ctx, "anon")
+	assert.NoError(t, err)
+	var ids []string
+	for _, item := range left {
+		ids = append(ids, item.ShortID)
+	}
+	assert.Equal(t, []string{"a2", "a3"}, ids, "claimed link is not left with the old owner")
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
 	"github.com/stretchr/testify/assert"
@@ -177,3 +179,365 @@ func TestFileStorage_BatchAndDeleted(t *testing.T) {
 		assert.False(t, rec2.Deleted)
 	})
 }
+
+func TestFileStorage_SaveRecord(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	filePath := filepath.Join(t.TempDir(), "record_test.jsonl")
+
+	fs, err := storage.NewFileStorage(filePath, logger)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	id, err := fs.SaveRecord(ctx, storage.URLRecord{
+		ShortID:      "locked",
+		OriginalURL:  "https://locked.com",
+		UserID:       "user1",
+		PasswordHash: "hash",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "locked", id)
+
+	id, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "other", OriginalURL: "https://locked.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+	assert.Equal(t, "locked", id)
+
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "locked", OriginalURL: "https://other.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrShortIDExists)
+
+	assert.NoError(t, fs.MarkDeleted("user1", []string{"locked"}))
+	fs.Close()
+
+	fs2, err := storage.NewFileStorage(filePath, logger)
+	assert.NoError(t, err)
+	defer fs2.Close()
+
+	rec, ok := fs2.Get("locked")
+	assert.True(t, ok)
+	assert.Equal(t, "user1", rec.UserID)
+	assert.Equal(t, "hash", rec.PasswordHash)
+	assert.True(t, rec.Deleted)
+}
+
+func TestFileStorage_ConsumeClick(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	filePath := filepath.Join(t.TempDir(), "clicks_test.jsonl")
+
+	fs, err := storage.NewFileStorage(filePath, logger)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "limited", OriginalURL: "https://limited.com", UserID: "u", MaxClicks: 5})
+	assert.NoError(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		success int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fs.ConsumeClick(ctx, "limited"); err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, success, "no double-spend under concurrent requests")
+
+	_, err = fs.ConsumeClick(ctx, "limited")
+	assert.ErrorIs(t, err, storage.ErrLinkExhausted)
+	fs.Close()
+
+	fs2, err := storage.NewFileStorage(filePath, logger)
+	assert.NoError(t, err)
+	defer fs2.Close()
+
+	rec, ok := fs2.Get("limited")
+	assert.True(t, ok)
+	assert.Equal(t, 5, rec.MaxClicks)
+	assert.Equal(t, 0, rec.ClicksLeft)
+	assert.Equal(t, 5, rec.Clicks)
+}
+
+func TestFileStorage_ClicksAreDeltas(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+
+	fs, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "s1", OriginalURL: "https://example.com/" + strings.Repeat("x", 500), UserID: "u1"})
+	assert.NoError(t, err)
+	before, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, fs.RecordClick(ctx, "s1"))
+	}
+	assert.NoError(t, fs.Close())
+
+	after, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Less(t, after.Size()-before.Size(), int64(3*64), "clicks do not copy the full record")
+
+	reopened, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	rec, ok := reopened.Get("s1")
+	assert.True(t, ok)
+	assert.Equal(t, 3, rec.Clicks)
+}
+
+func TestFileStorage_IdempotencyKeys(t *testing.T) {
+	logger := zap.NewNop()
+	fs, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "storage.json"), logger)
+	assert.NoError(t, err)
+	defer fs.Close()
+	ctx := context.Background()
+
+	rec := storage.IdempotencyRecord{UserID: "user1", Key: "k1", RequestHash: "h1", ExpiresAt: time.Now().Add(time.Hour)}
+
+	existing, err := fs.ClaimIdempotencyKey(ctx, rec)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = fs.ClaimIdempotencyKey(ctx, rec)
+	assert.NoError(t, err)
+	if assert.NotNil(t, existing) {
+		assert.False(t, existing.Completed)
+	}
+
+	other := rec
+	other.UserID = "user2"
+	existing, err = fs.ClaimIdempotencyKey(ctx, other)
+	assert.NoError(t, err)
+	assert.Nil(t, existing, "keys are scoped per user")
+
+	done := rec
+	done.Status, done.ContentType, done.Body = 201, "application/json", []byte(`{"result":"x"}`)
+	assert.NoError(t, fs.CompleteIdempotencyKey(ctx, done))
+	assert.NoError(t, fs.ReleaseIdempotencyKey(ctx, "user1", "k1"), "completed keys are kept")
+
+	existing, err = fs.ClaimIdempotencyKey(ctx, rec)
+	assert.NoError(t, err)
+	if assert.NotNil(t, existing) {
+		assert.True(t, existing.Completed)
+		assert.Equal(t, "h1", existing.RequestHash)
+		assert.Equal(t, 201, existing.Status)
+		assert.Equal(t, "application/json", existing.ContentType)
+		assert.Equal(t, `{"result":"x"}`, string(existing.Body))
+	}
+
+	assert.NoError(t, fs.ReleaseIdempotencyKey(ctx, "user2", "k1"))
+	existing, err = fs.ClaimIdempotencyKey(ctx, other)
+	assert.NoError(t, err)
+	assert.Nil(t, existing, "released key can be claimed again")
+
+	expired := storage.IdempotencyRecord{UserID: "user1", Key: "k2", RequestHash: "h1", ExpiresAt: time.Now().Add(-time.Second)}
+	_, err = fs.ClaimIdempotencyKey(ctx, expired)
+	assert.NoError(t, err)
+	expired.RequestHash = "h2"
+	existing, err = fs.ClaimIdempotencyKey(ctx, expired)
+	assert.NoError(t, err)
+	assert.Nil(t, existing, "expired key is replaced")
+}
+
+func TestFileStorage_UpdateLinkAndClicks(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fs, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "s1", OriginalURL: "https://old.example", UserID: "u1", ExpiresAt: expires})
+	assert.NoError(t, err)
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "s2", OriginalURL: "https://taken.example", UserID: "u1"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, fs.RecordClick(ctx, "s1"))
+	assert.NoError(t, fs.RecordClick(ctx, "s1"))
+	assert.ErrorIs(t, fs.RecordClick(ctx, "missing"), storage.ErrLinkNotFound)
+
+	newURL := "https://new.example"
+	_, err = fs.UpdateLink(ctx, "intruder", "s1", storage.LinkUpdate{OriginalURL: &newURL})
+	assert.ErrorIs(t, err, storage.ErrLinkNotFound)
+	taken := "https://taken.example"
+	_, err = fs.UpdateLink(ctx, "u1", "s1", storage.LinkUpdate{OriginalURL: &taken})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+	stale := storage.URLRecord{OriginalURL: "https://old.example"}
+	_, err = fs.UpdateLink(ctx, "u1", "s1", storage.LinkUpdate{OriginalURL: &newURL, Match: &stale})
+	assert.ErrorIs(t, err, storage.ErrLinkChanged, "expires_at differs from the match")
+
+	current, _ := fs.Get("s1")
+	rec, err := fs.UpdateLink(ctx, "u1", "s1", storage.LinkUpdate{OriginalURL: &newURL, Match: current})
+	assert.NoError(t, err)
+	assert.Equal(t, newURL, rec.OriginalURL)
+	assert.True(t, expires.Equal(rec.ExpiresAt), "expires_at is kept when not updated")
+	assert.NoError(t, fs.Close())
+
+	reopened, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	got, ok := reopened.Get("s1")
+	assert.True(t, ok)
+	assert.Equal(t, newURL, got.OriginalURL)
+	assert.Equal(t, 2, got.Clicks)
+	assert.True(t, expires.Equal(got.ExpiresAt))
+
+	id, ok := reopened.Lookup(ctx, newURL)
+	assert.True(t, ok)
+	assert.Equal(t, "s1", id)
+	_, ok = reopened.Lookup(ctx, "https://old.example")
+	assert.False(t, ok)
+
+	urls, err := reopened.GetUserURLs(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Contains(t, urls, storage.BatchItem{ShortID: "s1", OriginalURL: newURL})
+
+	moved := "https://moved.example"
+	_, err = reopened.UpdateLink(ctx, "u1", "s1", storage.LinkUpdate{OriginalURL: &moved})
+	assert.NoError(t, err)
+	assert.Contains(t, urls, storage.BatchItem{ShortID: "s1", OriginalURL: newURL}, "returned list is a copy")
+}
+
+func TestFileStorage_APIKeys(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	fs, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	created := time.Now().Truncate(time.Second)
+	first := storage.APIKey{ID: "k1", UserID: "user1", Name: "ci", Hash: "h1", Scopes: []string{storage.ScopeRead}, CreatedAt: created}
+	second := storage.APIKey{ID: "k2", UserID: "user1", Hash: "h2", Scopes: []string{storage.ScopeCreate, storage.ScopeRead}, CreatedAt: created.Add(time.Second), ExpiresAt: created.Add(time.Hour)}
+	foreign := storage.APIKey{ID: "k3", UserID: "user2", Hash: "h3", CreatedAt: created}
+	for _, k := range []storage.APIKey{second, first, foreign} {
+		assert.NoError(t, fs.CreateAPIKey(ctx, k))
+	}
+
+	key, err := fs.GetAPIKey(ctx, "h1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, key) {
+		assert.Equal(t, "user1", key.UserID)
+		assert.True(t, key.HasScope(storage.ScopeRead))
+		assert.False(t, key.HasScope(storage.ScopeDelete))
+	}
+	_, err = fs.GetAPIKey(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	_, err = fs.RevokeAPIKey(ctx, "user2", "k1")
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound, "keys of other users cannot be revoked")
+	revoked, err := fs.RevokeAPIKey(ctx, "user1", "k1")
+	assert.NoError(t, err)
+	assert.False(t, revoked.Active(time.Now()))
+	again, err := fs.RevokeAPIKey(ctx, "user1", "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, revoked.RevokedAt, again.RevokedAt, "repeated revoke keeps the time")
+	assert.NoError(t, fs.Close())
+
+	reopened, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	keys, err := reopened.ListAPIKeys(ctx, "user1")
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "k1", keys[0].ID)
+		assert.Equal(t, "ci", keys[0].Name)
+		assert.True(t, keys[0].RevokedAt.Equal(revoked.RevokedAt), "revocation survives restart")
+		assert.Equal(t, "k2", keys[1].ID)
+		assert.True(t, keys[1].ExpiresAt.Equal(second.ExpiresAt))
+		assert.Equal(t, second.Scopes, keys[1].Scopes)
+	}
+	key, err = reopened.GetAPIKey(ctx, "h2")
+	assert.NoError(t, err)
+	assert.True(t, key.Active(time.Now()))
+	assert.False(t, key.Active(created.Add(2*time.Hour)), "expired key is not active")
+}
+
+func TestFileStorage_UsersAndClaim(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	fs, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	user := storage.User{ID: "acc", Username: "alice", PasswordHash: "hash", CreatedAt: time.Now().Truncate(time.Second)}
+	assert.NoError(t, fs.CreateUser(ctx, user))
+	assert.ErrorIs(t, fs.CreateUser(ctx, storage.User{ID: "other", Username: "alice"}), storage.ErrUserExists)
+
+	_, err = fs.Save(ctx, "anon", "a1", "https://example.com/1")
+	assert.NoError(t, err)
+	_, err = fs.Save(ctx, "anon", "a2", "https://example.com/2")
+	assert.NoError(t, err)
+	_, err = fs.Save(ctx, "acc", "b1", "https://example.com/3")
+	assert.NoError(t, err)
+
+	claimed, err := fs.ClaimURLs(ctx, "anon", "acc")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+	claimed, err = fs.ClaimURLs(ctx, "anon", "acc")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, claimed)
+	assert.NoError(t, fs.Close())
+
+	reopened, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	got, err := reopened.GetUserByName(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "acc", got.ID)
+		assert.Equal(t, "hash", got.PasswordHash)
+		assert.True(t, user.CreatedAt.Equal(got.CreatedAt))
+	}
+	_, err = reopened.GetUser(ctx, "acc")
+	assert.NoError(t, err)
+	_, err = reopened.GetUser(ctx, "anon")
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	urls, err := reopened.GetUserURLs(ctx, "acc")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 3, "claimed links survive restart")
+	urls, err = reopened.GetUserURLs(ctx, "anon")
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	rec, ok := reopened.Get("a1")
+	if assert.True(t, ok) {
+		assert.Equal(t, "acc", rec.UserID)
+	}
+}
+
+func TestFileStorage_LongRecordLine(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+	long := "https://example.com/?q=" + strings.Repeat("x", 100*1024)
+
+	fs, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "big", OriginalURL: long, UserID: "u1"})
+	assert.NoError(t, err)
+	_, err = fs.SaveRecord(ctx, storage.URLRecord{ShortID: "next", OriginalURL: "https://example.com/next", UserID: "u1"})
+	assert.NoError(t, err)
+	assert.NoError(t, fs.Close())
+
+	reopened, err := storage.NewFileStorage(path, logger)
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	rec, ok := reopened.Get("big")
+	assert.True(t, ok, "lines over 64 KiB are loaded")
+	assert.Equal(t, long, rec.OriginalURL)
+	_, ok = reopened.Get("next")
+	assert.True(t, ok, "loading continues after a long line")
+}
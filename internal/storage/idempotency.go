@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idempotencySweepInterval — период удаления истёкших ключей идемпотентности из памяти.
+const idempotencySweepInterval = time.Minute
+
+// idempotencyKey — ключ записи идемпотентности: пользователь и значение заголовка.
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+// idempotencyKeys хранит ключи идемпотентности в памяти процесса.
+// Используется InMemoryStorage и FileStorage: ключи живут недолго (IDEMPOTENCY_TTL),
+// поэтому файловое хранилище их не сохраняет.
+type idempotencyKeys struct {
+	mu        sync.Mutex
+	records   map[idempotencyKey]IdempotencyRecord
+	nextSweep time.Time
+}
+
+// ClaimIdempotencyKey занимает ключ, если для него нет действующей записи.
+func (k *idempotencyKeys) ClaimIdempotencyKey(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	if k.records == nil {
+		k.records = make(map[idempotencyKey]IdempotencyRecord)
+	}
+	if now.After(k.nextSweep) {
+		for id, r := range k.records {
+			if !now.Before(r.ExpiresAt) {
+				delete(k.records, id)
+			}
+		}
+		k.nextSweep = now.Add(idempotencySweepInterval)
+	}
+
+	id := idempotencyKey{userID: rec.UserID, key: rec.Key}
+	if existing, ok := k.records[id]; ok && now.Before(existing.ExpiresAt) {
+		existing.Body = append([]byte(nil), existing.Body...)
+		return &existing, nil
+	}
+
+	rec.Completed = false
+	rec.Status, rec.ContentType, rec.Body = 0, "", nil
+	k.records[id] = rec
+	return nil, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ для занятого ключа.
+func (k *idempotencyKeys) CompleteIdempotencyKey(ctx context.Context, rec IdempotencyRecord) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	id := idempotencyKey{userID: rec.UserID, key: rec.Key}
+	stored, ok := k.records[id]
+	if !ok {
+		return nil
+	}
+	stored.Completed = true
+	stored.Status = rec.Status
+	stored.ContentType = rec.ContentType
+	stored.Body = append([]byte(nil), rec.Body...)
+	k.records[id] = stored
+	return nil
+}
+
+// ReleaseIdempotencyKey удаляет незавершённую запись ключа.
+func (k *idempotencyKeys) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	id := idempotencyKey{userID: userID, key: key}
+	if rec, ok := k.records[id]; ok && !rec.Completed {
+		delete(k.records, id)
+	}
+	return nil
+}
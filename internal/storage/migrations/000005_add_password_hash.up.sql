@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS max_clicks INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS clicks_left INTEGER NOT NULL DEFAULT 0;
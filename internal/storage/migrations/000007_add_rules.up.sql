@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS destinations JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS forwarding JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id VARCHAR(255) NOT NULL,
+    idem_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    status INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idem_key)
+    );
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
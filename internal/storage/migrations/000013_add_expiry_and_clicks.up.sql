@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS clicks INTEGER NOT NULL DEFAULT 0;
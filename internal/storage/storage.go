@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"slices"
 	"sync"
+	"time"
 )
 
 type InMemoryStorage struct {
@@ -10,6 +12,10 @@ type InMemoryStorage struct {
 	data            map[string]URLRecord
 	originalToShort map[string]string
 	userURLs        map[string][]BatchItem
+
+	idempotencyKeys
+	apiKeys
+	users
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
@@ -42,6 +48,7 @@ func (s *InMemoryStorage) SaveBatch(ctx context.Context, userID string, batch []
 			OriginalURL: item.OriginalURL,
 			UserID:      userID,
 			Deleted:     false,
+			CreatedAt:   time.Now(),
 		}
 
 		s.originalToShort[item.OriginalURL] = item.ShortID
@@ -67,6 +74,7 @@ func (s *InMemoryStorage) Save(ctx context.Context, userID, id, url string) (str
 		OriginalURL: url,
 		UserID:      userID,
 		Deleted:     false,
+		CreatedAt:   time.Now(),
 	}
 
 	s.data[id] = rec
@@ -76,6 +84,133 @@ func (s *InMemoryStorage) Save(ctx context.Context, userID, id, url string) (str
 	return id, nil
 }
 
+func (s *InMemoryStorage) SaveRecord(ctx context.Context, rec URLRecord) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.originalToShort[rec.OriginalURL]; ok {
+		return existing, ErrURLExists
+	}
+	if _, ok := s.data[rec.ShortID]; ok {
+		return "", ErrShortIDExists
+	}
+
+	rec.Deleted = false
+	rec.ClicksLeft = rec.MaxClicks
+	rec.Clicks = 0
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	s.data[rec.ShortID] = rec
+	s.originalToShort[rec.OriginalURL] = rec.ShortID
+	s.userURLs[rec.UserID] = append(s.userURLs[rec.UserID], BatchItem{ShortID: rec.ShortID, OriginalURL: rec.OriginalURL})
+
+	return rec.ShortID, nil
+}
+
+func (s *InMemoryStorage) Lookup(ctx context.Context, originalURL string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.originalToShort[originalURL]
+	return id, ok
+}
+
+func (s *InMemoryStorage) ConsumeClick(ctx context.Context, id string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.data[id]
+	if !ok || rec.MaxClicks <= 0 || rec.ClicksLeft <= 0 {
+		return 0, ErrLinkExhausted
+	}
+	rec.ClicksLeft--
+	rec.Clicks++
+	s.data[id] = rec
+
+	return rec.ClicksLeft, nil
+}
+
+func (s *InMemoryStorage) RecordClick(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.data[id]
+	if !ok {
+		return ErrLinkNotFound
+	}
+	rec.Clicks++
+	s.data[id] = rec
+
+	return nil
+}
+
+func (s *InMemoryStorage) UpdateLink(ctx context.Context, userID, id string, upd LinkUpdate) (*URLRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.data[id]
+	if !ok || rec.UserID != userID {
+		return nil, ErrLinkNotFound
+	}
+	if !upd.matches(rec) {
+		return nil, ErrLinkChanged
+	}
+	if upd.OriginalURL != nil && *upd.OriginalURL != rec.OriginalURL {
+		if _, ok := s.originalToShort[*upd.OriginalURL]; ok {
+			return nil, ErrURLExists
+		}
+		delete(s.originalToShort, rec.OriginalURL)
+		s.originalToShort[*upd.OriginalURL] = id
+		renameUserURL(s.userURLs[userID], id, *upd.OriginalURL)
+		rec.OriginalURL = *upd.OriginalURL
+	}
+	if upd.ExpiresAt != nil {
+		rec.ExpiresAt = *upd.ExpiresAt
+	}
+	s.data[id] = rec
+
+	c := rec
+	return &c, nil
+}
+
+// renameUserURL обновляет оригинальный URL ссылки id в списке ссылок пользователя.
+func renameUserURL(list []BatchItem, id, originalURL string) {
+	for i := range list {
+		if list[i].ShortID == id {
+			list[i].OriginalURL = originalURL
+		}
+	}
+}
+
+func (s *InMemoryStorage) SetRules(ctx context.Context, userID, id string, rules []RedirectRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.data[id]
+	if !ok || rec.UserID != userID {
+		return ErrLinkNotFound
+	}
+	rec.Rules = rules
+	s.data[id] = rec
+
+	return nil
+}
+
+func (s *InMemoryStorage) SetDestinations(ctx context.Context, userID, id string, dests []Destination) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.data[id]
+	if !ok || rec.UserID != userID {
+		return ErrLinkNotFound
+	}
+	rec.Destinations = dests
+	s.data[id] = rec
+
+	return nil
+}
+
 func (s *InMemoryStorage) Get(id string) (*URLRecord, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -84,6 +219,8 @@ func (s *InMemoryStorage) Get(id string) (*URLRecord, bool) {
 		return nil, false
 	}
 	c := rec
+	c.Rules = append([]RedirectRule(nil), rec.Rules...)
+	c.Destinations = append([]Destination(nil), rec.Destinations...)
 	return &c, true
 }
 
@@ -95,7 +232,48 @@ func (s *InMemoryStorage) GetUserURLs(ctx context.Context, userID string) ([]Bat
 	if !ok || len(list) == 0 {
 		return nil, nil
 	}
-	return list, nil
+	// Копия: UpdateLink меняет элементы списка под блокировкой, а вызывающий читает без неё.
+	return slices.Clone(list), nil
+}
+
+func (s *InMemoryStorage) ExportUserURLs(ctx context.Context, userID string, fn func(URLRecord) error) error {
+	s.mu.RLock()
+	list := s.userURLs[userID]
+	recs := make([]URLRecord, 0, len(list))
+	for _, item := range list {
+		if rec, ok := s.data[item.ShortID]; ok {
+			recs = append(recs, rec)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, rec := range recs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryStorage) ClaimURLs(ctx context.Context, fromUserID, toUserID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.userURLs[fromUserID]
+	for _, item := range list {
+		if rec, ok := s.data[item.ShortID]; ok {
+			rec.UserID = toUserID
+			s.data[item.ShortID] = rec
+		}
+	}
+	if len(list) > 0 {
+		s.userURLs[toUserID] = append(s.userURLs[toUserID], list...)
+		delete(s.userURLs, fromUserID)
+	}
+	return len(list), nil
 }
 
 func (s *InMemoryStorage) MarkDeleted(userID string, shorts []string) error {
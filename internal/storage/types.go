@@ -1,6 +1,9 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // URLRecord представляет одну запись URL в хранилище.
 // Используется как единая структура для всех типов хранилищ.
@@ -13,6 +16,94 @@ type URLRecord struct {
 	UserID string
 	// Deleted — флаг, помечающий URL как удалённый.
 	Deleted bool
+	// PasswordHash — bcrypt-хеш пароля для доступа к ссылке.
+	// Пустая строка означает, что ссылка не защищена паролем.
+	PasswordHash string
+	// MaxClicks — максимальное число переходов по ссылке; 0 означает без ограничений.
+	MaxClicks int
+	// ClicksLeft — оставшееся число переходов для ссылок с MaxClicks > 0.
+	ClicksLeft int
+	// Rules — упорядоченный список правил условного редиректа.
+	// Если ни одно правило не подошло, используется OriginalURL.
+	Rules []RedirectRule
+	// Destinations — взвешенные варианты адреса для A/B-сплита.
+	// Если список пуст, редирект выполняется на OriginalURL.
+	Destinations []Destination
+	// Forwarding — настройки передачи query-параметров, UTM-меток и суффикса пути.
+	Forwarding Forwarding
+	// RedirectCode — HTTP-код редиректа (301, 302, 307 или 308);
+	// 0 означает код по умолчанию из конфигурации.
+	RedirectCode int
+	// CreatedAt — время создания ссылки; нулевое значение, если оно неизвестно
+	// (записи, созданные до появления этого поля).
+	CreatedAt time.Time
+	// ExpiresAt — момент, после которого ссылка перестаёт работать; нулевое значение — бессрочно.
+	ExpiresAt time.Time
+	// Clicks — число переходов по ссылке с момента появления этого поля.
+	Clicks int
+}
+
+// Expired сообщает, что срок действия ссылки истёк к моменту now.
+func (r *URLRecord) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// LinkUpdate описывает частичное изменение ссылки; nil-поля не меняются.
+type LinkUpdate struct {
+	// OriginalURL — новый адрес назначения.
+	OriginalURL *string
+	// ExpiresAt — новый срок действия; указатель на нулевое время снимает ограничение.
+	ExpiresAt *time.Time
+	// Match — если не nil, изменение применяется, только пока адрес назначения, срок действия
+	// и пометка удаления ссылки совпадают с Match (условное обновление для If-Match);
+	// иначе возвращается ErrLinkChanged.
+	Match *URLRecord
+}
+
+// matches сообщает, что rec не изменилась относительно условия upd.Match.
+func (upd LinkUpdate) matches(rec URLRecord) bool {
+	m := upd.Match
+	return m == nil || rec.OriginalURL == m.OriginalURL && rec.ExpiresAt.Equal(m.ExpiresAt) && rec.Deleted == m.Deleted
+}
+
+// Forwarding описывает, что из входящего запроса переносится в адрес назначения.
+type Forwarding struct {
+	// PassQuery — добавлять query-параметры посетителя к адресу назначения.
+	PassQuery bool `json:"pass_query,omitempty"`
+	// PathSuffix — перенаправлять /{id}/rest/of/path на destination/rest/of/path.
+	PathSuffix bool `json:"path_suffix,omitempty"`
+	// UTM — UTM-метки (utm_source, utm_campaign, ...), добавляемые к адресу назначения.
+	// В значениях поддерживаются шаблоны {id} и {variant}.
+	UTM map[string]string `json:"utm,omitempty"`
+}
+
+// IsZero сообщает, что настройки передачи не заданы.
+func (f Forwarding) IsZero() bool {
+	return !f.PassQuery && !f.PathSuffix && len(f.UTM) == 0
+}
+
+// Destination описывает один вариант адреса назначения в A/B-сплите.
+type Destination struct {
+	// Name — метка варианта для аудита; если пусто, используется порядковый номер.
+	Name string `json:"name,omitempty"`
+	// URL — адрес назначения варианта.
+	URL string `json:"url"`
+	// Weight — относительный вес варианта, например 70 и 30.
+	Weight int `json:"weight"`
+}
+
+// RedirectRule описывает правило условного редиректа.
+// Правило срабатывает, если выполнены все заданные (непустые) условия;
+// правило без условий срабатывает всегда.
+type RedirectRule struct {
+	// Platform — платформа посетителя по User-Agent: ios, android, windows, macos или linux.
+	Platform string `json:"platform,omitempty"`
+	// Language — язык из Accept-Language, например "en" или "pt-BR".
+	Language string `json:"language,omitempty"`
+	// Country — код страны ISO 3166-1 alpha-2 по базе GeoIP, например "DE".
+	Country string `json:"country,omitempty"`
+	// URL — адрес, на который выполняется редирект при срабатывании правила.
+	URL string `json:"url"`
 }
 
 // BatchItem используется для пакетного сохранения URL.
@@ -23,6 +114,86 @@ type BatchItem struct {
 	OriginalURL string
 }
 
+// IdempotencyRecord — ответ на запрос с заголовком Idempotency-Key,
+// сохранённый для повтора при ретраях клиента.
+type IdempotencyRecord struct {
+	// UserID — пользователь, в пределах которого уникален ключ.
+	UserID string
+	// Key — значение заголовка Idempotency-Key.
+	Key string
+	// RequestHash — хеш метода, пути и тела первого запроса.
+	RequestHash string
+	// Completed — ответ сохранён; false, пока первый запрос ещё выполняется.
+	Completed bool
+	// Status — HTTP-статус сохранённого ответа.
+	Status int
+	// ContentType — Content-Type сохранённого ответа.
+	ContentType string
+	// Body — тело сохранённого ответа.
+	Body []byte
+	// ExpiresAt — момент, после которого ключ можно использовать заново.
+	ExpiresAt time.Time
+}
+
+// Области действия (scopes) API-ключа.
+const (
+	// ScopeCreate разрешает создавать и изменять ссылки (POST, PUT, PATCH).
+	ScopeCreate = "create"
+	// ScopeRead разрешает читать ссылки пользователя (GET).
+	ScopeRead = "read"
+	// ScopeDelete разрешает удалять ссылки (DELETE).
+	ScopeDelete = "delete"
+)
+
+// APIKey — ключ программного доступа к API от имени пользователя.
+// Сам ключ не хранится: только его SHA-256 хеш.
+type APIKey struct {
+	// ID — публичный идентификатор ключа; входит в сам ключ и показывается в списке.
+	ID string
+	// UserID — пользователь, от имени которого действует ключ.
+	UserID string
+	// Name — описание ключа для владельца.
+	Name string
+	// Hash — SHA-256 хеш ключа в hex.
+	Hash string
+	// Scopes — разрешённые области действия: ScopeCreate, ScopeRead, ScopeDelete.
+	Scopes []string
+	// CreatedAt — время создания ключа.
+	CreatedAt time.Time
+	// ExpiresAt — момент, после которого ключ не принимается; нулевое значение — бессрочно.
+	ExpiresAt time.Time
+	// RevokedAt — время отзыва ключа; нулевое значение — ключ не отозван.
+	RevokedAt time.Time
+}
+
+// Active сообщает, что ключ не отозван и не истёк к моменту now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
+
+// HasScope сообщает, разрешена ли ключу область действия scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// User — зарегистрированная учётная запись.
+// Анонимные пользователи в хранилище не записываются: их ID живёт только в токене.
+type User struct {
+	// ID — идентификатор пользователя; используется как UserID ссылок и sub токена.
+	ID string
+	// Username — имя для входа в нижнем регистре, уникальное.
+	Username string
+	// PasswordHash — bcrypt-хеш пароля.
+	PasswordHash string
+	// CreatedAt — время регистрации.
+	CreatedAt time.Time
+}
+
 // Storage описывает интерфейс хранилища URL.
 // Поддерживает как единичное, так и пакетное сохранение,
 // получение URL по короткому идентификатору, список URL пользователя,
@@ -39,6 +210,17 @@ type Storage interface {
 	//   - error: ErrURLExists если URL уже существует, либо другую ошибку.
 	Save(ctx context.Context, userID, id, url string) (string, error)
 
+	// SaveRecord сохраняет запись URL вместе с дополнительными атрибутами ссылки
+	// (например, хешем пароля). Владелец берётся из rec.UserID.
+	// Параметры:
+	//   - ctx: context запроса для контроля таймаута и отмены.
+	//   - rec: запись для сохранения.
+	// Возвращает:
+	//   - string: короткий идентификатор сохранённого или уже существующего URL.
+	//   - error: ErrURLExists если URL уже существует (атрибуты при этом не применяются),
+	//     ErrShortIDExists если rec.ShortID уже занят другой ссылкой, либо другую ошибку.
+	SaveRecord(ctx context.Context, rec URLRecord) (string, error)
+
 	// Get возвращает запись URL по короткому идентификатору.
 	// Параметры:
 	//   - id: короткий идентификатор URL.
@@ -47,6 +229,68 @@ type Storage interface {
 	//   - bool: true если запись найдена, false если не найдена.
 	Get(id string) (*URLRecord, bool)
 
+	// Lookup выполняет обратный поиск короткого идентификатора по оригинальному URL.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - originalURL: оригинальный URL.
+	// Возвращает:
+	//   - string: короткий идентификатор.
+	//   - bool: true если URL найден, false если не найден.
+	Lookup(ctx context.Context, originalURL string) (string, bool)
+
+	// ConsumeClick атомарно уменьшает счётчик оставшихся переходов ссылки с ограничением MaxClicks
+	// и увеличивает счётчик Clicks. Параллельные вызовы не могут израсходовать больше переходов,
+	// чем было задано.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - id: короткий идентификатор URL.
+	// Возвращает:
+	//   - int: число переходов, оставшихся после текущего.
+	//   - error: ErrLinkExhausted если переходы закончились или ссылка не ограничена, либо другую ошибку.
+	ConsumeClick(ctx context.Context, id string) (int, error)
+
+	// RecordClick увеличивает счётчик переходов Clicks ссылки без ограничения MaxClicks.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - id: короткий идентификатор URL.
+	// Возвращает:
+	//   - error: ErrLinkNotFound если ссылка не найдена, либо другую ошибку.
+	RecordClick(ctx context.Context, id string) error
+
+	// UpdateLink частично изменяет ссылку пользователя: адрес назначения и срок действия.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор владельца ссылки.
+	//   - id: короткий идентификатор URL.
+	//   - upd: изменяемые поля.
+	// Возвращает:
+	//   - *URLRecord: запись после изменения.
+	//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю,
+	//     ErrURLExists если новый адрес уже сокращён другой ссылкой, либо другую ошибку.
+	UpdateLink(ctx context.Context, userID, id string, upd LinkUpdate) (*URLRecord, error)
+
+	// SetRules заменяет список правил условного редиректа ссылки.
+	// Пустой список удаляет все правила.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор владельца ссылки.
+	//   - id: короткий идентификатор URL.
+	//   - rules: новый упорядоченный список правил.
+	// Возвращает:
+	//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю, либо другую ошибку.
+	SetRules(ctx context.Context, userID, id string, rules []RedirectRule) error
+
+	// SetDestinations заменяет взвешенные варианты адреса назначения ссылки.
+	// Пустой список отключает A/B-сплит.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор владельца ссылки.
+	//   - id: короткий идентификатор URL.
+	//   - dests: новый список вариантов.
+	// Возвращает:
+	//   - error: ErrLinkNotFound если ссылка не найдена или принадлежит другому пользователю, либо другую ошибку.
+	SetDestinations(ctx context.Context, userID, id string, dests []Destination) error
+
 	// SaveBatch сохраняет несколько URL одним батчем.
 	// Параметры:
 	//   - ctx: context запроса.
@@ -74,6 +318,115 @@ type Storage interface {
 	//   - error: ошибка запроса.
 	GetUserURLs(ctx context.Context, userID string) ([]BatchItem, error)
 
+	// ExportUserURLs последовательно передаёт в fn все ссылки пользователя, включая удалённые,
+	// не загружая их в память целиком. Ошибка fn прерывает обход и возвращается вызывающему.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор пользователя.
+	//   - fn: обработчик очередной записи.
+	// Возвращает:
+	//   - error: ошибка хранилища или ошибка, возвращённая fn.
+	ExportUserURLs(ctx context.Context, userID string, fn func(URLRecord) error) error
+
+	// ClaimIdempotencyKey атомарно занимает ключ идемпотентности пользователя
+	// незавершённой записью rec (Completed = false). Истёкшая запись с тем же ключом заменяется.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - rec: запись с UserID, Key, RequestHash и ExpiresAt.
+	// Возвращает:
+	//   - *IdempotencyRecord: nil, если ключ занят этим вызовом, иначе действующая запись с этим ключом.
+	//   - error: ошибка хранилища.
+	ClaimIdempotencyKey(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error)
+
+	// CompleteIdempotencyKey сохраняет ответ для ключа, занятого ClaimIdempotencyKey.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - rec: запись с UserID, Key и полями ответа (Status, ContentType, Body).
+	// Возвращает:
+	//   - error: ошибка хранилища.
+	CompleteIdempotencyKey(ctx context.Context, rec IdempotencyRecord) error
+
+	// ReleaseIdempotencyKey освобождает незавершённый ключ, чтобы клиент мог повторить запрос.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор пользователя.
+	//   - key: ключ идемпотентности.
+	// Возвращает:
+	//   - error: ошибка хранилища.
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+
+	// CreateAPIKey сохраняет новый API-ключ пользователя.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - key: ключ с ID, UserID, Hash и атрибутами.
+	// Возвращает:
+	//   - error: ошибка хранилища.
+	CreateAPIKey(ctx context.Context, key APIKey) error
+
+	// GetAPIKey находит API-ключ по хешу, в том числе отозванный или истёкший.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - hash: SHA-256 хеш ключа в hex.
+	// Возвращает:
+	//   - *APIKey: найденный ключ.
+	//   - error: ErrAPIKeyNotFound если ключ не найден, либо другую ошибку.
+	GetAPIKey(ctx context.Context, hash string) (*APIKey, error)
+
+	// ListAPIKeys возвращает API-ключи пользователя, включая отозванные, в порядке создания.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор пользователя.
+	// Возвращает:
+	//   - []APIKey: ключи пользователя.
+	//   - error: ошибка хранилища.
+	ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+
+	// RevokeAPIKey отзывает API-ключ пользователя; повторный отзыв не меняет RevokedAt.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - userID: идентификатор владельца ключа.
+	//   - id: идентификатор ключа.
+	// Возвращает:
+	//   - *APIKey: ключ после отзыва.
+	//   - error: ErrAPIKeyNotFound если ключ не найден или принадлежит другому пользователю, либо другую ошибку.
+	RevokeAPIKey(ctx context.Context, userID, id string) (*APIKey, error)
+
+	// CreateUser сохраняет новую учётную запись.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - user: учётная запись с ID, Username и PasswordHash.
+	// Возвращает:
+	//   - error: ErrUserExists если имя занято, либо другую ошибку.
+	CreateUser(ctx context.Context, user User) error
+
+	// GetUser находит учётную запись по ID.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - id: идентификатор пользователя.
+	// Возвращает:
+	//   - *User: найденная учётная запись.
+	//   - error: ErrUserNotFound если учётной записи нет (например, пользователь анонимный), либо другую ошибку.
+	GetUser(ctx context.Context, id string) (*User, error)
+
+	// GetUserByName находит учётную запись по имени.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - username: имя в нижнем регистре.
+	// Возвращает:
+	//   - *User: найденная учётная запись.
+	//   - error: ErrUserNotFound если учётной записи нет, либо другую ошибку.
+	GetUserByName(ctx context.Context, username string) (*User, error)
+
+	// ClaimURLs переносит все ссылки пользователя fromUserID на пользователя toUserID.
+	// Параметры:
+	//   - ctx: context запроса.
+	//   - fromUserID: анонимный пользователь, чьи ссылки переносятся.
+	//   - toUserID: учётная запись, получающая ссылки.
+	// Возвращает:
+	//   - int: число перенесённых ссылок.
+	//   - error: ошибка хранилища.
+	ClaimURLs(ctx context.Context, fromUserID, toUserID string) (int, error)
+
 	// MarkDeleted помечает список URL как удалённые для указанного пользователя.
 	// Параметры:
 	//   - userID: идентификатор пользователя.
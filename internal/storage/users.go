@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// users хранит учётные записи в памяти процесса.
+// Используется InMemoryStorage и FileStorage; FileStorage задаёт persistUser,
+// чтобы учётные записи переживали перезапуск.
+type users struct {
+	usersMu     sync.RWMutex
+	usersByID   map[string]User
+	usersByName map[string]string
+	// persistUser сохраняет новую учётную запись; вызывается под блокировкой.
+	persistUser func(User) error
+}
+
+// putUser записывает учётную запись в память. Вызывается под блокировкой.
+func (u *users) putUser(user User) {
+	if u.usersByID == nil {
+		u.usersByID = make(map[string]User)
+		u.usersByName = make(map[string]string)
+	}
+	u.usersByID[user.ID] = user
+	u.usersByName[user.Username] = user.ID
+}
+
+// CreateUser сохраняет новую учётную запись.
+func (u *users) CreateUser(ctx context.Context, user User) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
+	if _, ok := u.usersByName[user.Username]; ok {
+		return ErrUserExists
+	}
+	if u.persistUser != nil {
+		if err := u.persistUser(user); err != nil {
+			return err
+		}
+	}
+	u.putUser(user)
+	return nil
+}
+
+// GetUser находит учётную запись по ID.
+func (u *users) GetUser(ctx context.Context, id string) (*User, error) {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
+
+	user, ok := u.usersByID[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// GetUserByName находит учётную запись по имени.
+func (u *users) GetUserByName(ctx context.Context, username string) (*User, error) {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
+
+	id, ok := u.usersByName[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := u.usersByID[id]
+	return &user, nil
+}
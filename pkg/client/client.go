@@ -0,0 +1,366 @@
+// Package client — Go SDK для HTTP API сервиса сокращения ссылок.
+//
+// Client оборачивает эндпоинты сервиса в типизированные методы, хранит токен
+// пользователя (cookie auth_token) и сжимает запросы и ответы gzip.
+//
+//	c, err := client.New("http://localhost:8080",
+//		client.WithTokenStore(client.FileTokenStore{Path: "token"}))
+//	if err != nil {
+//		return err
+//	}
+//	short, err := c.Shorten(ctx, "https://example.com")
+//	var conflict *client.ConflictError
+//	if errors.As(err, &conflict) {
+//		short = conflict.ShortURL
+//	}
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/handler"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/problem"
+)
+
+// authCookie — имя cookie, в которой сервер выдаёт токен пользователя.
+const authCookie = "auth_token"
+
+// Типы запросов и ответов API.
+type (
+	// ShortenRequest — тело POST /api/shorten.
+	ShortenRequest = handler.RequestJSON
+	// ShortenResponse — ответ POST /api/shorten.
+	ShortenResponse = handler.ResponseJSON
+	// BatchRequestItem — элемент тела POST /api/shorten/batch.
+	BatchRequestItem = handler.BatchRequestItem
+	// BatchResponseItem — элемент ответа POST /api/shorten/batch.
+	BatchResponseItem = handler.BatchResponseItem
+	// UserURL — элемент ответа GET /api/user/urls.
+	UserURL = handler.UserURL
+	// ExpandResponse — ответ GET /api/expand/{id}.
+	ExpandResponse = handler.ExpandResponse
+)
+
+// Client — клиент HTTP API сервиса. Безопасен для конкурентного использования.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	gzip    bool
+	store   TokenStore
+
+	mu    sync.Mutex
+	token string
+}
+
+// Option настраивает Client.
+type Option func(*Client)
+
+// WithHTTPClient задаёт http.Client для запросов (по умолчанию http.DefaultClient).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithGzip включает или отключает сжатие тел запросов и ответов (по умолчанию включено).
+func WithGzip(enabled bool) Option {
+	return func(c *Client) {
+		c.gzip = enabled
+	}
+}
+
+// WithToken задаёт начальный токен пользователя.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithTokenStore задаёт хранилище токена: токен читается при создании клиента
+// и сохраняется, когда сервер выдаёт новый.
+func WithTokenStore(store TokenStore) Option {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// New создаёт клиент для сервиса по адресу baseURL, например http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("shortener: invalid base url %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    http.DefaultClient,
+		gzip:    true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.store != nil && c.token == "" {
+		if c.token, err = c.store.Load(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Token возвращает текущий токен пользователя.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Shorten сокращает rawURL через POST /api/shorten и возвращает короткую ссылку.
+// Если URL уже сокращён, возвращает существующую ссылку и *ConflictError.
+func (c *Client) Shorten(ctx context.Context, rawURL string) (string, error) {
+	var resp ShortenResponse
+	status, err := c.doJSON(ctx, http.MethodPost, "/api/shorten", ShortenRequest{URL: rawURL}, &resp, http.StatusConflict)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusConflict {
+		return resp.Result, &ConflictError{ShortURL: resp.Result}
+	}
+	return resp.Result, nil
+}
+
+// ShortenBatch сокращает несколько URL через POST /api/shorten/batch.
+// Результат каждого элемента — в поле Status ответа (created, exists или error).
+func (c *Client) ShortenBatch(ctx context.Context, items []BatchRequestItem) ([]BatchResponseItem, error) {
+	var resp []BatchResponseItem
+	if _, err := c.doJSON(ctx, http.MethodPost, "/api/shorten/batch", items, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UserURLs возвращает ссылки текущего пользователя через GET /api/user/urls.
+// Если ссылок нет, возвращает пустой срез.
+func (c *Client) UserURLs(ctx context.Context) ([]UserURL, error) {
+	resp := []UserURL{}
+	if _, err := c.doJSON(ctx, http.MethodGet, "/api/user/urls", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteURLs ставит ссылки пользователя в очередь на удаление через DELETE /api/user/urls.
+// Удаление асинхронное: ссылки помечаются удалёнными после ответа сервера.
+func (c *Client) DeleteURLs(ctx context.Context, ids []string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/api/user/urls", ids, nil)
+	return err
+}
+
+// Expand возвращает исходный адрес и статус короткой ссылки через GET /api/expand/{id}.
+func (c *Client) Expand(ctx context.Context, id string) (*ExpandResponse, error) {
+	var resp ExpandResponse
+	if _, err := c.doJSON(ctx, http.MethodGet, "/api/expand/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Resolve выполняет переход по короткой ссылке GET /{id} без следования редиректу
+// и возвращает адрес из Location. Для удалённой или истёкшей ссылки возвращает ErrGone.
+func (c *Client) Resolve(ctx context.Context, id string) (string, error) {
+	hc := *c.http
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := c.do(ctx, &hc, http.MethodGet, "/"+url.PathEscape(id), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("shortener: unexpected response %d without Location", resp.StatusCode)
+	}
+	return location, nil
+}
+
+// Ping проверяет доступность сервиса и хранилища через GET /ping.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.doJSON(ctx, http.MethodGet, "/ping", nil, nil)
+	return err
+}
+
+// doJSON отправляет in в JSON (если не nil) и декодирует ответ в out (если не nil и тело не пустое).
+// Коды из accept, как и 2xx, считаются успешными: их тело тоже декодируется в out.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any, accept ...int) (int, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return 0, err
+		}
+	}
+
+	resp, err := c.do(ctx, c.http, method, path, body, accept...)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return resp.StatusCode, fmt.Errorf("shortener: decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// do выполняет запрос: сжимает тело JSON, передаёт токен в cookie, сохраняет
+// новый токен из ответа и разжимает тело ответа. Ответы с кодом ≥ 400, кроме
+// перечисленных в accept, возвращаются как *APIError.
+func (c *Client) do(ctx context.Context, hc *http.Client, method, path string, body []byte, accept ...int) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+		if c.gzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(body); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			reader = &buf
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if c.gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+	}
+	if c.gzip {
+		// Явный Accept-Encoding отключает прозрачное разжатие в http.Transport.
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if token := c.Token(); token != "" {
+		req.AddCookie(&http.Cookie{Name: authCookie, Value: token})
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.saveToken(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		resp.Body = &gzipBody{raw: resp.Body}
+		resp.Header.Del("Content-Encoding")
+	}
+
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+	for _, code := range accept {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	return nil, readAPIError(resp)
+}
+
+// saveToken запоминает токен из cookie auth_token ответа и сохраняет его в TokenStore.
+func (c *Client) saveToken(resp *http.Response) error {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name != authCookie {
+			continue
+		}
+		token := cookie.Value
+		if cookie.MaxAge < 0 {
+			token = ""
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if token == c.token {
+			return nil
+		}
+		c.token = token
+		if c.store != nil {
+			return c.store.Save(token)
+		}
+		return nil
+	}
+	return nil
+}
+
+// readAPIError собирает *APIError из ответа с ошибкой.
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var p problem.Problem
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), problem.ContentType) && json.Unmarshal(data, &p) == nil {
+		apiErr.Type = p.Type
+		apiErr.Title = p.Title
+		apiErr.Detail = p.Detail
+		apiErr.RequestID = p.RequestID
+		return apiErr
+	}
+	apiErr.Body = strings.TrimSpace(string(data))
+	return apiErr
+}
+
+// gzipBody разжимает тело ответа при первом чтении; пустое тело
+// (например, у 202 Accepted) читается как пустое, а не как ошибка gzip.
+type gzipBody struct {
+	raw io.ReadCloser
+	zr  *gzip.Reader
+	eof bool
+}
+
+// Read читает разжатые данные.
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.eof {
+		return 0, io.EOF
+	}
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.raw)
+		if errors.Is(err, io.EOF) {
+			g.eof = true
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		g.zr = zr
+	}
+	return g.zr.Read(p)
+}
+
+// Close закрывает исходное тело ответа.
+func (g *gzipBody) Close() error {
+	return g.raw.Close()
+}
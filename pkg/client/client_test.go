@@ -0,0 +1,175 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/api"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/audit"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/auth"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/config"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/router"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/service"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/internal/storage"
+	"github.com/BuJIKuH/go-musthave-shortener-tpl/pkg/client"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testServer запускает httptest сервер с настоящим маршрутизатором и хранилищем в памяти.
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	spec, err := api.LoadOpenAPI()
+	if err != nil {
+		t.Fatalf("load openapi: %v", err)
+	}
+
+	logger := zap.NewNop()
+	am, err := auth.NewManager("test-secret")
+	if err != nil {
+		t.Fatalf("auth manager: %v", err)
+	}
+	store := storage.NewInMemoryStorage()
+	deleter := service.NewDeleter(store.MarkDeleted)
+	t.Cleanup(deleter.Close)
+
+	srv := httptest.NewUnstartedServer(nil)
+	cfg := &config.Config{ShortenAddress: "http://" + srv.Listener.Addr().String(), RedirectCode: http.StatusTemporaryRedirect, IdempotencyTTL: time.Hour}
+	srv.Config.Handler = router.New(cfg, store, am, deleter,
+		audit.NewService(logger), audit.NewBroker(0), service.NewPasswordLimiter(), service.NewLoginLimiter(), nil, spec, logger)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newClient создаёт клиент для srv.
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(srv.URL, append([]client.Option{client.WithHTTPClient(srv.Client())}, opts...)...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	srv := testServer(t)
+	ctx := context.Background()
+	tokenPath := filepath.Join(t.TempDir(), "shortener", "token")
+	c := newClient(t, srv, client.WithTokenStore(client.FileTokenStore{Path: tokenPath}))
+
+	assert.NoError(t, c.Ping(ctx))
+
+	short, err := c.Shorten(ctx, "https://example.com/sdk")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(short, srv.URL+"/"), short)
+	assert.NotEmpty(t, c.Token())
+
+	t.Run("token is persisted", func(t *testing.T) {
+		data, err := os.ReadFile(tokenPath)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Token(), strings.TrimSpace(string(data)))
+		info, err := os.Stat(tokenPath)
+		if assert.NoError(t, err) {
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		}
+
+		// Новый клиент с тем же хранилищем видит ссылки того же пользователя.
+		again := newClient(t, srv, client.WithTokenStore(client.FileTokenStore{Path: tokenPath}), client.WithGzip(false))
+		urls, err := again.UserURLs(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []client.UserURL{{ShortURL: short, OriginalURL: "https://example.com/sdk"}}, urls)
+	})
+
+	t.Run("duplicate in memory storage", func(t *testing.T) {
+		// Хранилище в памяти отвечает на повтор 201 с существующей ссылкой.
+		existing, err := c.Shorten(ctx, "https://example.com/sdk")
+		assert.NoError(t, err)
+		assert.Equal(t, short, existing)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		resp, err := c.ShortenBatch(ctx, []client.BatchRequestItem{
+			{CorrelationID: "1", OriginalURL: "https://example.com/b1"},
+			{CorrelationID: "2", OriginalURL: "https://example.com/b2"},
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, resp, 2) {
+			assert.Equal(t, "1", resp[0].CorrelationID)
+			assert.NotEmpty(t, resp[0].ShortURL)
+		}
+
+		urls, err := c.UserURLs(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, urls, 3)
+	})
+
+	t.Run("expand and resolve", func(t *testing.T) {
+		id := short[strings.LastIndex(short, "/")+1:]
+		exp, err := c.Expand(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/sdk", exp.OriginalURL)
+
+		location, err := c.Resolve(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/sdk", location)
+
+		_, err = c.Expand(ctx, "missing")
+		assert.True(t, errors.Is(err, client.ErrNotFound))
+		var apiErr *client.APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+			assert.NotEmpty(t, apiErr.Title)
+		}
+	})
+
+	t.Run("delete makes link gone", func(t *testing.T) {
+		id := short[strings.LastIndex(short, "/")+1:]
+		assert.NoError(t, c.DeleteURLs(ctx, []string{id}))
+
+		assert.Eventually(t, func() bool {
+			_, err := c.Resolve(ctx, id)
+			return errors.Is(err, client.ErrGone)
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("new user has no urls", func(t *testing.T) {
+		urls, err := newClient(t, srv).UserURLs(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, urls)
+	})
+}
+
+func TestClient_Conflict(t *testing.T) {
+	// PostgreSQL-хранилище отвечает на повтор 409 с существующей ссылкой.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"result":"http://short/abc"}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := newClient(t, srv)
+
+	existing, err := c.Shorten(context.Background(), "https://example.com/sdk")
+	assert.True(t, errors.Is(err, client.ErrConflict))
+	var conflict *client.ConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Equal(t, "http://short/abc", conflict.ShortURL)
+	}
+	assert.Equal(t, "http://short/abc", existing)
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	_, err := client.New("localhost:8080")
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Ошибки, с которыми можно сравнивать результат методов через errors.Is.
+var (
+	// ErrConflict — сервер ответил 409 Conflict, например ссылка уже сокращена.
+	ErrConflict = errors.New("shortener: conflict")
+	// ErrGone — сервер ответил 410 Gone: ссылка удалена или истекла.
+	ErrGone = errors.New("shortener: link is gone")
+	// ErrNotFound — сервер ответил 404 Not Found.
+	ErrNotFound = errors.New("shortener: not found")
+)
+
+// APIError — ответ сервера с кодом 4xx/5xx.
+// Поля Type, Title, Detail и RequestID заполняются из application/problem+json.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Title      string
+	Detail     string
+	RequestID  string
+	// Body — текст ответа, если он не в формате problem+json.
+	Body string
+}
+
+// Error возвращает описание ошибки.
+func (e *APIError) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Body
+	}
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("shortener: %d: %s", e.StatusCode, msg)
+}
+
+// Is сопоставляет код ответа с ErrConflict, ErrGone и ErrNotFound.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrGone:
+		return e.StatusCode == http.StatusGone
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// ConflictError возвращается Shorten, если URL уже был сокращён.
+// ShortURL — существующая короткая ссылка; errors.Is(err, ErrConflict) возвращает true.
+type ConflictError struct {
+	ShortURL string
+}
+
+// Error возвращает описание ошибки.
+func (e *ConflictError) Error() string {
+	return "shortener: url already shortened as " + e.ShortURL
+}
+
+// Is возвращает true для ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
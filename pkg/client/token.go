@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenStore сохраняет токен пользователя (cookie auth_token) между запусками приложения.
+type TokenStore interface {
+	// Load возвращает сохранённый токен или пустую строку, если токена нет.
+	Load() (string, error)
+	// Save сохраняет токен; пустой токен удаляет сохранённое значение.
+	Save(token string) error
+}
+
+// FileTokenStore хранит токен в файле с правами 0600.
+type FileTokenStore struct {
+	Path string
+}
+
+// Load читает токен из файла; отсутствующий файл означает пустой токен.
+func (s FileTokenStore) Load() (string, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save записывает токен в файл, создавая каталог при необходимости.
+func (s FileTokenStore) Save(token string) error {
+	if token == "" {
+		if err := os.Remove(s.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove token: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	if err := os.WriteFile(s.Path, []byte(token+"\n"), 0o600); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	return nil
+}